@@ -17,6 +17,7 @@ func NewAgent(h Handler) *Agent {
 	}
 	a := &Agent{
 		transactions: make(map[transactionID]agentTransaction),
+		namespaces:   make(map[string]*AgentNamespace),
 		handler:      h,
 	}
 	return a
@@ -32,8 +33,9 @@ type Agent struct {
 	// minimizing mux lock and protecting agentTransaction from
 	// data races via unexpected concurrent access.
 	transactions map[transactionID]agentTransaction
+	namespaces   map[string]*AgentNamespace
 	closed       bool       // all calls are invalid if true
-	mux          sync.Mutex // protects transactions and closed
+	mux          sync.Mutex // protects transactions, namespaces and closed
 	handler      Handler    // handles transactions
 }
 
@@ -57,6 +59,15 @@ type Event struct {
 type agentTransaction struct {
 	id       transactionID
 	deadline time.Time
+	ns       *AgentNamespace // nil for transactions of Agent itself
+}
+
+// handlerOf returns handler of t. Should be called with locked mux.
+func (a *Agent) handlerOf(t agentTransaction) Handler {
+	if t.ns != nil {
+		return t.ns.handler
+	}
+	return a.handler
 }
 
 var (
@@ -79,7 +90,7 @@ func (a *Agent) StopWithError(id [TransactionIDSize]byte, err error) error {
 	}
 	t, exists := a.transactions[id]
 	delete(a.transactions, id)
-	h := a.handler
+	h := a.handlerOf(t)
 	a.mux.Unlock()
 	if !exists {
 		return ErrTransactionNotExists
@@ -106,9 +117,13 @@ var ErrAgentClosed = errors.New("agent is closed")
 //
 // Agent handler is guaranteed to be eventually called.
 func (a *Agent) Start(id [TransactionIDSize]byte, deadline time.Time) error {
+	return a.start(nil, id, deadline)
+}
+
+func (a *Agent) start(ns *AgentNamespace, id [TransactionIDSize]byte, deadline time.Time) error {
 	a.mux.Lock()
 	defer a.mux.Unlock()
-	if a.closed {
+	if a.closed || (ns != nil && ns.closed) {
 		return ErrAgentClosed
 	}
 	_, exists := a.transactions[id]
@@ -118,6 +133,7 @@ func (a *Agent) Start(id [TransactionIDSize]byte, deadline time.Time) error {
 	a.transactions[id] = agentTransaction{
 		id:       id,
 		deadline: deadline,
+		ns:       ns,
 	}
 	return nil
 }
@@ -135,9 +151,15 @@ var ErrTransactionTimeOut = errors.New("transaction is timed out")
 //
 // It is safe to call Collect concurrently but makes no sense.
 func (a *Agent) Collect(gcTime time.Time) error {
-	toRemove := make([]transactionID, 0, agentCollectCap)
+	return a.collect(nil, gcTime)
+}
+
+// collect terminates transactions of ns, or all transactions if ns is
+// nil, that have deadline before gcTime.
+func (a *Agent) collect(ns *AgentNamespace, gcTime time.Time) error {
+	toRemove := make([]agentCollected, 0, agentCollectCap)
 	a.mux.Lock()
-	if a.closed {
+	if a.closed || (ns != nil && ns.closed) {
 		// Doing nothing if agent is closed.
 		// All transactions should be already closed
 		// during Close() call.
@@ -145,46 +167,62 @@ func (a *Agent) Collect(gcTime time.Time) error {
 		return ErrAgentClosed
 	}
 	// Adding all transactions with deadline before gcTime
-	// to toCall and toRemove slices.
+	// to toRemove slice.
 	// No allocs if there are less than agentCollectCap
 	// timed out transactions.
 	for id, t := range a.transactions {
-		if t.deadline.Before(gcTime) {
-			toRemove = append(toRemove, id)
+		if t.deadline.Before(gcTime) && (ns == nil || t.ns == ns) {
+			toRemove = append(toRemove, agentCollected{id: id, h: a.handlerOf(t)})
 		}
 	}
 	// Un-registering timed out transactions.
-	for _, id := range toRemove {
-		delete(a.transactions, id)
+	for _, t := range toRemove {
+		delete(a.transactions, t.id)
 	}
 	// Calling handler does not require locked mutex,
 	// reducing lock time.
-	h := a.handler
 	a.mux.Unlock()
 	// Sending ErrTransactionTimeOut to handler for all transactions,
 	// blocking until last one.
 	event := Event{
 		Error: ErrTransactionTimeOut,
 	}
-	for _, id := range toRemove {
-		event.TransactionID = id
-		h(event)
+	for _, t := range toRemove {
+		event.TransactionID = t.id
+		t.h(event)
 	}
 	return nil
 }
 
-// Process incoming message, synchronously passing it to handler.
+// agentCollected is timed out transaction with its handler.
+type agentCollected struct {
+	id transactionID
+	h  Handler
+}
+
+// Process incoming message, synchronously passing it to handler of
+// transaction, or to agent handler if there is no such transaction.
 func (a *Agent) Process(m *Message) error {
+	return a.process(nil, m)
+}
+
+// process passes m to handler of transaction, or to handler of ns if
+// there is no such transaction.
+func (a *Agent) process(ns *AgentNamespace, m *Message) error {
 	e := Event{
 		TransactionID: m.TransactionID,
 		Message:       m,
 	}
 	a.mux.Lock()
-	if a.closed {
+	if a.closed || (ns != nil && ns.closed) {
 		a.mux.Unlock()
 		return ErrAgentClosed
 	}
-	h := a.handler
+	t, exists := a.transactions[m.TransactionID]
+	if !exists {
+		t.ns = ns
+	}
+	h := a.handlerOf(t)
 	delete(a.transactions, m.TransactionID)
 	a.mux.Unlock()
 	h(e)
@@ -216,9 +254,14 @@ func (a *Agent) Close() error {
 	}
 	for _, t := range a.transactions {
 		e.TransactionID = t.id
-		a.handler(e)
+		a.handlerOf(t)(e)
+	}
+	for _, ns := range a.namespaces {
+		ns.closed = true
+		ns.handler = nil
 	}
 	a.transactions = nil
+	a.namespaces = nil
 	a.closed = true
 	a.handler = nil
 	a.mux.Unlock()
@@ -226,3 +269,148 @@ func (a *Agent) Close() error {
 }
 
 type transactionID [TransactionIDSize]byte
+
+// Namespace returns view of agent for logical user that is identified
+// by tag, e.g. ICE stream, creating it if necessary. Transactions that
+// are started via namespace are tagged by it, so they are handled,
+// collected, stopped and counted separately from others, while sharing
+// transaction ids with whole agent.
+//
+// AgentNamespace implements ClientAgent, so clients can share agent
+// via WithAgent. Returns nil if agent is closed.
+func (a *Agent) Namespace(tag string) *AgentNamespace {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if a.closed {
+		return nil
+	}
+	ns, ok := a.namespaces[tag]
+	if !ok {
+		ns = &AgentNamespace{
+			tag:     tag,
+			a:       a,
+			handler: NoopHandler,
+		}
+		a.namespaces[tag] = ns
+	}
+	return ns
+}
+
+// AgentNamespace is view of Agent for transactions that are tagged by
+// namespace, see Agent.Namespace.
+type AgentNamespace struct {
+	tag string
+	a   *Agent
+
+	// Fields below are protected by a.mux.
+	handler Handler
+	closed  bool
+}
+
+// Tag returns tag of namespace.
+func (n *AgentNamespace) Tag() string {
+	return n.tag
+}
+
+// Start registers transaction of namespace, like Agent.Start.
+func (n *AgentNamespace) Start(id [TransactionIDSize]byte, deadline time.Time) error {
+	return n.a.start(n, id, deadline)
+}
+
+// Stop stops transaction of namespace by id with ErrTransactionStopped.
+// Returns ErrTransactionNotExists for transactions of other namespaces.
+func (n *AgentNamespace) Stop(id [TransactionIDSize]byte) error {
+	return n.StopWithError(id, ErrTransactionStopped)
+}
+
+// StopWithError stops transaction of namespace by id with err.
+func (n *AgentNamespace) StopWithError(id [TransactionIDSize]byte, err error) error {
+	a := n.a
+	a.mux.Lock()
+	if a.closed || n.closed {
+		a.mux.Unlock()
+		return ErrAgentClosed
+	}
+	t, exists := a.transactions[id]
+	if !exists || t.ns != n {
+		a.mux.Unlock()
+		return ErrTransactionNotExists
+	}
+	delete(a.transactions, id)
+	h := n.handler
+	a.mux.Unlock()
+	h(Event{
+		TransactionID: id,
+		Error:         err,
+	})
+	return nil
+}
+
+// Collect terminates transactions of namespace that have deadline before
+// gcTime with ErrTransactionTimeOut, like Agent.Collect.
+func (n *AgentNamespace) Collect(gcTime time.Time) error {
+	return n.a.collect(n, gcTime)
+}
+
+// Process passes m to handler of its transaction, which can be of other
+// namespace, or to handler of namespace if there is no such transaction.
+func (n *AgentNamespace) Process(m *Message) error {
+	return n.a.process(n, m)
+}
+
+// SetHandler sets handler of namespace transactions.
+func (n *AgentNamespace) SetHandler(h Handler) error {
+	n.a.mux.Lock()
+	defer n.a.mux.Unlock()
+	if n.a.closed || n.closed {
+		return ErrAgentClosed
+	}
+	n.handler = h
+	return nil
+}
+
+// Pending returns count of transactions of namespace that are in
+// progress.
+func (n *AgentNamespace) Pending() int {
+	n.a.mux.Lock()
+	defer n.a.mux.Unlock()
+	pending := 0
+	for _, t := range n.a.transactions {
+		if t.ns == n {
+			pending++
+		}
+	}
+	return pending
+}
+
+// Close terminates transactions of namespace with ErrAgentClosed and
+// removes namespace from agent, which stays open.
+func (n *AgentNamespace) Close() error {
+	a := n.a
+	e := Event{
+		Error: ErrAgentClosed,
+	}
+	a.mux.Lock()
+	if a.closed || n.closed {
+		a.mux.Unlock()
+		return ErrAgentClosed
+	}
+	var ids []transactionID
+	for id, t := range a.transactions {
+		if t.ns == n {
+			ids = append(ids, id)
+			delete(a.transactions, id)
+		}
+	}
+	h := n.handler
+	n.closed = true
+	n.handler = nil
+	delete(a.namespaces, n.tag)
+	// Calling handler without locked mutex, so it can use agent.
+	a.mux.Unlock()
+	for _, id := range ids {
+		e.TransactionID = id
+		h(e)
+	}
+	return nil
+}
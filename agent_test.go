@@ -1,6 +1,7 @@
 package stun
 
 import (
+	"net"
 	"testing"
 	"time"
 )
@@ -210,3 +211,170 @@ func BenchmarkAgent_Process(b *testing.B) {
 		}
 	}
 }
+
+func TestAgent_Namespace(t *testing.T) {
+	var (
+		root     []Event
+		first    []Event
+		second   []Event
+		a        = NewAgent(func(e Event) { root = append(root, e) })
+		ns1      = a.Namespace("first")
+		ns2      = a.Namespace("second")
+		now      = time.Now()
+		deadline = now.Add(time.Second)
+	)
+	if a.Namespace("first") != ns1 || ns1.Tag() != "first" {
+		t.Fatal("namespace should be reused")
+	}
+	if err := ns1.SetHandler(func(e Event) { first = append(first, e) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := ns2.SetHandler(func(e Event) { second = append(second, e) }); err != nil {
+		t.Fatal(err)
+	}
+	id1, id2, id3 := NewTransactionID(), NewTransactionID(), NewTransactionID()
+	if err := ns1.Start(id1, deadline); err != nil {
+		t.Fatal(err)
+	}
+	if err := ns2.Start(id2, deadline); err != nil {
+		t.Fatal(err)
+	}
+	if err := ns2.Start(id1, deadline); err != ErrTransactionExists {
+		t.Errorf("ids should be shared, got %v", err)
+	}
+	if err := a.Start(id3, deadline); err != nil {
+		t.Fatal(err)
+	}
+	if ns1.Pending() != 1 || ns2.Pending() != 1 {
+		t.Errorf("unexpected pending %d, %d", ns1.Pending(), ns2.Pending())
+	}
+	if err := ns1.Stop(id2); err != ErrTransactionNotExists {
+		t.Errorf("should not stop transaction of other namespace, got %v", err)
+	}
+	// Collecting only first namespace.
+	if err := ns1.Collect(deadline.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || first[0].TransactionID != id1 || first[0].Error != ErrTransactionTimeOut {
+		t.Errorf("unexpected first events %v", first)
+	}
+	if len(second) != 0 || len(root) != 0 {
+		t.Error("other transactions should not be collected")
+	}
+	// Response is passed to owner of transaction.
+	if err := ns1.Process(&Message{TransactionID: id2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 1 || second[0].Message == nil {
+		t.Errorf("unexpected second events %v", second)
+	}
+	// Unknown transaction is passed to namespace handler.
+	if err := ns1.Process(&Message{TransactionID: NewTransactionID()}); err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 2 {
+		t.Errorf("unexpected first events %v", first)
+	}
+	t.Run("Close", func(t *testing.T) {
+		id := NewTransactionID()
+		if err := ns2.Start(id, deadline); err != nil {
+			t.Fatal(err)
+		}
+		if err := ns2.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if len(second) != 2 || second[1].Error != ErrAgentClosed {
+			t.Errorf("unexpected second events %v", second)
+		}
+		if err := ns2.Start(NewTransactionID(), deadline); err != ErrAgentClosed {
+			t.Errorf("unexpected error %v", err)
+		}
+		if a.Namespace("second") == ns2 {
+			t.Error("closed namespace should be replaced")
+		}
+		// Agent stays open.
+		if err := a.Collect(deadline.Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if len(root) != 1 || root[0].TransactionID != id3 {
+			t.Errorf("unexpected root events %v", root)
+		}
+		if err := a.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ns1.Start(NewTransactionID(), deadline); err != ErrAgentClosed {
+			t.Errorf("unexpected error %v", err)
+		}
+		if a.Namespace("first") != nil {
+			t.Error("closed agent should have no namespaces")
+		}
+	})
+}
+
+func TestClient_AgentNamespace(t *testing.T) {
+	addr, closeServer := serveUDP(t, func(req *Message) *Message {
+		return MustBuild(req, BindingSuccess)
+	})
+	defer closeServer()
+	a := NewAgent(nil)
+	defer a.Close()
+	for _, tag := range []string{"first", "second"} {
+		conn, err := net.Dial("udp4", addr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewClient(conn, WithAgent(a.Namespace(tag)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+			}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClient_AgentNamespaceClose(t *testing.T) {
+	a := NewAgent(nil)
+	defer a.Close()
+	ns := a.Namespace("test")
+	c, err := NewClient(noopConnection{}, WithAgent(ns))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	events := make(chan Event, 1)
+	if err = c.Start(MustBuild(TransactionID, BindingRequest), func(e Event) {
+		events <- e
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Pending transaction can be retransmitted, but should be completed
+	// instead of being started again on closed namespace.
+	closed := make(chan error, 1)
+	go func() {
+		closed <- ns.Close()
+	}()
+	select {
+	case err = <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("namespace close timed out")
+	}
+	select {
+	case e := <-events:
+		if e.Error != ErrAgentClosed {
+			t.Errorf("unexpected error %v", e.Error)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("transaction was not completed")
+	}
+}
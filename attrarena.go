@@ -0,0 +1,73 @@
+package stun
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Limits of attrsArena.
+const (
+	attrsChunkSize   = 512 // attributes in single chunk
+	defaultAttrsHint = 8   // initial capacity of buffers
+	maxAttrsHint     = 64  // buffers are never larger
+)
+
+// attrsArena carves Attributes buffers of pooled messages from shared
+// chunks, so buffers are allocated once per chunk instead of once per
+// message and growth step. Capacity of buffers follows attribute counts
+// that are observed on release of messages.
+//
+// Buffers are sliced with full capacity, so appending beyond it
+// reallocates instead of overwriting neighbor buffer, and chunk is
+// freed by garbage collector when all its buffers are dropped.
+type attrsArena struct {
+	hint int32 // capacity of buffers, accessed atomically
+
+	mux   sync.Mutex // guards chunk
+	chunk Attributes
+}
+
+var messageAttrsArena = &attrsArena{hint: defaultAttrsHint}
+
+// alloc returns empty buffer with capacity of observed attribute count.
+func (a *attrsArena) alloc() Attributes {
+	n := a.capacity()
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if cap(a.chunk)-len(a.chunk) < n {
+		a.chunk = make(Attributes, 0, attrsChunkSize)
+	}
+	start := len(a.chunk)
+	a.chunk = a.chunk[:start+n]
+	return a.chunk[start : start : start+n]
+}
+
+// capacity returns current capacity of buffers.
+func (a *attrsArena) capacity() int {
+	return int(atomic.LoadInt32(&a.hint))
+}
+
+// observe adjusts capacity of buffers by count n of attributes of
+// released message: capacity grows to n at once and decays slowly, so
+// rare large messages do not inflate all buffers for long. Concurrent
+// updates can be lost, which only delays adjustment.
+func (a *attrsArena) observe(n int) {
+	if n > maxAttrsHint {
+		n = maxAttrsHint
+	}
+	old := a.capacity()
+	hint := old
+	switch {
+	case n > hint:
+		hint = n
+	case n < hint:
+		hint -= (hint - n + 7) / 8
+		if hint < defaultAttrsHint {
+			hint = defaultAttrsHint
+		}
+	}
+	if hint == old {
+		return
+	}
+	atomic.StoreInt32(&a.hint, int32(hint))
+}
@@ -0,0 +1,80 @@
+package stun
+
+import (
+	"testing"
+
+	"github.com/pion/stun/internal/testutil"
+)
+
+func TestAttrsArena(t *testing.T) {
+	a := &attrsArena{hint: defaultAttrsHint}
+	first, second := a.alloc(), a.alloc()
+	if len(first) != 0 || cap(first) != defaultAttrsHint {
+		t.Fatalf("unexpected len %d, cap %d", len(first), cap(first))
+	}
+	second = append(second, RawAttribute{Type: AttrSoftware})
+	for i := 0; i <= defaultAttrsHint; i++ {
+		first = append(first, RawAttribute{Type: AttrUsername})
+	}
+	if second[0].Type != AttrSoftware {
+		t.Error("growth of buffer should not overwrite neighbor")
+	}
+	t.Run("Observe", func(t *testing.T) {
+		a.observe(20)
+		if a.capacity() != 20 || cap(a.alloc()) != 20 {
+			t.Errorf("capacity should grow to 20, got %d", a.capacity())
+		}
+		a.observe(1000)
+		if a.capacity() != maxAttrsHint {
+			t.Errorf("capacity should be limited, got %d", a.capacity())
+		}
+		a.observe(2)
+		if c := a.capacity(); c >= maxAttrsHint || c <= defaultAttrsHint {
+			t.Errorf("capacity should decay slowly, got %d", c)
+		}
+		for i := 0; i < 100; i++ {
+			a.observe(2)
+		}
+		if a.capacity() != defaultAttrsHint {
+			t.Errorf("capacity should decay to default, got %d", a.capacity())
+		}
+	})
+	t.Run("Chunk", func(t *testing.T) {
+		a := &attrsArena{hint: maxAttrsHint}
+		testutil.ShouldNotAllocate(t, func() {
+			a.alloc()
+		})
+		for i := 0; i < attrsChunkSize/maxAttrsHint*2; i++ {
+			if b := a.alloc(); cap(b) != maxAttrsHint {
+				t.Fatalf("unexpected cap %d", cap(b))
+			}
+		}
+	})
+}
+
+func TestReleaseMessage_Large(t *testing.T) {
+	m := AcquireMessage()
+	m.Attributes = make(Attributes, maxAttrsHint+1)
+	ReleaseMessage(m)
+	if m.Attributes != nil {
+		t.Error("large buffer should not be retained")
+	}
+}
+
+func TestMessage_CloneToInconsistent(t *testing.T) {
+	m := MustBuild(TransactionID, BindingRequest, NewSoftware("pion/stun"), Fingerprint)
+	// Attribute that is not in m.Raw.
+	m.Attributes = append(m.Attributes, RawAttribute{Type: AttrUsername, Value: []byte("user"), Length: 4})
+	b := new(Message)
+	if err := m.CloneTo(b); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Attributes) != 2 || b.Contains(AttrUsername) {
+		t.Errorf("should be decoded from m.Raw: %v", b.Attributes)
+	}
+	testutil.ShouldNotAllocate(t, func() {
+		if err := m.CloneTo(b); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -53,6 +53,12 @@ const (
 	AttrFingerprint     AttrType = 0x8028 // FINGERPRINT
 )
 
+// Attributes from RFC 3489 classic STUN, reserved since RFC 5389.
+const (
+	AttrSourceAddress  AttrType = 0x0004 // SOURCE-ADDRESS
+	AttrChangedAddress AttrType = 0x0005 // CHANGED-ADDRESS
+)
+
 // Attributes from RFC 5245 ICE.
 const (
 	AttrPriority       AttrType = 0x0024 // PRIORITY
@@ -84,6 +90,29 @@ const (
 	AttrRequestedAddressFamily AttrType = 0x0017 // REQUESTED-ADDRESS-FAMILY
 )
 
+// Attributes from RFC 8656 TURN.
+const (
+	AttrAddressErrorCode AttrType = 0x8001 // ADDRESS-ERROR-CODE
+)
+
+// Attributes from RFC 5780 NAT Behavior Discovery.
+const (
+	AttrChangeRequest  AttrType = 0x0003 // CHANGE-REQUEST
+	AttrPadding        AttrType = 0x0026 // PADDING
+	AttrResponsePort   AttrType = 0x0027 // RESPONSE-PORT
+	AttrResponseOrigin AttrType = 0x802B // RESPONSE-ORIGIN
+	AttrOtherAddress   AttrType = 0x802C // OTHER-ADDRESS
+)
+
+// Attributes from RFC 8489 STUN.
+const (
+	AttrMessageIntegritySHA256 AttrType = 0x001C // MESSAGE-INTEGRITY-SHA256
+	AttrPasswordAlgorithm      AttrType = 0x001D // PASSWORD-ALGORITHM
+	AttrUserhash               AttrType = 0x001E // USERHASH
+	AttrPasswordAlgorithms     AttrType = 0x8002 // PASSWORD-ALGORITHMS
+	AttrAlternateDomain        AttrType = 0x8003 // ALTERNATE-DOMAIN
+)
+
 // Attributes from An Origin Attribute for the STUN Protocol.
 const (
 	AttrOrigin AttrType = 0x802F
@@ -121,7 +150,20 @@ var attrNames = map[AttrType]string{
 	AttrReservationToken:       "RESERVATION-TOKEN",
 	AttrConnectionID:           "CONNECTION-ID",
 	AttrRequestedAddressFamily: "REQUESTED-ADDRESS-FAMILY",
+	AttrAddressErrorCode:       "ADDRESS-ERROR-CODE",
 	AttrOrigin:                 "ORIGIN",
+	AttrSourceAddress:          "SOURCE-ADDRESS",
+	AttrChangedAddress:         "CHANGED-ADDRESS",
+	AttrChangeRequest:          "CHANGE-REQUEST",
+	AttrPadding:                "PADDING",
+	AttrResponsePort:           "RESPONSE-PORT",
+	AttrResponseOrigin:         "RESPONSE-ORIGIN",
+	AttrOtherAddress:           "OTHER-ADDRESS",
+	AttrMessageIntegritySHA256: "MESSAGE-INTEGRITY-SHA256",
+	AttrPasswordAlgorithm:      "PASSWORD-ALGORITHM",
+	AttrUserhash:               "USERHASH",
+	AttrPasswordAlgorithms:     "PASSWORD-ALGORITHMS",
+	AttrAlternateDomain:        "ALTERNATE-DOMAIN",
 }
 
 func (t AttrType) String() string {
@@ -140,6 +182,10 @@ func (t AttrType) String() string {
 // don't understand, but cannot successfully process a message if it
 // contains comprehension-required attributes that are not
 // understood.
+//
+// Value never includes padding: it is added to the next 4-byte boundary
+// with zero bytes while encoding and skipped while decoding, so Length
+// is the length of Value.
 type RawAttribute struct {
 	Type   AttrType
 	Length uint16 // ignored while encoding
@@ -147,12 +193,25 @@ type RawAttribute struct {
 }
 
 // AddTo implements Setter, adding attribute as a.Type with a.Value and ignoring
-// the Length field.
+// the Length field. The a.Value is copied, so custom attributes can
+// be added in Build pipeline as RawAttribute.
 func (a RawAttribute) AddTo(m *Message) error {
 	m.Add(a.Type, a.Value)
 	return nil
 }
 
+// GetFrom implements Getter, decoding first attribute with a.Type from m
+// to a. The a.Value refers to m.Raw and is valid only until m.Raw is
+// modified. Returns ErrAttributeNotFound if there is no such attribute.
+func (a *RawAttribute) GetFrom(m *Message) error {
+	attr, ok := m.Attributes.Get(a.Type)
+	if !ok {
+		return ErrAttributeNotFound
+	}
+	*a = attr
+	return nil
+}
+
 // Equal returns true if a == b.
 func (a RawAttribute) Equal(b RawAttribute) bool {
 	if a.Type != b.Type {
@@ -40,6 +40,31 @@ func TestRawAttribute_AddTo(t *testing.T) {
 	}
 }
 
+func TestRawAttribute_GetFrom(t *testing.T) {
+	m := MustBuild(RawAttribute{
+		Type:  AttrData,
+		Value: []byte{1, 2, 3},
+	})
+	decoded := new(Message)
+	if _, err := decoded.Write(m.Raw); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Raw) != messageHeaderSize+attributeHeaderSize+4 {
+		t.Error("value should be padded")
+	}
+	a := RawAttribute{Type: AttrData}
+	if err := a.GetFrom(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if a.Type != AttrData || a.Length != 3 || !bytes.Equal(a.Value, []byte{1, 2, 3}) {
+		t.Error("unexpected attribute", a)
+	}
+	missing := RawAttribute{Type: AttrSoftware}
+	if err := missing.GetFrom(decoded); err != ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+}
+
 func TestMessage_GetNoAllocs(t *testing.T) {
 	m := New()
 	NewSoftware("c").AddTo(m)
@@ -0,0 +1,86 @@
+package stun
+
+import (
+	"net"
+	"time"
+)
+
+// Decision is outcome of request that is recorded by Server in
+// AuditRecord.
+type Decision byte
+
+// Possible decisions.
+const (
+	// DecisionAllowed means that request is answered with success
+	// response.
+	DecisionAllowed Decision = iota
+	// DecisionChallenged means that request is answered with 401
+	// (Unauthorized) or 438 (Stale Nonce) error response, so client
+	// should retry it with credentials.
+	DecisionChallenged
+	// DecisionDenied means that request is answered with other error
+	// response, or is dropped if AuditRecord.Code is zero.
+	DecisionDenied
+)
+
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllowed:
+		return "allowed"
+	case DecisionChallenged:
+		return "challenged"
+	case DecisionDenied:
+		return "denied"
+	default:
+		return "unknown"
+	}
+}
+
+// AuditRecord describes processing of single request by Server.
+type AuditRecord struct {
+	Peer     net.Addr
+	Method   Method
+	Username string // authenticated user, empty if not authenticated
+	Decision Decision
+	Code     ErrorCode // of error response, zero if there is none
+	Duration time.Duration
+}
+
+// AuditHandler is called with record of every request that is decoded
+// by Server, including Binding requests. Handler is called
+// concurrently by serve methods before response is sent, so it should
+// not block.
+type AuditHandler func(r AuditRecord)
+
+// WithAudit sets handler of audit records of requests, e.g. to log
+// decisions of server for compliance. Messages that fail decoding or
+// policy, indications and health checks are not recorded.
+func WithAudit(h AuditHandler) ServerOption {
+	return func(s *Server) {
+		s.audit = h
+	}
+}
+
+// auditRequest passes record of req that is answered with res to audit
+// handler, res is empty if req is dropped.
+func (s *Server) auditRequest(res, req *Message, addr net.Addr, start time.Time, username *string) {
+	r := AuditRecord{
+		Peer:     addr,
+		Method:   req.Type.Method,
+		Username: *username,
+		Duration: s.now().Sub(start),
+	}
+	switch {
+	case len(res.Raw) == 0:
+		r.Decision = DecisionDenied
+	case res.Type.Class == ClassErrorResponse:
+		var code ErrorCodeAttribute
+		_ = code.GetFrom(res)
+		r.Code = code.Code
+		r.Decision = DecisionDenied
+		if code.Code == CodeUnauthorized || code.Code == CodeStaleNonce {
+			r.Decision = DecisionChallenged
+		}
+	}
+	s.audit(r)
+}
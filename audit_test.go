@@ -0,0 +1,78 @@
+package stun
+
+import (
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestServer_Audit(t *testing.T) {
+	var (
+		mux     sync.Mutex
+		records []AuditRecord
+	)
+	s := newTestServer(t,
+		WithAuth("realm", func(username, realm string) ([]byte, bool) {
+			return NewLongTermIntegrity(username, realm, "secret"), username == "user"
+		}),
+		WithAudit(func(r AuditRecord) {
+			mux.Lock()
+			records = append(records, r)
+			mux.Unlock()
+		}),
+	)
+	addr, _ := listenServer(t, "udp", s)
+	defer s.Close()
+	c, err := Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	credentials := &LongTermCredentials{Username: "user", Password: "secret"}
+	for _, m := range []MessageType{BindingRequest, allocateRequest} {
+		if err = credentials.Do(c, func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+			}
+		}, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = c.Indicate(MustBuild(TransactionID, bindingIndication)); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	if len(records) != 3 {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	for i, expected := range []AuditRecord{
+		{Method: MethodBinding, Decision: DecisionChallenged, Code: CodeUnauthorized},
+		{Method: MethodBinding, Decision: DecisionAllowed, Username: "user"},
+		{Method: MethodAllocate, Decision: DecisionDenied, Code: CodeBadRequest, Username: "user"},
+	} {
+		r := records[i]
+		if r.Method != expected.Method || r.Decision != expected.Decision || r.Code != expected.Code || r.Username != expected.Username {
+			t.Errorf("record %d: %+v, expected %+v", i, r, expected)
+		}
+		if peer, ok := r.Peer.(*net.UDPAddr); !ok || peer.String() != c.c.(net.Conn).LocalAddr().String() {
+			t.Errorf("unexpected peer %s", r.Peer)
+		}
+	}
+}
+
+func TestDecision_String(t *testing.T) {
+	for d, s := range map[Decision]string{
+		DecisionAllowed:    "allowed",
+		DecisionChallenged: "challenged",
+		DecisionDenied:     "denied",
+		Decision(10):       "unknown",
+	} {
+		if d.String() != s {
+			t.Errorf("%d: %q, expected %q", d, d, s)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package stun
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result is result of single transaction of DoBatch.
+type Result struct {
+	// Message is response, nil if Error is set.
+	Message *Message
+	Error   error
+}
+
+// defaultPacing is the default interval between requests in DoBatch,
+// equal to Ta of ICE connectivity checks.
+//
+// RFC 8445 Section 14.2
+const defaultPacing = time.Millisecond * 50
+
+// WithPacing sets interval between requests that are sent by DoBatch.
+func WithPacing(d time.Duration) ClientOption {
+	return func(c *Client) {
+		c.pacing = d
+	}
+}
+
+// DoBatch starts transactions for reqs, sending request every pacing
+// interval (see WithPacing), and waits for all of them to complete.
+// Results are in the same order as reqs and each reqs[i] should have
+// unique transaction id.
+//
+// If ctx is done, remaining requests are not sent and pending
+// transactions are stopped, failing with ctx.Err(). The returned error
+// is ctx.Err() in that case.
+func (c *Client) DoBatch(ctx context.Context, reqs []*Message) ([]Result, error) {
+	if err := c.checkInit(); err != nil {
+		return nil, err
+	}
+	var (
+		results = make([]Result, len(reqs))
+		wg      sync.WaitGroup
+		cancel  = ctx.Done()
+	)
+	wg.Add(len(reqs))
+	started := 0
+	for i, m := range reqs {
+		if i > 0 && c.pacing > 0 {
+			select {
+			case <-after(c.clock, c.pacing):
+			case <-cancel:
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		started++
+		result := &results[i]
+		if err := c.Start(m, func(e Event) {
+			defer wg.Done()
+			if e.Error != nil {
+				result.Error = e.Error
+				return
+			}
+			result.Message = new(Message)
+			result.Error = e.Message.CloneTo(result.Message)
+		}); err != nil {
+			result.Error = err
+			wg.Done()
+		}
+	}
+	for i := started; i < len(reqs); i++ {
+		results[i].Error = ctx.Err()
+		wg.Done()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return results, ctx.Err()
+	case <-cancel:
+	}
+	for _, m := range reqs[:started] {
+		// Error is returned for already completed transactions.
+		_ = c.cancel(m.TransactionID, ctx.Err())
+	}
+	<-done
+	return results, ctx.Err()
+}
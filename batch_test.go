@@ -0,0 +1,84 @@
+package stun
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClient_DoBatch(t *testing.T) {
+	addr, closeServer := serveUDP(t, func(req *Message) *Message {
+		return MustBuild(req, BindingSuccess)
+	})
+	defer closeServer()
+	conn, err := net.Dial("udp4", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(conn, WithPacing(time.Millisecond*10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	reqs := []*Message{
+		MustBuild(TransactionID, BindingRequest),
+		MustBuild(TransactionID, BindingRequest),
+		MustBuild(TransactionID, BindingRequest),
+	}
+	start := time.Now()
+	results, err := c.DoBatch(context.Background(), reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*20 {
+		t.Errorf("requests should be paced, elapsed %s", elapsed)
+	}
+	for i, r := range results {
+		if r.Error != nil {
+			t.Errorf("%d: %v", i, r.Error)
+			continue
+		}
+		if r.Message.TransactionID != reqs[i].TransactionID || r.Message.Type != BindingSuccess {
+			t.Errorf("%d: unexpected response %s", i, r.Message)
+		}
+	}
+}
+
+func TestClient_DoBatchCancel(t *testing.T) {
+	// Server that never responds.
+	server, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	conn, err := net.Dial("udp4", server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(conn, WithPacing(time.Millisecond*40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*60)
+	defer cancel()
+	reqs := []*Message{
+		MustBuild(TransactionID, BindingRequest),
+		MustBuild(TransactionID, BindingRequest),
+		MustBuild(TransactionID, BindingRequest),
+	}
+	start := time.Now()
+	results, err := c.DoBatch(ctx, reqs)
+	if err != context.DeadlineExceeded {
+		t.Error("unexpected error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("transactions should be stopped, elapsed %s", elapsed)
+	}
+	for i, r := range results {
+		if r.Error != context.DeadlineExceeded {
+			t.Errorf("%d: unexpected error %v", i, r.Error)
+		}
+	}
+}
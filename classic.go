@@ -0,0 +1,64 @@
+package stun
+
+import "net"
+
+// SourceAddress represents SOURCE-ADDRESS attribute of RFC 3489, the
+// address that response was sent from. Replaced by RESPONSE-ORIGIN.
+//
+// RFC 3489 Section 11.2.5
+type SourceAddress MappedAddress
+
+func (a SourceAddress) String() string {
+	return MappedAddress(a).String()
+}
+
+// AddTo adds SOURCE-ADDRESS attribute to message.
+func (a *SourceAddress) AddTo(m *Message) error {
+	return (*MappedAddress)(a).addAs(m, AttrSourceAddress)
+}
+
+// GetFrom decodes SOURCE-ADDRESS from message.
+func (a *SourceAddress) GetFrom(m *Message) error {
+	return (*MappedAddress)(a).getAs(m, AttrSourceAddress)
+}
+
+// ChangedAddress represents CHANGED-ADDRESS attribute of RFC 3489, the
+// address that response would have been sent from if CHANGE-REQUEST
+// asked to change both IP and port. Replaced by OTHER-ADDRESS.
+//
+// RFC 3489 Section 11.2.3
+type ChangedAddress MappedAddress
+
+func (a ChangedAddress) String() string {
+	return MappedAddress(a).String()
+}
+
+// AddTo adds CHANGED-ADDRESS attribute to message.
+func (a *ChangedAddress) AddTo(m *Message) error {
+	return (*MappedAddress)(a).addAs(m, AttrChangedAddress)
+}
+
+// GetFrom decodes CHANGED-ADDRESS from message.
+func (a *ChangedAddress) GetFrom(m *Message) error {
+	return (*MappedAddress)(a).getAs(m, AttrChangedAddress)
+}
+
+// ReflexiveAddress is reflexive transport address of client, decoded
+// from XOR-MAPPED-ADDRESS or, if it is absent, from MAPPED-ADDRESS
+// that is sent by RFC 3489 servers.
+type ReflexiveAddress struct {
+	IP   net.IP
+	Port int
+}
+
+func (a ReflexiveAddress) String() string {
+	return MappedAddress(a).String()
+}
+
+// GetFrom decodes XOR-MAPPED-ADDRESS or MAPPED-ADDRESS from message.
+func (a *ReflexiveAddress) GetFrom(m *Message) error {
+	if m.Contains(AttrXORMappedAddress) {
+		return (*XORMappedAddress)(a).GetFrom(m)
+	}
+	return (*MappedAddress)(a).GetFrom(m)
+}
@@ -0,0 +1,69 @@
+package stun
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDecoder_Classic(t *testing.T) {
+	m := MustBuild(TransactionID, BindingSuccess,
+		&MappedAddress{IP: net.IPv4(192, 0, 2, 1), Port: 3478},
+		&SourceAddress{IP: net.IPv4(198, 51, 100, 1), Port: 3478},
+		&ChangedAddress{IP: net.IPv4(198, 51, 100, 2), Port: 3479},
+	)
+	// RFC 3489 has 128-bit transaction id instead of magic cookie.
+	copy(m.Raw[4:8], []byte{1, 2, 3, 4})
+	if err := new(Decoder).Decode(m.Raw, new(Message)); err == nil {
+		t.Fatal("should fail without Classic")
+	}
+	d := &Decoder{Classic: true}
+	decoded := new(Message)
+	if err := d.Decode(m.Raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.TransactionID != m.TransactionID || decoded.Type != BindingSuccess {
+		t.Errorf("unexpected message %s", decoded)
+	}
+	var (
+		source  SourceAddress
+		changed ChangedAddress
+		mapped  ReflexiveAddress
+	)
+	if err := source.GetFrom(decoded); err != nil || source.String() != "198.51.100.1:3478" {
+		t.Error("unexpected SOURCE-ADDRESS", source, err)
+	}
+	if err := changed.GetFrom(decoded); err != nil || changed.String() != "198.51.100.2:3479" {
+		t.Error("unexpected CHANGED-ADDRESS", changed, err)
+	}
+	if err := mapped.GetFrom(decoded); err != nil || mapped.String() != "192.0.2.1:3478" {
+		t.Error("should fall back to MAPPED-ADDRESS", mapped, err)
+	}
+	t.Run("Warn", func(t *testing.T) {
+		var warnings int
+		soft := &Decoder{Classic: true, Warn: func(error) { warnings++ }}
+		truncated := new(Message)
+		if err := soft.Decode(m.Raw[:len(m.Raw)-4], truncated); err != nil {
+			t.Fatal(err)
+		}
+		if warnings != 1 || len(truncated.Attributes) != 2 {
+			t.Error("unexpected result", warnings, truncated)
+		}
+		if err := soft.Decode(m.Raw[:10], truncated); err != ErrUnexpectedHeaderEOF {
+			t.Error("unexpected error", err)
+		}
+	})
+}
+
+func TestReflexiveAddress_GetFrom(t *testing.T) {
+	m := MustBuild(TransactionID, BindingSuccess,
+		&MappedAddress{IP: net.IPv4(192, 0, 2, 1), Port: 1},
+		&XORMappedAddress{IP: net.IPv4(192, 0, 2, 2), Port: 2},
+	)
+	var a ReflexiveAddress
+	if err := a.GetFrom(m); err != nil || a.String() != "192.0.2.2:2" {
+		t.Error("XOR-MAPPED-ADDRESS should be preferred", a, err)
+	}
+	if err := a.GetFrom(MustBuild(TransactionID, BindingSuccess)); err != ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+}
@@ -0,0 +1,67 @@
+package stun
+
+// PacketKind is protocol of packet on socket that is shared by STUN,
+// DTLS, SRTP and other protocols, see Classify.
+type PacketKind byte
+
+// Possible kinds of packets.
+const (
+	PacketUnknown     PacketKind = iota
+	PacketSTUN                   // first byte 0..3 and magic cookie
+	PacketZRTP                   // first byte 16..19
+	PacketDTLS                   // first byte 20..63
+	PacketTURNChannel            // first byte 64..79, ChannelData
+	PacketRTP                    // first byte 128..191
+	PacketRTCP                   // as RTP, but payload type 192..223
+)
+
+func (k PacketKind) String() string {
+	switch k {
+	case PacketSTUN:
+		return "STUN"
+	case PacketZRTP:
+		return "ZRTP"
+	case PacketDTLS:
+		return "DTLS"
+	case PacketTURNChannel:
+		return "TURN ChannelData"
+	case PacketRTP:
+		return "RTP"
+	case PacketRTCP:
+		return "RTCP"
+	default:
+		return "unknown"
+	}
+}
+
+// Classify returns kind of packet b by its first byte, so packets of
+// shared socket can be demultiplexed, e.g. in WebRTC stacks. Packets
+// with first byte of STUN range are STUN only if they have magic
+// cookie, see IsMessage, and RTCP is distinguished from RTP by payload
+// type in second byte. Other packets are PacketUnknown and should be
+// dropped.
+//
+// RFC 7983 Section 7, RFC 5761 Section 4
+func Classify(b []byte) PacketKind {
+	if len(b) == 0 {
+		return PacketUnknown
+	}
+	switch first := b[0]; {
+	case first <= 3:
+		if IsMessage(b) {
+			return PacketSTUN
+		}
+	case first >= 16 && first <= 19:
+		return PacketZRTP
+	case first >= 20 && first <= 63:
+		return PacketDTLS
+	case first >= 64 && first <= 79:
+		return PacketTURNChannel
+	case first >= 128 && first <= 191:
+		if len(b) > 1 && b[1] >= 192 && b[1] <= 223 {
+			return PacketRTCP
+		}
+		return PacketRTP
+	}
+	return PacketUnknown
+}
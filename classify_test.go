@@ -0,0 +1,57 @@
+package stun
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	noCookie := MustBuild(TransactionID, BindingRequest)
+	noCookie.Raw[4] = 0
+	for _, tc := range []struct {
+		name string
+		b    []byte
+		kind PacketKind
+	}{
+		{"Empty", nil, PacketUnknown},
+		{"STUN", MustBuild(TransactionID, BindingRequest).Raw, PacketSTUN},
+		{"NoCookie", noCookie.Raw, PacketUnknown},
+		{"ZRTP", []byte{16, 0}, PacketZRTP},
+		{"DTLS", []byte{22, 254, 253}, PacketDTLS},
+		{"ChannelData", []byte{0x40, 0x00, 0x00, 0x04}, PacketTURNChannel},
+		{"RTP", []byte{0x80, 96}, PacketRTP},
+		{"RTPShort", []byte{0x80}, PacketRTP},
+		{"RTCP", []byte{0x80, 200}, PacketRTCP},
+		{"Reserved", []byte{100}, PacketUnknown},
+		{"High", []byte{200}, PacketUnknown},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if kind := Classify(tc.b); kind != tc.kind {
+				t.Errorf("%s, expected %s", kind, tc.kind)
+			}
+		})
+	}
+}
+
+func TestPacketKind_String(t *testing.T) {
+	for k, s := range map[PacketKind]string{
+		PacketUnknown:     "unknown",
+		PacketSTUN:        "STUN",
+		PacketZRTP:        "ZRTP",
+		PacketDTLS:        "DTLS",
+		PacketTURNChannel: "TURN ChannelData",
+		PacketRTP:         "RTP",
+		PacketRTCP:        "RTCP",
+	} {
+		if k.String() != s {
+			t.Errorf("%q, expected %q", k, s)
+		}
+	}
+}
+
+func BenchmarkClassify(b *testing.B) {
+	m := MustBuild(TransactionID, BindingRequest)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if Classify(m.Raw) != PacketSTUN {
+			b.Fatal("should be STUN")
+		}
+	}
+}
@@ -1,12 +1,15 @@
 package stun
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,7 +17,14 @@ import (
 
 // Dial connects to the address on the named network and then
 // initializes Client on that connection, returning error if any.
+//
+// For "tcp" networks messages are framed on the stream and
+// retransmissions are disabled, see NewClient. Use "tls" network for
+// STUN over TLS with default configuration, see DialTLS.
 func Dial(network, address string) (*Client, error) {
+	if network == "tls" {
+		return DialTLS(address, nil)
+	}
 	conn, err := net.Dial(network, address)
 	if err != nil {
 		return nil, err
@@ -22,6 +32,21 @@ func Dial(network, address string) (*Client, error) {
 	return NewClient(conn)
 }
 
+// DialTLS connects to the address over TLS using config and initializes
+// Client on that connection. If address has no port, DefaultTLSPort
+// is used. Nil config is same as zero config, so server certificate is
+// verified against address host and system roots.
+func DialTLS(address string, config *tls.Config) (*Client, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(DefaultTLSPort))
+	}
+	conn, err := tls.Dial("tcp", address, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn)
+}
+
 // ErrNoConnection means that ClientOptions.Connection is nil.
 var ErrNoConnection = errors.New("no connection provided")
 
@@ -37,21 +62,51 @@ func WithHandler(h Handler) ClientOption {
 	}
 }
 
+// WithIndicationHandler sets handler of indications of type t, which
+// is called instead of handler of WithHandler, see also
+// Client.HandleIndication.
+func WithIndicationHandler(t MessageType, h Handler) ClientOption {
+	return func(c *Client) {
+		if c.indications == nil {
+			c.indications = make(map[MessageType]Handler)
+		}
+		c.indications[t] = h
+	}
+}
+
 // WithRTO sets client RTO as defined in STUN RFC.
 func WithRTO(rto time.Duration) ClientOption {
 	return func(c *Client) {
 		c.rto = int64(rto)
+		c.rtoSet = true
 	}
 }
 
 // WithClock sets Clock of client, the source of current time.
-// Also clock is passed to default collector if set.
+// Also clock is passed to default collector if set. If clock is
+// AfterClock, its timers drive default collector, keepalives and pacing
+// of DoBatch, so retransmissions and timeouts can be tested without
+// real sleeps.
 func WithClock(clock Clock) ClientOption {
 	return func(c *Client) {
 		c.clock = clock
 	}
 }
 
+// WithTransactionIDReader sets source of transaction ids that are
+// generated by client, e.g. for keepalives, StartTemplate and
+// LongTermCredentials, see Client.TransactionID. Defaults to source of
+// package, see SetTransactionIDReader.
+func WithTransactionIDReader(r io.Reader) ClientOption {
+	return func(c *Client) {
+		if r == nil {
+			c.ids = transactionIDs
+			return
+		}
+		c.ids = &transactionIDReader{r: r}
+	}
+}
+
 // WithTimeoutRate sets RTO timer minimum resolution.
 func WithTimeoutRate(d time.Duration) ClientOption {
 	return func(c *Client) {
@@ -83,27 +138,91 @@ var WithNoConnClose ClientOption = func(c *Client) {
 	c.closeConn = false
 }
 
-// WithNoRetransmit disables retransmissions and sets RTO to
-// defaultMaxAttempts * defaultRTO which will be effectively time out
-// if not set.
+// WithNoRetransmit disables retransmissions, so transaction times out
+// after single RTO. RTO defaults to defaultTransactionTimeout (39.5 s)
+// if it is not set by WithRTO or is zero.
 //
 // Useful for TCP connections where transport handles RTO.
 func WithNoRetransmit(c *Client) {
 	c.maxAttempts = 0
-	if c.rto == 0 {
-		c.rto = defaultMaxAttempts * int64(defaultRTO)
+	c.rm = 1
+	if !c.rtoSet || c.rto == 0 {
+		c.rto = int64(defaultTransactionTimeout)
+	}
+}
+
+// WithRc sets maximum count of request transmissions, including
+// the first one. Values less than 1 are treated as 1.
+//
+// RFC 5389 Section 7.2.1 recommends Rc of 7.
+func WithRc(rc int) ClientOption {
+	return func(c *Client) {
+		if rc < 1 {
+			rc = 1
+		}
+		c.maxAttempts = int32(rc - 1)
+	}
+}
+
+// WithRm sets multiplier of RTO that is used as timeout after last
+// request transmission. Values less than 1 are treated as 1.
+//
+// RFC 5389 Section 7.2.1 recommends Rm of 16.
+func WithRm(rm int) ClientOption {
+	return func(c *Client) {
+		if rm < 1 {
+			rm = 1
+		}
+		c.rm = int32(rm)
 	}
 }
 
+// WithStream enables framing of messages on stream-oriented
+// connection and disables retransmissions, so transaction times out
+// after 39.5 s (RFC 5389 Section 7.2.2).
+//
+// Enabled by default for TCP connections.
+func WithStream(c *Client) {
+	c.stream = true
+	WithNoRetransmit(c)
+}
+
+// WithSpec pins edition of STUN specification that is used by client:
+// with SpecRFC3489 responses without magic cookie are accepted, and
+// editions before RFC 8489 disable its features in long-term
+// credentials. Defaults to SpecRFC8489.
+func WithSpec(spec Spec) ClientOption {
+	return func(c *Client) {
+		c.spec = spec
+	}
+}
+
+func isStream(conn Connection) bool {
+	l, ok := conn.(interface {
+		LocalAddr() net.Addr
+	})
+	if !ok {
+		return false
+	}
+	_, ok = l.LocalAddr().(*net.TCPAddr)
+	return ok
+}
+
+// Default retransmission parameters as recommended in RFC 5389
+// Section 7.2.1: requests are sent at 0, 500, 1500, 3500, 7500, 15500
+// and 31500 ms and transaction times out at 39500 ms.
 const (
-	defaultTimeoutRate = time.Millisecond * 5
-	defaultRTO         = time.Millisecond * 300
-	defaultMaxAttempts = 7
+	defaultTimeoutRate        = time.Millisecond * 5
+	defaultRTO                = time.Millisecond * 500
+	defaultMaxAttempts        = 6 // Rc - 1
+	defaultRm                 = 16
+	defaultTransactionTimeout = time.Millisecond * 39500
 )
 
 // NewClient initializes new Client from provided options,
 // starting internal goroutines and using default options fields
-// if necessary. Call Close method after using Client to close conn and
+// if necessary. Invalid option values are rejected with OptionErr,
+// see also ClientConfig. Call Close method after using Client to close conn and
 // release resources.
 //
 // The conn will be closed on Close call. Use WithNoConnClose option to
@@ -112,23 +231,37 @@ const (
 // Note that user should handle the protocol multiplexing, client does not
 // provide any API for it, so if you need to read application data, wrap the
 // connection with your (de-)multiplexer and pass the wrapper as conn.
+//
+// If conn is stream-oriented (i.e. LocalAddr is *net.TCPAddr), messages
+// are read back-to-back from the stream, retransmissions are disabled
+// and transaction times out after 39.5 seconds as defined in
+// RFC 5389 Section 7.2.2. Use WithStream to enable this explicitly.
 func NewClient(conn Connection, options ...ClientOption) (*Client, error) {
 	c := &Client{
 		close:       make(chan struct{}),
 		c:           conn,
 		clock:       systemClock,
+		ids:         transactionIDs,
 		rto:         int64(defaultRTO),
 		rtoRate:     defaultTimeoutRate,
+		pacing:      defaultPacing,
 		t:           make(map[transactionID]*clientTransaction, 100),
 		maxAttempts: defaultMaxAttempts,
+		rm:          defaultRm,
 		closeConn:   true,
 	}
+	if isStream(conn) {
+		WithStream(c)
+	}
 	for _, o := range options {
 		o(c)
 	}
 	if c.c == nil {
 		return nil, ErrNoConnection
 	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
 	if c.a == nil {
 		c.a = NewAgent(nil)
 	}
@@ -148,10 +281,30 @@ func NewClient(conn Connection, options ...ClientOption) (*Client, error) {
 	}
 	c.wg.Add(1)
 	go c.readUntilClosed()
+	if c.keepalive.autostart {
+		if err := c.StartKeepalive(); err != nil {
+			return nil, err
+		}
+	}
 	runtime.SetFinalizer(c, clientFinalizer)
 	return c, nil
 }
 
+// validate returns OptionErr if some option value is invalid.
+func (c *Client) validate() error {
+	switch {
+	case c.rto <= 0:
+		return OptionErr{Option: "RTO", Value: time.Duration(c.rto)}
+	case c.rtoRate <= 0:
+		return OptionErr{Option: "TimeoutRate", Value: c.rtoRate}
+	case c.pacing < 0:
+		return OptionErr{Option: "Pacing", Value: c.pacing}
+	case c.spec.edition() == 0:
+		return OptionErr{Option: "Spec", Value: c.spec}
+	}
+	return c.keepalive.validate()
+}
+
 func clientFinalizer(c *Client) {
 	if c == nil {
 		return
@@ -187,21 +340,34 @@ type ClientAgent interface {
 
 // Client simulates "connection" to STUN server.
 type Client struct {
-	rto         int64 // time.Duration
+	rto         int64  // time.Duration
+	panics      uint64 // recovered in read loop
+	quirks      uint32 // Quirks
 	a           ClientAgent
 	c           Connection
 	close       chan struct{}
 	rtoRate     time.Duration
-	maxAttempts int32
+	pacing      time.Duration
+	maxAttempts int32 // retransmissions count, Rc - 1
+	rm          int32
 	closed      bool
 	closeConn   bool // should call c.Close() while closing
+	rtoSet      bool // rto is set by WithRTO
+	stream      bool // messages are framed on stream
+	spec        Spec
 	wg          sync.WaitGroup
 	clock       Clock
+	ids         *transactionIDReader
 	handler     Handler
+	errorLog    *log.Logger
 	collector   Collector
+	quirksTable QuirksTable
+	keepalive   keepalive
 	t           map[transactionID]*clientTransaction
+	retransmits map[transactionID]error // cancellation errors of transactions in retransmission
+	indications map[MessageType]Handler
 
-	// mux guards closed and t
+	// mux guards closed, t, retransmits and indications
 	mux sync.RWMutex
 }
 
@@ -210,13 +376,16 @@ type Client struct {
 // provided by event.
 // Concurrent access is invalid.
 type clientTransaction struct {
-	id      transactionID
-	attempt int32
-	calls   int32
-	h       Handler
-	start   time.Time
-	rto     time.Duration
-	raw     []byte
+	id          transactionID
+	attempt     int32
+	maxAttempts int32
+	rm          int32
+	calls       int32
+	h           Handler
+	start       time.Time
+	rto         time.Duration
+	raw         []byte
+	template    *Template // rebuilds raw for retransmissions if set
 }
 
 func (t *clientTransaction) handle(e Event) {
@@ -239,14 +408,31 @@ func acquireClientTransaction() *clientTransaction {
 
 func putClientTransaction(t *clientTransaction) {
 	t.raw = t.raw[:0]
+	t.template = nil
 	t.start = time.Time{}
 	t.attempt = 0
 	t.id = transactionID{}
 	clientTransactionPool.Put(t)
 }
 
+// rebuild builds t.raw from t.template with the same transaction id,
+// refreshing late-binding attributes.
+func (t *clientTransaction) rebuild() error {
+	m := &Message{Raw: t.raw[:0]}
+	if err := t.template.Build(m, t.id); err != nil {
+		return err
+	}
+	t.raw = m.Raw
+	return nil
+}
+
+// nextTimeout returns deadline of current attempt. RTO is doubled after
+// each transmission and last transmission is waited for Rm * RTO.
 func (t *clientTransaction) nextTimeout(now time.Time) time.Time {
-	return now.Add(time.Duration(t.attempt+1) * t.rto)
+	if t.attempt >= t.maxAttempts {
+		return now.Add(time.Duration(t.rm) * t.rto)
+	}
+	return now.Add(t.rto << uint(t.attempt))
 }
 
 // start registers transaction.
@@ -258,6 +444,13 @@ func (c *Client) start(t *clientTransaction) error {
 	if c.closed {
 		return ErrClientClosed
 	}
+	if err, retransmit := c.retransmits[t.id]; retransmit {
+		delete(c.retransmits, t.id)
+		if err != nil {
+			// Transaction was cancelled during retransmission.
+			return err
+		}
+	}
 	_, exists := c.t[t.id]
 	if exists {
 		return ErrTransactionExists
@@ -266,11 +459,78 @@ func (c *Client) start(t *clientTransaction) error {
 	return nil
 }
 
+// ErrTransactionCancelled is Event.Error of transaction that was
+// cancelled by Client.Cancel.
+var ErrTransactionCancelled = errors.New("transaction is cancelled")
+
+// Cancel completes transaction id with ErrTransactionCancelled without
+// waiting for response or timeout, so pending retransmissions are not
+// sent, e.g. when ICE prunes candidate pair during connectivity check.
+// Handler is called before Cancel returns, unless transaction is being
+// retransmitted concurrently.
+//
+// Could return ErrClientClosed, ErrTransactionNotExists.
+func (c *Client) Cancel(id [TransactionIDSize]byte) error {
+	if err := c.checkInit(); err != nil {
+		return err
+	}
+	return c.cancel(id, ErrTransactionCancelled)
+}
+
+// cancel completes transaction id with err. If transaction is being
+// retransmitted, err is returned by start on its registration, so
+// handler is called with err too.
+func (c *Client) cancel(id transactionID, err error) error {
+	c.mux.Lock()
+	if c.closed {
+		c.mux.Unlock()
+		return ErrClientClosed
+	}
+	t, found := c.t[id]
+	_, retransmit := c.retransmits[id]
+	if found {
+		delete(c.t, id)
+	} else if retransmit {
+		c.retransmits[id] = err
+	}
+	c.mux.Unlock()
+	if !found {
+		if !retransmit {
+			return ErrTransactionNotExists
+		}
+		return nil
+	}
+	// Stopping agent transaction. This will call handleAgentCallback
+	// with "ErrTransactionStopped" error which will be ignored.
+	_ = c.a.Stop(id)
+	t.handle(Event{
+		TransactionID: id,
+		Error:         err,
+	})
+	putClientTransaction(t)
+	return nil
+}
+
 // Clock abstracts the source of current time.
 type Clock interface {
 	Now() time.Time
 }
 
+// AfterClock is Clock that also provides timers, like time.After.
+type AfterClock interface {
+	Clock
+	After(d time.Duration) <-chan time.Time
+}
+
+// after returns channel that receives time after d by clock, falling
+// back to time.After if clock is not AfterClock.
+func after(clock Clock, d time.Duration) <-chan time.Time {
+	if c, ok := clock.(AfterClock); ok {
+		return c.After(d)
+	}
+	return time.After(d)
+}
+
 type systemClockService struct{}
 
 func (systemClockService) Now() time.Time { return time.Now() }
@@ -312,7 +572,10 @@ func (c CloseErr) Error() string {
 
 func (c *Client) readUntilClosed() {
 	defer c.wg.Done()
-	m := new(Message)
+	var (
+		m       = new(Message)
+		decoder = Decoder{Classic: c.spec == SpecRFC3489}
+	)
 	m.Raw = make([]byte, 1024)
 	for {
 		select {
@@ -320,15 +583,55 @@ func (c *Client) readUntilClosed() {
 			return
 		default:
 		}
-		_, err := m.ReadFrom(c.c)
+		var err error
+		if c.stream {
+			if err = readStreamMessage(c.c, m); err != nil {
+				// Framing is lost, no further messages can be read.
+				return
+			}
+		} else {
+			var n int
+			n, err = c.c.Read(m.Raw[:cap(m.Raw)])
+			m.Raw = m.Raw[:n]
+		}
+		if err == nil {
+			err = decoder.Decode(m.Raw, m)
+		}
 		if err == nil {
-			if pErr := c.a.Process(m); pErr == ErrAgentClosed {
+			if pErr := c.process(m); pErr == ErrAgentClosed {
 				return
 			}
 		}
 	}
 }
 
+// readStreamMessage reads single message from stream r to m.Raw, reading
+// header first and then message body of length from header. Message
+// is not decoded.
+func readStreamMessage(r io.Reader, m *Message) error {
+	if cap(m.Raw) < messageHeaderSize {
+		m.Raw = make([]byte, messageHeaderSize, 1024)
+	}
+	m.Raw = m.Raw[:messageHeaderSize]
+	if _, err := io.ReadFull(r, m.Raw); err != nil {
+		return err
+	}
+	fullSize := messageHeaderSize + int(bin.Uint16(m.Raw[2:4]))
+	if cap(m.Raw) < fullSize {
+		raw := make([]byte, fullSize)
+		copy(raw, m.Raw)
+		m.Raw = raw
+	}
+	m.Raw = m.Raw[:fullSize]
+	if _, err := io.ReadFull(r, m.Raw[messageHeaderSize:]); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
 func closedOrPanic(err error) {
 	if err == nil || err == ErrAgentClosed {
 		return
@@ -351,6 +654,21 @@ type Collector interface {
 }
 
 func (a *tickerCollector) Start(rate time.Duration, f func(now time.Time)) error {
+	if clock, ok := a.clock.(AfterClock); ok {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			for {
+				select {
+				case <-a.close:
+					return
+				case <-clock.After(rate):
+					f(a.clock.Now())
+				}
+			}
+		}()
+		return nil
+	}
 	t := time.NewTicker(rate)
 	a.wg.Add(1)
 	go func() {
@@ -389,6 +707,8 @@ func (c *Client) Close() error {
 	}
 	c.closed = true
 	c.mux.Unlock()
+	// Keepalive can not be started after closed is set.
+	_ = c.StopKeepalive()
 	if closeErr := c.collector.Close(); closeErr != nil {
 		return closeErr
 	}
@@ -408,6 +728,33 @@ func (c *Client) Close() error {
 	}
 }
 
+// ErrNotIndication means that message type is not indication.
+var ErrNotIndication = errors.New("message type is not indication")
+
+// HandleIndication sets handler of unsolicited indications of type t
+// from server, e.g. Data indications of TURN, replacing previous one.
+// Nil h removes handler, so such indications are passed to handler of
+// WithHandler. The e.Message is valid only until h returns.
+func (c *Client) HandleIndication(t MessageType, h Handler) error {
+	if t.Class != ClassIndication {
+		return ErrNotIndication
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.closed {
+		return ErrClientClosed
+	}
+	if h == nil {
+		delete(c.indications, t)
+		return nil
+	}
+	if c.indications == nil {
+		c.indications = make(map[MessageType]Handler)
+	}
+	c.indications[t] = h
+	return nil
+}
+
 // Indicate sends indication m to server. Shorthand to Start call
 // with zero deadline and callback.
 func (c *Client) Indicate(m *Message) error {
@@ -424,13 +771,16 @@ type callbackWaitHandler struct {
 
 func (s *callbackWaitHandler) HandleEvent(e Event) {
 	s.cond.L.Lock()
+	defer func() {
+		// Releasing waiter even if callback panics.
+		s.processed = true
+		s.cond.Broadcast()
+		s.cond.L.Unlock()
+	}()
 	if s.callback == nil {
 		panic("s.callback is nil")
 	}
 	s.callback(e)
-	s.processed = true
-	s.cond.Broadcast()
-	s.cond.L.Unlock()
 }
 
 func (s *callbackWaitHandler) wait() {
@@ -485,18 +835,103 @@ func (c *Client) Do(m *Message, f func(Event)) error {
 	if f == nil {
 		return c.Indicate(m)
 	}
+	return c.wait(f, func(h Handler) error {
+		return c.Start(m, h)
+	})
+}
+
+// DoCtx is Do that cancels transaction when ctx is done, so f is
+// called with ctx.Err() as Event.Error, stopping retransmissions. If ctx
+// is already done, ctx.Err() is returned and m is not sent.
+func (c *Client) DoCtx(ctx context.Context, m *Message, f func(Event)) error {
+	if err := c.checkInit(); err != nil {
+		return err
+	}
+	if f == nil {
+		return c.Indicate(m)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	if err := c.Start(m, func(e Event) {
+		defer close(done)
+		f(e)
+	}); err != nil {
+		return err
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	// Error is returned for already completed transaction.
+	_ = c.cancel(m.TransactionID, ctx.Err())
+	<-done
+	return nil
+}
+
+// DoTemplate is StartTemplate wrapper that waits until f is called.
+func (c *Client) DoTemplate(t *Template, f func(Event)) error {
+	if err := c.checkInit(); err != nil {
+		return err
+	}
+	return c.wait(f, func(h Handler) error {
+		return c.StartTemplate(t, h)
+	})
+}
+
+// wait calls start with handler that calls f, waiting until f is
+// called.
+func (c *Client) wait(f func(Event), start func(h Handler) error) error {
 	h := callbackWaitHandlerPool.Get().(*callbackWaitHandler)
 	h.setCallback(f)
 	defer func() {
 		callbackWaitHandlerPool.Put(h)
 	}()
-	if err := c.Start(m, h.handler); err != nil {
+	if err := start(h.handler); err != nil {
 		return err
 	}
 	h.wait()
 	return nil
 }
 
+// AttrMissingErr occurs when response does not contain attributes that
+// were expected by ExpectAttributes.
+type AttrMissingErr struct {
+	Types []AttrType
+}
+
+func (e *AttrMissingErr) Error() string {
+	s := "response is missing attributes: "
+	for i, t := range e.Types {
+		if i != 0 {
+			s += ", "
+		}
+		s += t.String()
+	}
+	return s
+}
+
+// ExpectAttributes wraps f so that success responses missing any of
+// the provided attribute types are passed to f with *AttrMissingErr as
+// Event.Error. Event.Message is still set, so f can inspect response.
+// Events with errors and error responses are passed unchanged.
+//
+// Example:
+//
+//	c.Do(m, ExpectAttributes(f, AttrXORMappedAddress))
+func ExpectAttributes(f func(e Event), types ...AttrType) func(e Event) {
+	return func(e Event) {
+		if e.Error == nil && e.Message != nil && e.Message.Type.Class == ClassSuccessResponse {
+			if missing := e.Message.Missing(types...); len(missing) > 0 {
+				e.Error = &AttrMissingErr{Types: missing}
+			}
+		}
+		f(e)
+	}
+}
+
 func (c *Client) delete(id transactionID) {
 	c.mux.Lock()
 	if c.t != nil {
@@ -515,7 +950,44 @@ var bufferPool = &sync.Pool{
 	},
 }
 
+// Quirks returns quirks of server that are detected by SOFTWARE of last
+// response, or zero if no quirks table is set by WithQuirks.
+func (c *Client) Quirks() Quirks {
+	return Quirks(atomic.LoadUint32(&c.quirks))
+}
+
+// Spec returns edition of specification that is set by WithSpec.
+func (c *Client) Spec() Spec {
+	return c.spec
+}
+
+// TransactionID returns Setter of transaction id from source of client,
+// see WithTransactionIDReader, for requests that are passed to Start.
+func (c *Client) TransactionID() Setter {
+	return c.ids
+}
+
+func (c *Client) detectQuirks(m *Message) {
+	software, err := m.Get(AttrSoftware)
+	if err != nil {
+		return
+	}
+	atomic.StoreUint32(&c.quirks, uint32(c.quirksTable.Lookup(string(software))))
+}
+
+// completed returns true if transaction of e should not be
+// retransmitted: it is succeeded, stopped or its agent is closed.
+func completed(e Event) bool {
+	return e.Error == nil || e.Error == ErrTransactionStopped || e.Error == ErrAgentClosed
+}
+
 func (c *Client) handleAgentCallback(e Event) {
+	if c.quirksTable != nil && e.Message != nil {
+		c.detectQuirks(e.Message)
+	}
+	if c.keepalive.config.OnAddressChange != nil && e.Message != nil && e.Message.Type == BindingSuccess {
+		c.detectAddress(e.Message)
+	}
 	c.mux.Lock()
 	if c.closed {
 		c.mux.Unlock()
@@ -524,8 +996,24 @@ func (c *Client) handleAgentCallback(e Event) {
 	t, found := c.t[e.TransactionID]
 	if found {
 		delete(c.t, t.id)
+		if t.attempt < t.maxAttempts && !completed(e) {
+			// Transaction is unregistered until retransmission, marking it so
+			// it can be cancelled.
+			if c.retransmits == nil {
+				c.retransmits = make(map[transactionID]error)
+			}
+			c.retransmits[t.id] = nil
+		}
+	}
+	var indication Handler
+	if !found && e.Message != nil && e.Message.Type.Class == ClassIndication {
+		indication = c.indications[e.Message.Type]
 	}
 	c.mux.Unlock()
+	if indication != nil {
+		indication(e)
+		return
+	}
 	if !found {
 		if c.handler != nil && e.Error != ErrTransactionStopped {
 			c.handler(e)
@@ -533,7 +1021,7 @@ func (c *Client) handleAgentCallback(e Event) {
 		// Ignoring.
 		return
 	}
-	if atomic.LoadInt32(&c.maxAttempts) <= t.attempt || e.Error == nil {
+	if t.maxAttempts <= t.attempt || completed(e) {
 		// Transaction completed.
 		t.handle(e)
 		putClientTransaction(t)
@@ -541,6 +1029,17 @@ func (c *Client) handleAgentCallback(e Event) {
 	}
 	// Doing re-transmission.
 	t.attempt++
+	if t.template != nil {
+		if buildErr := t.rebuild(); buildErr != nil {
+			c.mux.Lock()
+			delete(c.retransmits, t.id)
+			c.mux.Unlock()
+			e.Error = buildErr
+			t.handle(e)
+			putClientTransaction(t)
+			return
+		}
+	}
 	b := bufferPool.Get().(*buffer)
 	b.buf = b.buf[:copy(b.buf[:cap(b.buf)], t.raw)]
 	defer bufferPool.Put(b)
@@ -565,6 +1064,16 @@ func (c *Client) handleAgentCallback(e Event) {
 		putClientTransaction(t)
 		return
 	}
+	c.mux.RLock()
+	_, found = c.t[id]
+	c.mux.RUnlock()
+	if !found {
+		// Transaction was cancelled and handled after registration, so
+		// retransmission is not sent. This will call handleAgentCallback
+		// with "ErrTransactionStopped" error which will be ignored.
+		_ = c.a.Stop(id)
+		return
+	}
 	// Writing message to connection again.
 	_, writeErr := c.c.Write(b.buf)
 	if writeErr != nil {
@@ -589,6 +1098,26 @@ func (c *Client) handleAgentCallback(e Event) {
 // Start starts transaction (if h set) and writes message to server, handler
 // is called asynchronously.
 func (c *Client) Start(m *Message, h Handler) error {
+	return c.startMessage(m, h, nil)
+}
+
+// StartTemplate builds request from template with new transaction id
+// and starts transaction like Start. Each retransmission is built from
+// template again with the same transaction id, so late-binding
+// attributes are refreshed.
+func (c *Client) StartTemplate(t *Template, h Handler) error {
+	var id [TransactionIDSize]byte
+	if err := c.ids.read(&id); err != nil {
+		return err
+	}
+	m := new(Message)
+	if err := t.Build(m, id); err != nil {
+		return err
+	}
+	return c.startMessage(m, h, t)
+}
+
+func (c *Client) startMessage(m *Message, h Handler, template *Template) error {
 	if err := c.checkInit(); err != nil {
 		return err
 	}
@@ -606,7 +1135,10 @@ func (c *Client) Start(m *Message, h Handler) error {
 		t.h = h
 		t.rto = time.Duration(atomic.LoadInt64(&c.rto))
 		t.attempt = 0
+		t.maxAttempts = atomic.LoadInt32(&c.maxAttempts)
+		t.rm = atomic.LoadInt32(&c.rm)
 		t.raw = append(t.raw[:0], m.Raw...)
+		t.template = template
 		t.calls = 0
 		d := t.nextTimeout(t.start)
 		if err := c.start(t); err != nil {
@@ -3,12 +3,21 @@ package stun
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math/big"
 	"net"
 	"os"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -256,6 +265,52 @@ func TestClient_Do(t *testing.T) {
 	}
 }
 
+func TestExpectAttributes(t *testing.T) {
+	var (
+		last    Event
+		handler = ExpectAttributes(func(e Event) {
+			last = e
+		}, AttrXORMappedAddress, AttrSoftware)
+	)
+	handler(Event{
+		Message: MustBuild(TransactionID, BindingSuccess, NewSoftware("software")),
+	})
+	missingErr, ok := last.Error.(*AttrMissingErr)
+	if !ok {
+		t.Fatalf("unexpected error %v", last.Error)
+	}
+	if len(missingErr.Types) != 1 || missingErr.Types[0] != AttrXORMappedAddress {
+		t.Error("unexpected missing types:", missingErr.Types)
+	}
+	if missingErr.Error() != "response is missing attributes: XOR-MAPPED-ADDRESS" {
+		t.Error("bad error string:", missingErr)
+	}
+	if last.Message == nil {
+		t.Error("message should be set")
+	}
+	handler(Event{
+		Message: MustBuild(TransactionID, BindingSuccess,
+			NewSoftware("software"),
+			&XORMappedAddress{IP: net.IPv4(1, 2, 3, 4)},
+		),
+	})
+	if last.Error != nil {
+		t.Error("unexpected error:", last.Error)
+	}
+	handler(Event{
+		Message: MustBuild(TransactionID, BindingError, CodeBadRequest),
+	})
+	if last.Error != nil {
+		t.Error("error responses should not be checked:", last.Error)
+	}
+	handler(Event{
+		Error: ErrTransactionTimeOut,
+	})
+	if last.Error != ErrTransactionTimeOut {
+		t.Error("error should be passed unchanged:", last.Error)
+	}
+}
+
 func TestCloseErr_Error(t *testing.T) {
 	for id, c := range []struct {
 		Err CloseErr
@@ -521,7 +576,11 @@ func (a *gcWaitAgent) Close() error {
 }
 
 func (a *gcWaitAgent) Collect(time.Time) error {
-	a.gc <- struct{}{}
+	// Not blocking, so collector can be stopped on Close.
+	select {
+	case a.gc <- struct{}{}:
+	default:
+	}
 	return nil
 }
 
@@ -687,6 +746,57 @@ func (m *manualClock) Now() time.Time {
 	return m.current
 }
 
+// timerClock is AfterClock with timers that fire when time is moved by
+// Add.
+type timerClock struct {
+	mux     sync.Mutex
+	current time.Time
+	timers  []clockTimer
+}
+
+type clockTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func (c *timerClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.current
+}
+
+func (c *timerClock) After(d time.Duration) <-chan time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	t := clockTimer{at: c.current.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	return t.c
+}
+
+// Add waits until at least one timer is pending and moves time by d,
+// firing timers that are due.
+func (c *timerClock) Add(d time.Duration) {
+	for {
+		c.mux.Lock()
+		if len(c.timers) > 0 {
+			break
+		}
+		c.mux.Unlock()
+		runtime.Gosched()
+	}
+	defer c.mux.Unlock()
+	c.current = c.current.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.current) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.current
+	}
+	c.timers = pending
+}
+
 type manualAgent struct {
 	start   func(id [TransactionIDSize]byte, deadline time.Time) error
 	stop    func(id [TransactionIDSize]byte) error
@@ -756,6 +866,11 @@ func TestClientRetransmission(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
 	c.SetRTO(time.Second)
 	gotReads := make(chan struct{})
 	go func() {
@@ -809,6 +924,11 @@ func testClientDoConcurrent(t *testing.T, concurrency int) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
 	c.SetRTO(time.Second)
 	conns := new(sync.WaitGroup)
 	wg := new(sync.WaitGroup)
@@ -1008,6 +1128,11 @@ func TestWithNoRetransmit(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
 	gotReads := make(chan struct{})
 	go func() {
 		buf := make([]byte, 1500)
@@ -1224,6 +1349,11 @@ func TestClientRTOWriteErr(t *testing.T) {
 	if startClientErr != nil {
 		t.Fatal(startClientErr)
 	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
 	go func() {
 		buf := make([]byte, 1500)
 		readN, readErr := connL.Read(buf)
@@ -1308,6 +1438,11 @@ func TestClientRTOAgentErr(t *testing.T) {
 	if startClientErr != nil {
 		t.Fatal(startClientErr)
 	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
 	go func() {
 		buf := make([]byte, 1500)
 		readN, readErr := connL.Read(buf)
@@ -1416,6 +1551,11 @@ func TestClientImmediateTimeout(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
 	gotReads := make(chan struct{})
 	go func() {
 		buf := make([]byte, 1500)
@@ -1435,3 +1575,605 @@ func TestClientImmediateTimeout(t *testing.T) {
 	})
 	<-gotReads
 }
+
+func TestClientTransaction_nextTimeout(t *testing.T) {
+	c, err := NewClient(noopConnection{}, WithAgent(&manualAgent{}), WithCollector(new(manualCollector)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var (
+		start = time.Unix(0, 0)
+		now   = start
+		tr    = &clientTransaction{
+			rto:         time.Duration(c.rto),
+			maxAttempts: c.maxAttempts,
+			rm:          c.rm,
+		}
+		sent []time.Duration
+	)
+	for {
+		sent = append(sent, now.Sub(start))
+		now = tr.nextTimeout(now)
+		if tr.attempt >= tr.maxAttempts {
+			break
+		}
+		tr.attempt++
+	}
+	expected := []time.Duration{0, 500, 1500, 3500, 7500, 15500, 31500}
+	if len(sent) != len(expected) {
+		t.Fatalf("%d transmissions, expected %d", len(sent), len(expected))
+	}
+	for i, d := range expected {
+		if sent[i] != d*time.Millisecond {
+			t.Errorf("transmission %d at %s, expected %s", i, sent[i], d*time.Millisecond)
+		}
+	}
+	if timeout := now.Sub(start); timeout != defaultTransactionTimeout {
+		t.Errorf("timeout at %s, expected %s", timeout, defaultTransactionTimeout)
+	}
+}
+
+func TestWithRcRm(t *testing.T) {
+	c := &Client{}
+	WithRc(3)(c)
+	WithRm(4)(c)
+	if c.maxAttempts != 2 || c.rm != 4 {
+		t.Errorf("unexpected maxAttempts %d, rm %d", c.maxAttempts, c.rm)
+	}
+	WithRc(0)(c)
+	WithRm(-1)(c)
+	if c.maxAttempts != 0 || c.rm != 1 {
+		t.Errorf("unexpected maxAttempts %d, rm %d", c.maxAttempts, c.rm)
+	}
+}
+
+func TestReadStreamMessage(t *testing.T) {
+	first := MustBuild(TransactionID, BindingRequest, NewSoftware("first"))
+	second := MustBuild(TransactionID, BindingSuccess, NewSoftware("second"))
+	stream := bytes.NewReader(append(append([]byte{}, first.Raw...), second.Raw...))
+	m := new(Message)
+	for _, expected := range []*Message{first, second} {
+		if err := readStreamMessage(stream, m); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Decode(); err != nil {
+			t.Fatal(err)
+		}
+		if !m.Equal(expected) {
+			t.Errorf("%s not equal to %s", m, expected)
+		}
+	}
+	if err := readStreamMessage(stream, m); err != io.EOF {
+		t.Error("should be EOF, got", err)
+	}
+	truncated := bytes.NewReader(first.Raw[:len(first.Raw)-1])
+	if err := readStreamMessage(truncated, m); err != io.ErrUnexpectedEOF {
+		t.Error("should be ErrUnexpectedEOF, got", err)
+	}
+}
+
+func TestClientTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, acceptErr := l.Accept()
+		if acceptErr != nil {
+			t.Error(acceptErr)
+			return
+		}
+		defer conn.Close()
+		var (
+			req     = new(Message)
+			written []byte
+		)
+		// Reading two requests and writing responses back-to-back.
+		for i := 0; i < 2; i++ {
+			if readErr := readStreamMessage(conn, req); readErr != nil {
+				t.Error(readErr)
+				return
+			}
+			if decErr := req.Decode(); decErr != nil {
+				t.Error(decErr)
+				return
+			}
+			written = append(written, MustBuild(req, BindingSuccess).Raw...)
+		}
+		if _, writeErr := conn.Write(written); writeErr != nil {
+			t.Error(writeErr)
+		}
+	}()
+	c, err := Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !c.stream || c.maxAttempts != 0 || time.Duration(c.rto) != defaultTransactionTimeout {
+		t.Error("tcp client should be in stream mode without retransmissions")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		if err := c.Start(MustBuild(TransactionID, BindingRequest), func(e Event) {
+			defer wg.Done()
+			if e.Error != nil {
+				t.Error(e.Error)
+			} else if e.Message.Type != BindingSuccess {
+				t.Error("unexpected type", e.Message.Type)
+			}
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
+
+func newTestTLSConfig(t *testing.T) (server, client *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	server = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	return server, &tls.Config{RootCAs: roots}
+}
+
+func TestDialTLS(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfig(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, acceptErr := l.Accept()
+			if acceptErr != nil {
+				return
+			}
+			req := new(Message)
+			if readErr := readStreamMessage(conn, req); readErr == nil && req.Decode() == nil {
+				_, _ = conn.Write(MustBuild(req, BindingSuccess).Raw)
+			}
+			conn.Close()
+		}
+	}()
+	t.Run("Verified", func(t *testing.T) {
+		c, err := DialTLS(l.Addr().String(), clientConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if !c.stream {
+			t.Error("tls client should be in stream mode")
+		}
+		if err := c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+			}
+		}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("Unverified", func(t *testing.T) {
+		if _, err := Dial("tls", l.Addr().String()); err == nil {
+			t.Error("should fail certificate verification")
+		}
+	})
+}
+
+func TestClient_Spec(t *testing.T) {
+	for _, tc := range []struct {
+		spec    Spec
+		success bool
+		rto     time.Duration
+	}{
+		// Response is ignored, so transaction times out.
+		{SpecRFC8489, false, time.Millisecond * 50},
+		{SpecRFC3489, true, time.Second * 5},
+	} {
+		t.Run(tc.spec.String(), func(t *testing.T) {
+			connL, connR := net.Pipe()
+			defer connL.Close()
+			c, err := NewClient(connR, WithSpec(tc.spec), WithRTO(tc.rto), WithNoRetransmit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if c.Spec() != tc.spec {
+				t.Errorf("unexpected spec %s", c.Spec())
+			}
+			go func() {
+				buf := make([]byte, 1500)
+				n, readErr := connL.Read(buf)
+				if readErr != nil {
+					t.Error(readErr)
+					return
+				}
+				req := new(Message)
+				if _, decodeErr := req.Write(buf[:n]); decodeErr != nil {
+					t.Error(decodeErr)
+					return
+				}
+				// Response of RFC 3489 server that does not echo cookie.
+				res := MustBuild(req, BindingSuccess, &MappedAddress{IP: net.IPv4(1, 2, 3, 4), Port: 1})
+				copy(res.Raw[4:8], []byte{1, 2, 3, 4})
+				if _, writeErr := connL.Write(res.Raw); writeErr != nil {
+					t.Error(writeErr)
+				}
+			}()
+			if doErr := c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+				if (e.Error == nil) != tc.success {
+					t.Errorf("unexpected error %v", e.Error)
+				}
+			}); doErr != nil {
+				t.Fatal(doErr)
+			}
+		})
+	}
+}
+
+func TestClientIndicationHandler(t *testing.T) {
+	a := &TestAgent{
+		e: make(chan Event),
+	}
+	dataIndication := NewType(MethodData, ClassIndication)
+	var gotDefault, gotBinding, gotData int
+	c, createErr := NewClient(noopConnection{},
+		WithAgent(a),
+		WithHandler(func(e Event) {
+			gotDefault++
+		}),
+		WithIndicationHandler(bindingIndication, func(e Event) {
+			gotBinding++
+		}),
+	)
+	if createErr != nil {
+		t.Fatal(createErr)
+	}
+	if err := c.HandleIndication(BindingRequest, func(e Event) {}); err != ErrNotIndication {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := c.HandleIndication(dataIndication, func(e Event) {
+		if e.Message.Type != dataIndication {
+			t.Errorf("unexpected type: %s", e.Message.Type)
+		}
+		gotData++
+	}); err != nil {
+		t.Fatal(err)
+	}
+	emit := func(typ MessageType) {
+		a.h(Event{
+			TransactionID: NewTransactionID(),
+			Message:       MustBuild(TransactionID, typ),
+		})
+	}
+	emit(bindingIndication)
+	emit(dataIndication)
+	emit(BindingSuccess)
+	if gotBinding != 1 || gotData != 1 || gotDefault != 1 {
+		t.Errorf("unexpected calls: %d, %d, %d", gotBinding, gotData, gotDefault)
+	}
+	if err := c.HandleIndication(dataIndication, nil); err != nil {
+		t.Fatal(err)
+	}
+	emit(dataIndication)
+	if gotData != 1 || gotDefault != 2 {
+		t.Errorf("handler should be removed: %d, %d", gotData, gotDefault)
+	}
+	if closeErr := c.Close(); closeErr != nil {
+		t.Error(closeErr)
+	}
+	if err := c.HandleIndication(dataIndication, nil); err != ErrClientClosed {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClient_DoCtx(t *testing.T) {
+	connL, connR := net.Pipe()
+	defer connL.Close()
+	c, err := NewClient(connR, WithRTO(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
+	requests := make(chan *Message, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, readErr := connL.Read(buf)
+			if readErr != nil {
+				return
+			}
+			m := new(Message)
+			if Decode(buf[:n], m) == nil {
+				requests <- m
+			}
+		}
+	}()
+	t.Run("Done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if doErr := c.DoCtx(ctx, MustBuild(TransactionID, BindingRequest), func(e Event) {
+			t.Error("should not be called")
+		}); doErr != context.Canceled {
+			t.Errorf("unexpected error: %v", doErr)
+		}
+	})
+	t.Run("Response", func(t *testing.T) {
+		go func() {
+			req := <-requests
+			res := MustBuild(req, BindingSuccess)
+			if _, writeErr := connL.Write(res.Raw); writeErr != nil {
+				t.Error(writeErr)
+			}
+		}()
+		if doErr := c.DoCtx(context.Background(), MustBuild(TransactionID, BindingRequest), func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+			} else if e.Message.Type != BindingSuccess {
+				t.Errorf("unexpected type: %s", e.Message.Type)
+			}
+		}); doErr != nil {
+			t.Fatal(doErr)
+		}
+	})
+	t.Run("Deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+		defer cancel()
+		if doErr := c.DoCtx(ctx, MustBuild(TransactionID, BindingRequest), func(e Event) {
+			if e.Error != context.DeadlineExceeded {
+				t.Errorf("unexpected error: %v", e.Error)
+			}
+		}); doErr != nil {
+			t.Fatal(doErr)
+		}
+		<-requests
+		c.mux.RLock()
+		pending, retransmits := len(c.t), len(c.retransmits)
+		c.mux.RUnlock()
+		if pending != 0 || retransmits != 0 {
+			t.Errorf("unexpected transactions: %d, %d", pending, retransmits)
+		}
+	})
+}
+
+func TestClient_Cancel(t *testing.T) {
+	t.Run("NotExists", func(t *testing.T) {
+		c, err := NewClient(noopConnection{}, WithAgent(&TestAgent{
+			e: make(chan Event),
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cancelErr := c.Cancel(transactionID{1}); cancelErr != ErrTransactionNotExists {
+			t.Errorf("unexpected error: %v", cancelErr)
+		}
+		if closeErr := c.Close(); closeErr != nil {
+			t.Fatal(closeErr)
+		}
+		if cancelErr := c.Cancel(transactionID{1}); cancelErr != ErrClientClosed {
+			t.Errorf("unexpected error: %v", cancelErr)
+		}
+	})
+	t.Run("Pending", func(t *testing.T) {
+		connL, connR := net.Pipe()
+		defer connL.Close()
+		clock := &timerClock{current: time.Now()}
+		c, err := NewClient(connR, WithClock(clock), WithRTO(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		writes := make(chan struct{}, 10)
+		go func() {
+			buf := make([]byte, 1500)
+			for {
+				if _, readErr := connL.Read(buf); readErr != nil {
+					return
+				}
+				writes <- struct{}{}
+			}
+		}()
+		m := MustBuild(TransactionID, BindingRequest)
+		done := make(chan error, 1)
+		if err = c.Start(m, func(e Event) {
+			done <- e.Error
+		}); err != nil {
+			t.Fatal(err)
+		}
+		<-writes
+		if err = c.Cancel(m.TransactionID); err != nil {
+			t.Fatal(err)
+		}
+		if doneErr := <-done; doneErr != ErrTransactionCancelled {
+			t.Errorf("unexpected error: %v", doneErr)
+		}
+		// Collector timer is still pending, firing it after RTO.
+		clock.Add(time.Second * 2)
+		if len(writes) != 0 {
+			t.Error("unexpected retransmission")
+		}
+		if err = c.Cancel(m.TransactionID); err != ErrTransactionNotExists {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("Retransmission", func(t *testing.T) {
+		c, err := NewClient(noopConnection{}, WithAgent(&TestAgent{
+			e: make(chan Event),
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		id := transactionID{1}
+		// Transaction is not registered while being retransmitted.
+		c.retransmits = map[transactionID]error{id: nil}
+		if cancelErr := c.cancel(id, context.Canceled); cancelErr != nil {
+			t.Fatal(cancelErr)
+		}
+		if startErr := c.start(&clientTransaction{id: id}); startErr != context.Canceled {
+			t.Errorf("unexpected error: %v", startErr)
+		}
+		if startErr := c.start(&clientTransaction{id: id}); startErr != nil {
+			t.Errorf("unexpected error: %v", startErr)
+		}
+	})
+}
+
+func TestWithTransactionIDReader(t *testing.T) {
+	ids := make([]byte, TransactionIDSize*2)
+	for i := range ids {
+		ids[i] = byte(i)
+	}
+	var written []byte
+	c, err := NewClient(&testConnection{
+		write: func(b []byte) (int, error) {
+			written = append(written[:0], b...)
+			return len(b), nil
+		},
+	}, WithAgent(&TestAgent{
+		e: make(chan Event, 10),
+	}), WithTransactionIDReader(bytes.NewReader(ids)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	m := MustBuild(c.TransactionID(), BindingRequest)
+	if !bytes.Equal(m.TransactionID[:], ids[:TransactionIDSize]) {
+		t.Errorf("unexpected id: %x", m.TransactionID)
+	}
+	template, err := NewTemplate([]Setter{BindingRequest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.StartTemplate(template, func(Event) {}); err != nil {
+		t.Fatal(err)
+	}
+	res := new(Message)
+	if _, err = res.Write(written); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res.TransactionID[:], ids[TransactionIDSize:]) {
+		t.Errorf("unexpected id: %x", res.TransactionID)
+	}
+	if err = c.StartTemplate(template, func(Event) {}); err != io.EOF {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClient_AfterClock(t *testing.T) {
+	connL, connR := net.Pipe()
+	defer connL.Close()
+	clock := &timerClock{current: time.Now()}
+	c, err := NewClient(connR,
+		WithClock(clock),
+		WithRTO(time.Second),
+		WithRc(2), WithRm(4),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
+	writes := make(chan struct{}, 10)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, readErr := connL.Read(buf); readErr != nil {
+				return
+			}
+			writes <- struct{}{}
+		}
+	}()
+	done := make(chan error, 1)
+	if err = c.Start(MustBuild(TransactionID, BindingRequest), func(e Event) {
+		done <- e.Error
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-writes
+	// Retransmission after RTO and timeout after Rm * RTO.
+	clock.Add(time.Second + time.Millisecond)
+	<-writes
+	for {
+		select {
+		case doneErr := <-done:
+			if doneErr != ErrTransactionTimeOut {
+				t.Errorf("unexpected error: %v", doneErr)
+			}
+			if len(writes) != 0 {
+				t.Error("unexpected retransmission")
+			}
+			return
+		default:
+			clock.Add(time.Second)
+		}
+	}
+}
+
+func TestWithNoRetransmit_timeout(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []ClientOption
+		timeout time.Duration
+	}{
+		{"Default", []ClientOption{WithNoRetransmit}, defaultTransactionTimeout},
+		{"RTOBefore", []ClientOption{WithRTO(time.Second), WithNoRetransmit}, time.Second},
+		{"RTOAfter", []ClientOption{WithNoRetransmit, WithRTO(time.Second)}, time.Second},
+		{"Stream", []ClientOption{WithRTO(time.Second), WithStream}, time.Second},
+		{"ZeroRTO", []ClientOption{WithRTO(0), WithNoRetransmit}, defaultTransactionTimeout},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			options := append([]ClientOption{WithAgent(&manualAgent{}), WithCollector(new(manualCollector))}, tc.options...)
+			c, err := NewClient(noopConnection{}, options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			start := time.Unix(0, 0)
+			tr := &clientTransaction{
+				rto:         time.Duration(c.rto),
+				maxAttempts: c.maxAttempts,
+				rm:          c.rm,
+			}
+			if timeout := tr.nextTimeout(start).Sub(start); timeout != tc.timeout {
+				t.Errorf("timeout at %s, expected %s", timeout, tc.timeout)
+			}
+			if tr.attempt < tr.maxAttempts {
+				t.Error("should not retransmit")
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"os/signal"
 	"runtime"
 	"runtime/pprof"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -83,7 +84,7 @@ func main() {
 		fmt.Println("using crypto/rand as random source for transaction id")
 	}
 	for i := 0; i < *workers; i++ {
-		wConn, connErr := net.Dial(*network, fmt.Sprintf("%s:%d", *addr, *port))
+		wConn, connErr := net.Dial(*network, net.JoinHostPort(*addr, strconv.Itoa(*port)))
 		if connErr != nil {
 			log.Fatalln("failed to dial:", wConn)
 		}
@@ -1,37 +1,98 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 
 	"github.com/pion/stun"
+	"github.com/pion/stun/selftest"
+	"github.com/pion/stun/stuntest"
+)
+
+var (
+	trace    = flag.String("trace", "", "record session to file, see stun-trace")
+	features = flag.Bool("features", false, "print RFC 8489 features detected from response")
+	inspect  = flag.Bool("inspect", false, "print compatibility report of server")
+	selfTest = flag.Bool("self-test", false, "run diagnostic of NAT and server reachability and print summary")
+	username = flag.String("username", "", "username of long-term credentials for self-test")
+	password = flag.String("password", "", "password of long-term credentials for self-test")
+	interval = flag.Duration("keepalive", stun.DefaultKeepaliveInterval, "keepalive interval to check in self-test, negative to skip")
 )
 
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, os.Args[0], "stun.l.google.com:19302")
+		flag.PrintDefaults()
 	}
 	flag.Parse()
 	addr := flag.Arg(0)
 	if addr == "" {
 		addr = "stun.l.google.com:19302"
 	}
-	c, err := stun.Dial("udp", addr)
+	if *inspect {
+		r, err := stun.Inspect(context.Background(), addr)
+		for _, t := range r.Transports {
+			if t.Err != nil {
+				fmt.Println(t.Network+":", t.Err)
+			}
+		}
+		if err != nil {
+			log.Fatalln("inspect:", err)
+		}
+		fmt.Println(r)
+		return
+	}
+	if *selfTest {
+		tester := &selftest.Tester{
+			Server:            addr,
+			Username:          *username,
+			Password:          *password,
+			KeepaliveInterval: *interval,
+		}
+		r := tester.Run()
+		if _, err := r.WriteTo(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		if !r.Passed() {
+			os.Exit(1)
+		}
+		return
+	}
+	conn, err := net.Dial("udp", addr)
 	if err != nil {
 		log.Fatal("dial:", err)
 	}
+	var connection stun.Connection = conn
+	if *trace != "" {
+		f, createErr := os.Create(*trace)
+		if createErr != nil {
+			log.Fatalln("trace:", createErr)
+		}
+		defer f.Close()
+		connection = stuntest.NewRecorder(conn, f)
+	}
+	c, err := stun.NewClient(connection)
+	if err != nil {
+		log.Fatal("client:", err)
+	}
 	if err = c.Do(stun.MustBuild(stun.TransactionID, stun.BindingRequest), func(res stun.Event) {
 		if res.Error != nil {
 			log.Fatalln(err)
 		}
-		var xorAddr stun.XORMappedAddress
-		if getErr := xorAddr.GetFrom(res.Message); getErr != nil {
+		// Falling back to MAPPED-ADDRESS for RFC 3489 servers.
+		var addr stun.ReflexiveAddress
+		if getErr := addr.GetFrom(res.Message); getErr != nil {
 			log.Fatalln(getErr)
 		}
-		fmt.Println(xorAddr)
+		fmt.Println(addr)
+		if *features {
+			fmt.Println("features:", stun.DetectServerFeatures(res.Message))
+		}
 	}); err != nil {
 		log.Fatal("do:", err)
 	}
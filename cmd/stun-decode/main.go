@@ -10,6 +10,12 @@ import (
 	"github.com/pion/stun"
 )
 
+var (
+	username = flag.String("username", "", "username for long-term credentials")
+	realm    = flag.String("realm", "", "realm for long-term credentials")
+	password = flag.String("password", "", "password to verify integrity, otherwise it is reported as unverified")
+)
+
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", "stun-decode")
@@ -28,4 +34,15 @@ func main() {
 		log.Fatalln("Unable to decode message:", err)
 	}
 	fmt.Println(m)
+	var key []byte
+	switch {
+	case *password == "":
+	case *realm != "":
+		key = stun.NewLongTermIntegrity(*username, *realm, *password)
+	default:
+		key = stun.NewShortTermIntegrity(*password)
+	}
+	for _, info := range stun.InspectIntegrity(m, key) {
+		fmt.Println(info)
+	}
 }
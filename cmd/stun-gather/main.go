@@ -0,0 +1,272 @@
+// Command stun-gather is an example of ICE candidate gathering: host
+// candidates are gathered from local interfaces and server-reflexive
+// candidates are discovered via STUN server for each host candidate.
+// If TURN server is set, relayed candidates are allocated on it for
+// each host candidate too.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/pion/stun"
+	"github.com/pion/stun/turn"
+)
+
+var (
+	server       = flag.String("server", "stun.l.google.com:19302", "Stun server address")
+	ipv6         = flag.Bool("6", false, "Gather IPv6 candidates too")
+	turnServer   = flag.String("turn", "", "TURN server address for relayed candidates")
+	turnUser     = flag.String("turn-user", "", "TURN username")
+	turnPassword = flag.String("turn-password", "", "TURN password")
+)
+
+// candidate is simplified ICE candidate.
+type candidate struct {
+	Type     string
+	Addr     *net.UDPAddr
+	Related  *net.UDPAddr // base of server-reflexive or mapped address of relayed candidate
+	Priority stun.Priority
+}
+
+func (c candidate) String() string {
+	s := fmt.Sprintf("%s %s priority %d", c.Type, c.Addr, c.Priority)
+	if c.Related != nil {
+		s += fmt.Sprintf(" related %s", c.Related)
+	}
+	return s
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", "stun-gather")
+		fmt.Fprintln(os.Stderr, "stun-gather -server stun.l.google.com:19302")
+		fmt.Fprintln(os.Stderr, "stun-gather -turn turn.example.org:3478 -turn-user user -turn-password secret")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	ips, err := localIPs()
+	if err != nil {
+		log.Fatalln("interfaces:", err)
+	}
+	var (
+		wg          sync.WaitGroup
+		mux         sync.Mutex
+		candidates  []candidate
+		allocations []*relay
+	)
+	add := func(c candidate) {
+		mux.Lock()
+		candidates = append(candidates, c)
+		mux.Unlock()
+	}
+	for i, ip := range ips {
+		conn, listenErr := net.ListenUDP("udp", &net.UDPAddr{IP: ip})
+		if listenErr != nil {
+			log.Println("listen:", listenErr)
+			continue
+		}
+		// Local preference is decreased for each next address,
+		// so candidates have unique priorities.
+		localPreference := stun.MaxLocalPreference - uint16(i)
+		host := conn.LocalAddr().(*net.UDPAddr)
+		add(candidate{
+			Type:     "host",
+			Addr:     host,
+			Priority: stun.NewPriority(stun.TypePreferenceHost, localPreference, 1),
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			srflx, gatherErr := gatherReflexive(conn, localPreference)
+			if gatherErr != nil {
+				log.Printf("srflx for %s: %v", host, gatherErr)
+				return
+			}
+			add(srflx)
+		}()
+		if *turnServer == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(ip net.IP) {
+			defer wg.Done()
+			r, relayed, gatherErr := gatherRelayed(ip, localPreference)
+			if gatherErr != nil {
+				log.Printf("relay for %s: %v", ip, gatherErr)
+				return
+			}
+			mux.Lock()
+			allocations = append(allocations, r)
+			mux.Unlock()
+			add(relayed)
+		}(ip)
+	}
+	wg.Wait()
+	for _, c := range candidates {
+		fmt.Println(c)
+	}
+	for _, r := range allocations {
+		if closeErr := r.Close(); closeErr != nil {
+			log.Println("close allocation:", closeErr)
+		}
+	}
+}
+
+// relay is TURN allocation of relayed candidate with its client.
+type relay struct {
+	*turn.Allocation
+	c *stun.Client
+}
+
+// Close deletes allocation and closes client.
+func (r *relay) Close() error {
+	if err := r.Allocation.Close(); err != nil {
+		_ = r.c.Close()
+		return err
+	}
+	return r.c.Close()
+}
+
+// gatherRelayed allocates relayed candidate on TURN server from new
+// socket on ip of host candidate, which is base of relayed candidate.
+// The allocation should be closed after use.
+func gatherRelayed(ip net.IP, localPreference uint16) (*relay, candidate, error) {
+	network := "udp4"
+	if ip.To4() == nil {
+		network = "udp6"
+	}
+	srvAddr, err := net.ResolveUDPAddr(network, *turnServer)
+	if err != nil {
+		return nil, candidate{}, err
+	}
+	conn, err := net.DialUDP(network, &net.UDPAddr{IP: ip}, srvAddr)
+	if err != nil {
+		return nil, candidate{}, err
+	}
+	c, err := stun.NewClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, candidate{}, err
+	}
+	var options []turn.AllocationOption
+	if *turnUser != "" {
+		options = append(options, turn.WithCredentials(&stun.LongTermCredentials{
+			Username: *turnUser,
+			Password: *turnPassword,
+		}))
+	}
+	if network == "udp6" {
+		options = append(options, turn.WithAddressFamily(turn.AddressFamilyIPv6))
+	}
+	a, err := turn.Allocate(c, options...)
+	if err != nil {
+		_ = c.Close()
+		return nil, candidate{}, err
+	}
+	relayed, mapped := a.Relayed(), a.Mapped()
+	return &relay{Allocation: a, c: c}, candidate{
+		Type:     "relay",
+		Addr:     &net.UDPAddr{IP: relayed.IP, Port: relayed.Port},
+		Related:  &net.UDPAddr{IP: mapped.IP, Port: mapped.Port},
+		Priority: stun.NewPriority(stun.TypePreferenceRelayed, localPreference, 1),
+	}, nil
+}
+
+// gatherReflexive discovers server-reflexive candidate for host candidate
+// conn. The conn is closed by underlying client.
+func gatherReflexive(conn *net.UDPConn, localPreference uint16) (candidate, error) {
+	network := "udp4"
+	if conn.LocalAddr().(*net.UDPAddr).IP.To4() == nil {
+		network = "udp6"
+	}
+	srvAddr, err := net.ResolveUDPAddr(network, *server)
+	if err != nil {
+		return candidate{}, err
+	}
+	c, err := stun.NewClient(&packetConn{UDPConn: conn, remote: srvAddr})
+	if err != nil {
+		return candidate{}, err
+	}
+	defer c.Close()
+	var (
+		res      candidate
+		eventErr error
+	)
+	if err = c.Do(stun.MustBuild(stun.TransactionID, stun.BindingRequest), func(e stun.Event) {
+		if e.Error != nil {
+			eventErr = e.Error
+			return
+		}
+		var addr stun.XORMappedAddress
+		if eventErr = addr.GetFrom(e.Message); eventErr != nil {
+			return
+		}
+		res = candidate{
+			Type:     "srflx",
+			Addr:     &net.UDPAddr{IP: addr.IP, Port: addr.Port},
+			Related:  conn.LocalAddr().(*net.UDPAddr),
+			Priority: stun.NewPriority(stun.TypePreferenceServerReflexive, localPreference, 1),
+		}
+	}); err != nil {
+		return candidate{}, err
+	}
+	return res, eventErr
+}
+
+// packetConn adapts unconnected UDP socket of host candidate to
+// stun.Connection, reading and writing only to remote.
+type packetConn struct {
+	*net.UDPConn
+	remote *net.UDPAddr
+}
+
+func (c *packetConn) Read(b []byte) (int, error) {
+	for {
+		n, addr, err := c.ReadFromUDP(b)
+		if err != nil {
+			return n, err
+		}
+		if addr.IP.Equal(c.remote.IP) && addr.Port == c.remote.Port {
+			return n, nil
+		}
+	}
+}
+
+func (c *packetConn) Write(b []byte) (int, error) {
+	return c.WriteToUDP(b, c.remote)
+}
+
+// localIPs returns addresses of up interfaces, excluding loopback and
+// link-local ones.
+func localIPs() ([]net.IP, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, addrsErr := iface.Addrs()
+		if addrsErr != nil {
+			return nil, addrsErr
+		}
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			if ipNet.IP.To4() == nil && !*ipv6 {
+				continue
+			}
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return ips, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+var (
+	server    = flag.String("server", "stun.l.google.com:19302", "Stun server address")
+	reflector = flag.String("reflector", "", "Reflector address (enables probe)")
+	reflect   = flag.String("reflect", "", "Run in reflector mode on address")
+	port      = flag.Int("port", 0, "Local port to check")
+	timeout   = flag.Duration("timeout", time.Millisecond*500, "Timeout of single attempt")
+	attempts  = flag.Int("attempts", 3, "Maximum attempts count")
+)
+
+const udp = "udp4"
+
+var errTimeout = errors.New("timed out")
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", "stun-portcheck")
+		fmt.Fprintln(os.Stderr, "stun-portcheck -reflect :3479")
+		fmt.Fprintln(os.Stderr, "stun-portcheck -port 27015 -reflector 198.51.100.1:3479")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *reflect != "" {
+		if err := runReflector(*reflect); err != nil {
+			log.Fatalln("reflector:", err)
+		}
+		return
+	}
+	conn, err := net.ListenUDP(udp, &net.UDPAddr{Port: *port})
+	if err != nil {
+		log.Fatalln("listen:", err)
+	}
+	defer conn.Close()
+	log.Println("local addr:", conn.LocalAddr())
+
+	srvAddr, err := net.ResolveUDPAddr(udp, *server)
+	if err != nil {
+		log.Fatalln("resolve server:", err)
+	}
+	res, _, err := roundTrip(conn, srvAddr, func(from *net.UDPAddr) bool {
+		return from.String() == srvAddr.String()
+	})
+	if err != nil {
+		log.Fatalln("discovery:", err)
+	}
+	var public stun.XORMappedAddress
+	if err = public.GetFrom(res); err != nil {
+		log.Fatalln("discovery:", err)
+	}
+	fmt.Println("public addr:", public)
+	if *reflector == "" {
+		return
+	}
+
+	refAddr, err := net.ResolveUDPAddr(udp, *reflector)
+	if err != nil {
+		log.Fatalln("resolve reflector:", err)
+	}
+	// Reflector answers from another port, so response is unsolicited
+	// for NAT or firewall and reaches us only if port is open.
+	res, from, err := roundTrip(conn, refAddr, func(from *net.UDPAddr) bool {
+		return from.IP.Equal(refAddr.IP) && from.Port != refAddr.Port
+	})
+	if err == errTimeout {
+		fmt.Println("port", public.Port, "is filtered")
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalln("probe:", err)
+	}
+	var reflected stun.XORMappedAddress
+	if err = reflected.GetFrom(res); err != nil {
+		log.Fatalln("probe:", err)
+	}
+	if reflected.String() != public.String() {
+		log.Printf("reflector sees %s instead of %s", reflected, public)
+	}
+	fmt.Println("port", public.Port, "is open, probe received from", from)
+}
+
+// roundTrip sends binding request to addr and waits for response with
+// same transaction id from address that satisfies accept.
+func roundTrip(conn *net.UDPConn, addr *net.UDPAddr, accept func(from *net.UDPAddr) bool) (*stun.Message, *net.UDPAddr, error) {
+	req, err := stun.Build(stun.TransactionID, stun.BindingRequest, stun.Fingerprint)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		buf = make([]byte, 1024)
+		res = new(stun.Message)
+	)
+	for i := 0; i < *attempts; i++ {
+		if _, err = conn.WriteToUDP(req.Raw, addr); err != nil {
+			return nil, nil, err
+		}
+		deadline := time.Now().Add(*timeout)
+		if err = conn.SetReadDeadline(deadline); err != nil {
+			return nil, nil, err
+		}
+		for {
+			n, from, readErr := conn.ReadFromUDP(buf)
+			if readErr != nil {
+				if netErr, ok := readErr.(net.Error); ok && netErr.Timeout() {
+					break
+				}
+				return nil, nil, readErr
+			}
+			if !accept(from) || !stun.IsMessage(buf[:n]) {
+				continue
+			}
+			res.Raw = append(res.Raw[:0], buf[:n]...)
+			if decErr := res.Decode(); decErr != nil {
+				continue
+			}
+			if res.TransactionID != req.TransactionID {
+				continue
+			}
+			return res, from, nil
+		}
+	}
+	return nil, nil, errTimeout
+}
+
+// runReflector listens for binding requests on addr and responds to them
+// from another port.
+func runReflector(addr string) error {
+	laddr, err := net.ResolveUDPAddr(udp, addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP(udp, laddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	out, err := net.ListenUDP(udp, &net.UDPAddr{IP: laddr.IP})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	log.Println("reflecting from", conn.LocalAddr(), "to", out.LocalAddr())
+	var (
+		buf = make([]byte, 1024)
+		req = new(stun.Message)
+		res = new(stun.Message)
+	)
+	for {
+		n, from, readErr := conn.ReadFromUDP(buf)
+		if readErr != nil {
+			return readErr
+		}
+		if !stun.IsMessage(buf[:n]) {
+			continue
+		}
+		req.Raw = append(req.Raw[:0], buf[:n]...)
+		if decErr := req.Decode(); decErr != nil || req.Type != stun.BindingRequest {
+			continue
+		}
+		if buildErr := res.Build(req, stun.BindingSuccess,
+			&stun.XORMappedAddress{IP: from.IP, Port: from.Port},
+			stun.NewSoftware("stun-portcheck"),
+			stun.Fingerprint,
+		); buildErr != nil {
+			log.Println("build:", buildErr)
+			continue
+		}
+		if _, writeErr := out.WriteToUDP(res.Raw, from); writeErr != nil {
+			log.Println("write:", writeErr)
+			continue
+		}
+		log.Println("reflected probe to", from)
+	}
+}
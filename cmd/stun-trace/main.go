@@ -0,0 +1,89 @@
+// Command stun-trace renders STUN session traces recorded by
+// stuntest.Recorder, e.g. by "stun-client -trace".
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pion/stun"
+	"github.com/pion/stun/stuntest"
+)
+
+var (
+	direction = flag.String("dir", "", "show only messages with direction (in or out)")
+	method    = flag.String("method", "", "show only messages with method, e.g. binding")
+	class     = flag.String("class", "", "show only messages with class, e.g. \"error response\"")
+	id        = flag.String("id", "", "show only messages with base64-encoded transaction id")
+)
+
+func match(r stuntest.Record, m *stun.Message) bool {
+	if *direction != "" && *direction != r.Direction.String() {
+		return false
+	}
+	if m == nil {
+		// Not a STUN message, so only direction can be matched.
+		return *method == "" && *class == "" && *id == ""
+	}
+	if *method != "" && !strings.EqualFold(*method, m.Type.Method.String()) {
+		return false
+	}
+	if *class != "" && !strings.EqualFold(*class, m.Type.Class.String()) {
+		return false
+	}
+	if *id != "" && *id != base64.StdEncoding.EncodeToString(m.TransactionID[:]) {
+		return false
+	}
+	return true
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, os.Args[0], "[flags] session.trace")
+		fmt.Fprintln(os.Stderr, "Reads trace from stdin if file is not set or is \"-\"")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	var input io.Reader = os.Stdin
+	if name := flag.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatalln("open:", err)
+		}
+		defer f.Close()
+		input = f
+	}
+	var (
+		r      stuntest.Record
+		m      = stun.New()
+		reader = bufio.NewReader(input)
+		out    = bufio.NewWriter(os.Stdout)
+	)
+	defer out.Flush()
+	for {
+		if err := stuntest.ReadRecord(reader, &r); err != nil {
+			if err == io.EOF {
+				return
+			}
+			out.Flush()
+			log.Fatalln("read:", err)
+		}
+		decoded := m
+		if err := stun.Decode(r.Raw, m); err != nil {
+			decoded = nil
+		}
+		if !match(r, decoded) {
+			continue
+		}
+		if err := stuntest.Dump(out, r, decoded); err != nil {
+			log.Fatalln("write:", err)
+		}
+	}
+}
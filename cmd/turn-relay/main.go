@@ -0,0 +1,222 @@
+// Command turn-relay is an example of exchanging data between two
+// clients via TURN server, using files in shared directory as
+// rendezvous for relayed addresses.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pion/stun"
+	"github.com/pion/stun/turn"
+)
+
+var (
+	server   = flag.String("server", "localhost:3478", "TURN server address")
+	username = flag.String("user", "user", "username")
+	password = flag.String("password", "secret", "password")
+	mode     = flag.String("mode", "both", "peer role: offer, answer or both")
+	dir      = flag.String("dir", os.TempDir(), "rendezvous directory")
+	timeout  = flag.Duration("timeout", time.Second*30, "timeout of exchange")
+)
+
+// demuxConn passes STUN messages to stun.Client and payload of
+// ChannelData messages to data.
+type demuxConn struct {
+	net.Conn
+	data chan<- []byte
+}
+
+func (c demuxConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(b)
+		if err != nil || !turn.IsChannelData(b[:n]) {
+			return n, err
+		}
+		d := &turn.ChannelData{Raw: b[:n]}
+		if d.Decode() != nil {
+			continue
+		}
+		select {
+		case c.data <- append([]byte(nil), d.Data...):
+		default:
+			// Dropping data if nobody reads it.
+		}
+	}
+}
+
+func rendezvous(role, kind string) string {
+	return filepath.Join(*dir, "turn-relay-"+role+"."+kind)
+}
+
+// publish atomically writes value to rendezvous file.
+func publish(role, kind, value string) error {
+	name := rendezvous(role, kind)
+	if err := ioutil.WriteFile(name+".tmp", []byte(value), 0600); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+var errTimeout = errors.New("timed out")
+
+// waitFile waits until rendezvous file exists and returns its content.
+func waitFile(name string, deadline time.Time) (string, error) {
+	for !exists(name) {
+		if time.Now().After(deadline) {
+			return "", errTimeout
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+	v, err := ioutil.ReadFile(name)
+	return string(v), err
+}
+
+func run(role, other string) error {
+	deadline := time.Now().Add(*timeout)
+	// Files of role are removed by peer when it is done with them.
+	for _, kind := range []string{"addr", "done"} {
+		_ = os.Remove(rendezvous(role, kind))
+	}
+	conn, err := net.Dial("udp", *server)
+	if err != nil {
+		return err
+	}
+	data := make(chan []byte, 16)
+	client, err := stun.NewClient(demuxConn{Conn: conn, data: data}, stun.WithHandler(func(e stun.Event) {
+		// Data indications are received before channel is bound.
+		if e.Error != nil || e.Message.Type != turn.DataIndication {
+			return
+		}
+		var payload turn.Data
+		if payload.GetFrom(e.Message) != nil {
+			return
+		}
+		select {
+		case data <- append([]byte(nil), payload...):
+		default:
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// 1. Allocating relayed address and publishing it to peer.
+	a, err := turn.Allocate(client, turn.WithCredentials(&stun.LongTermCredentials{
+		Username: *username,
+		Password: *password,
+	}))
+	if err != nil {
+		return fmt.Errorf("allocate: %v", err)
+	}
+	log.Println(role, "relayed address:", a.Relayed())
+	if err = publish(role, "addr", a.Relayed().String()); err != nil {
+		return err
+	}
+	peerAddr, err := waitFile(rendezvous(other, "addr"), deadline)
+	if err != nil {
+		return fmt.Errorf("waiting for %s: %v", other, err)
+	}
+	resolved, err := net.ResolveUDPAddr("udp", peerAddr)
+	if err != nil {
+		return err
+	}
+	peer := turn.XORPeerAddress{IP: resolved.IP, Port: resolved.Port}
+	log.Println(role, "peer address:", peer)
+
+	// 2. Installing permission and binding channel to peer.
+	if err = a.CreatePermission(peer.IP); err != nil {
+		return fmt.Errorf("permission: %v", err)
+	}
+	number, err := a.BindChannel(peer)
+	if err != nil {
+		return fmt.Errorf("channel bind: %v", err)
+	}
+	log.Println(role, "bound channel", number)
+
+	// 3. Sending greeting until peer confirms that it is received.
+	msg := &turn.ChannelData{Number: number, Data: []byte("hello from " + role)}
+	msg.Encode()
+	ticker := time.NewTicker(time.Millisecond * 200)
+	defer ticker.Stop()
+	received := false
+	for !received || !exists(rendezvous(other, "done")) {
+		if time.Now().After(deadline) {
+			return errTimeout
+		}
+		select {
+		case <-ticker.C:
+			if _, err = conn.Write(msg.Raw); err != nil {
+				return err
+			}
+		case payload := <-data:
+			if received {
+				continue
+			}
+			received = true
+			log.Printf("%s received: %q", role, payload)
+			if err = publish(role, "done", ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, kind := range []string{"addr", "done"} {
+		_ = os.Remove(rendezvous(other, kind))
+	}
+
+	// 4. Deleting allocation.
+	if err = a.Close(); err != nil {
+		return fmt.Errorf("close: %v", err)
+	}
+	log.Println(role, "done")
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	roles := map[string]string{"offer": "answer", "answer": "offer"}
+	if *mode != "both" {
+		other, ok := roles[*mode]
+		if !ok {
+			log.Fatalln("unknown mode", *mode)
+		}
+		if err := run(*mode, other); err != nil {
+			log.Fatalln(*mode, err)
+		}
+		return
+	}
+	errs := make(chan error, len(roles))
+	for role, other := range roles {
+		go func(role, other string) {
+			if err := run(role, other); err != nil {
+				errs <- fmt.Errorf("%s: %v", role, err)
+				return
+			}
+			errs <- nil
+		}(role, other)
+	}
+	failed := false
+	for range roles {
+		if err := <-errs; err != nil {
+			log.Println(err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
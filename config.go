@@ -0,0 +1,139 @@
+package stun
+
+import (
+	"fmt"
+	"time"
+)
+
+// OptionErr means that value of option is invalid, and is returned by
+// constructors that validate options.
+type OptionErr struct {
+	Option string
+	Value  interface{}
+}
+
+func (e OptionErr) Error() string {
+	return fmt.Sprintf("invalid value %v of %s option", e.Value, e.Option)
+}
+
+// ClientConfig is serializable alternative to client options, e.g. for
+// configuration files. Zero values mean defaults.
+type ClientConfig struct {
+	RTO          time.Duration `json:"rto,omitempty"`
+	TimeoutRate  time.Duration `json:"timeout_rate,omitempty"`
+	Rc           int           `json:"rc,omitempty"`
+	Rm           int           `json:"rm,omitempty"`
+	NoRetransmit bool          `json:"no_retransmit,omitempty"`
+	Stream       bool          `json:"stream,omitempty"`
+	Pacing       time.Duration `json:"pacing,omitempty"`
+	NoConnClose  bool          `json:"no_conn_close,omitempty"`
+	Spec         Spec          `json:"spec,omitempty"`
+
+	// KeepaliveInterval enables keepalives if set, see WithKeepalive.
+	KeepaliveInterval time.Duration `json:"keepalive_interval,omitempty"`
+}
+
+// Options returns client options that are equivalent to c, for
+// NewClient(conn, c.Options()...).
+func (c ClientConfig) Options() []ClientOption {
+	var options []ClientOption
+	if c.RTO != 0 {
+		options = append(options, WithRTO(c.RTO))
+	}
+	if c.TimeoutRate != 0 {
+		options = append(options, WithTimeoutRate(c.TimeoutRate))
+	}
+	if c.Rc != 0 {
+		options = append(options, WithRc(c.Rc))
+	}
+	if c.Rm != 0 {
+		options = append(options, WithRm(c.Rm))
+	}
+	if c.NoRetransmit {
+		options = append(options, WithNoRetransmit)
+	}
+	if c.Stream {
+		options = append(options, WithStream)
+	}
+	if c.Pacing != 0 {
+		options = append(options, WithPacing(c.Pacing))
+	}
+	if c.NoConnClose {
+		options = append(options, WithNoConnClose)
+	}
+	if c.Spec != SpecRFC8489 {
+		options = append(options, WithSpec(c.Spec))
+	}
+	if c.KeepaliveInterval != 0 {
+		options = append(options, WithKeepalive(Keepalive{Interval: c.KeepaliveInterval}))
+	}
+	return options
+}
+
+// ServerConfig is serializable alternative to server options. Handlers
+// are not serializable and should be passed as options.
+type ServerConfig struct {
+	Software      string        `json:"software,omitempty"`
+	Fingerprint   bool          `json:"fingerprint,omitempty"`
+	NonceLifetime time.Duration `json:"nonce_lifetime,omitempty"`
+	Spec          Spec          `json:"spec,omitempty"`
+
+	RequireFingerprint bool `json:"require_fingerprint,omitempty"`
+
+	RateLimit        float64 `json:"rate_limit,omitempty"`
+	RateBurst        int     `json:"rate_burst,omitempty"`
+	MaxAmplification int     `json:"max_amplification,omitempty"`
+	TransactionLimit int     `json:"transaction_limit,omitempty"`
+	AllocationLimit  int     `json:"allocation_limit,omitempty"`
+
+	// HealthCheckSoftware enables health checks with NewHealthProbe.
+	HealthCheckSoftware string `json:"health_check_software,omitempty"`
+
+	// AllowedMethods restricts served methods, see WithAllowedMethods.
+	AllowedMethods []Method `json:"allowed_methods,omitempty"`
+
+	MemoryLimit int64 `json:"memory_limit,omitempty"`
+}
+
+// Options returns server options that are equivalent to c, for
+// NewServer(c.Options()...).
+func (c ServerConfig) Options() []ServerOption {
+	var options []ServerOption
+	if c.Software != "" {
+		options = append(options, WithSoftware(c.Software))
+	}
+	if c.Fingerprint {
+		options = append(options, WithFingerprint)
+	}
+	if c.NonceLifetime != 0 {
+		options = append(options, WithNonceLifetime(c.NonceLifetime))
+	}
+	if c.Spec != SpecRFC8489 {
+		options = append(options, WithServerSpec(c.Spec))
+	}
+	if c.RequireFingerprint {
+		options = append(options, WithRequireFingerprint)
+	}
+	if c.RateLimit != 0 || c.RateBurst != 0 {
+		options = append(options, WithRateLimit(c.RateLimit, c.RateBurst))
+	}
+	if c.MaxAmplification != 0 {
+		options = append(options, WithMaxAmplification(c.MaxAmplification))
+	}
+	if c.TransactionLimit != 0 {
+		options = append(options, WithTransactionLimit(c.TransactionLimit))
+	}
+	if c.AllocationLimit != 0 {
+		options = append(options, WithAllocationLimit(c.AllocationLimit))
+	}
+	if c.HealthCheckSoftware != "" {
+		options = append(options, WithHealthCheck(NewHealthProbe(c.HealthCheckSoftware)))
+	}
+	if len(c.AllowedMethods) > 0 {
+		options = append(options, WithAllowedMethods(c.AllowedMethods...))
+	}
+	if c.MemoryLimit != 0 {
+		options = append(options, WithMemoryLimit(c.MemoryLimit))
+	}
+	return options
+}
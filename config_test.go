@@ -0,0 +1,110 @@
+package stun
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClientConfig_Options(t *testing.T) {
+	var config ClientConfig
+	if err := json.Unmarshal([]byte(`{"rto":1000000,"rc":3,"rm":4,"no_conn_close":true,"spec":"RFC5389"}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.RTO != time.Millisecond || config.Rc != 3 || config.Rm != 4 || !config.NoConnClose || config.Spec != SpecRFC5389 {
+		t.Fatalf("unexpected config %+v", config)
+	}
+	if len(config.Options()) != 5 {
+		t.Errorf("unexpected options count %d", len(config.Options()))
+	}
+	if len((ClientConfig{}).Options()) != 0 {
+		t.Error("zero config should have no options")
+	}
+	c := &Client{}
+	for _, o := range config.Options() {
+		o(c)
+	}
+	if c.rto != int64(time.Millisecond) || c.maxAttempts != 2 || c.rm != 4 || c.closeConn || c.spec != SpecRFC5389 {
+		t.Errorf("options are not applied: %+v", c)
+	}
+}
+
+func TestNewClient_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []ClientOption
+		option  string
+	}{
+		{"RTO", []ClientOption{WithRTO(-time.Second)}, "RTO"},
+		{"TimeoutRate", []ClientOption{WithTimeoutRate(0)}, "TimeoutRate"},
+		{"Pacing", []ClientOption{WithPacing(-time.Second)}, "Pacing"},
+		{"Spec", []ClientOption{WithSpec(Spec(10))}, "Spec"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, _ := net.Pipe()
+			defer conn.Close()
+			_, err := NewClient(conn, tc.options...)
+			if optionErr, ok := err.(OptionErr); !ok || optionErr.Option != tc.option {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestServerConfig_Options(t *testing.T) {
+	config := ServerConfig{
+		Software:         "test",
+		Fingerprint:      true,
+		NonceLifetime:    time.Minute,
+		RateLimit:        10,
+		RateBurst:        5,
+		MaxAmplification: 3,
+		TransactionLimit: 4,
+		AllocationLimit:  2,
+
+		HealthCheckSoftware: "health",
+		AllowedMethods:      []Method{MethodBinding},
+		MemoryLimit:         1 << 20,
+	}
+	s, err := NewServer(config.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.software.String() != "test" || !s.fingerprint || s.nonceLifetime != time.Minute ||
+		s.limiter.rate != 10 || s.limiter.burst != 5 || s.maxAmplification != 3 ||
+		s.transactions.limit != 4 || s.allocations.limit != 2 || s.healthProbe == nil ||
+		!s.allowed(MethodBinding) || s.allowed(MethodAllocate) || s.stats.memory.limit != 1<<20 {
+		t.Errorf("options are not applied: %+v", s)
+	}
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err = NewServer(WithNonceLifetime(-time.Minute)); err == nil {
+			t.Error("should error")
+		}
+		if _, err = NewServer(WithAuth("realm", nil)); err == nil {
+			t.Error("should error")
+		}
+		if _, err = NewServer(WithRateLimit(10, 0)); err == nil {
+			t.Error("should error")
+		}
+		if _, err = NewServer(WithTransactionLimit(-1)); err == nil {
+			t.Error("should error")
+		}
+		if _, err = NewServer(WithMaxAmplification(-1)); err == nil {
+			t.Error("should error")
+		}
+		if _, err = NewServer(WithServerSpec(Spec(10))); err == nil {
+			t.Error("should error")
+		}
+		auth := func(username, realm string) ([]byte, bool) { return nil, false }
+		if _, err = NewServer(WithAuth("realm", auth), WithServerSpec(SpecRFC3489)); err == nil {
+			t.Error("should error")
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		err := OptionErr{Option: "RTO", Value: time.Second}
+		if err.Error() != "invalid value 1s of RTO option" {
+			t.Error(err)
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package stun
+
+import "sync"
+
+// LongTermCredentials implements client side of long-term credential
+// mechanism: the first request is sent without credentials, and on
+// 401 (Unauthorized) or 438 (Stale Nonce) challenge the request is
+// retried with USERNAME, REALM, NONCE and MESSAGE-INTEGRITY.
+//
+// If nonce cookie of challenge signals password algorithms feature, the
+// strongest supported algorithm from PASSWORD-ALGORITHMS is used for key
+// derivation and requests include both PASSWORD-ALGORITHM and
+// PASSWORD-ALGORITHMS, otherwise MD5 is used.
+//
+// Quirks of server detected by client are honored, see WithQuirks, and
+// features of later editions than Client.Spec are not used.
+//
+// Realm, nonce and key are cached, so next requests are authenticated
+// from the start. Use separate LongTermCredentials for each server.
+//
+// RFC 5389 Section 10.2, RFC 8489 Section 9.2
+type LongTermCredentials struct {
+	Username string
+	Password string
+
+	// Prepare is applied to username, realm and password before key
+	// derivation, e.g. OpaqueString as required by RFC 8489 or SASLprep
+	// for legacy servers. Nil Prepare means that credentials are
+	// already prepared.
+	Prepare func(s string) (string, error)
+
+	// Userhash enables sending USERHASH instead of USERNAME for user
+	// anonymity if server signals support of it in nonce cookie.
+	//
+	// RFC 8489 Section 9.2.4
+	Userhash bool
+
+	mux       sync.Mutex // guards fields below
+	realm     Realm
+	nonce     Nonce
+	integrity MessageIntegrity
+	userhash  Userhash
+	anonymity bool
+
+	algorithms PasswordAlgorithms
+	algorithm  PasswordAlgorithmAttribute
+}
+
+// Do builds request from setters and performs transaction on c like
+// Client.Do, handling challenges. Each attempt is new transaction with
+// new transaction id. Setters should not add credentials; FINGERPRINT
+// is kept as last attribute if set.
+//
+// Authenticated responses with invalid MESSAGE-INTEGRITY are passed
+// to f with ErrIntegrityMismatch error.
+func (l *LongTermCredentials) Do(c *Client, f func(Event), setters ...Setter) error {
+	var (
+		m     = new(Message)
+		stale = false
+	)
+	username, err := l.prepare(l.Username)
+	if err != nil {
+		return err
+	}
+	for {
+		integrity, err := l.build(m, c.ids, username, setters, c.Quirks()|c.Spec().quirks())
+		if err != nil {
+			return err
+		}
+		retry := false
+		if err = c.Do(m, func(e Event) {
+			if l.challenge(e, integrity != nil, &stale, c.Quirks()|c.Spec().quirks()) {
+				retry = true
+				return
+			}
+			if e.Error == nil && integrity != nil && e.Message.Contains(AttrMessageIntegrity) {
+				if checkErr := integrity.Check(e.Message); checkErr != nil {
+					e.Error = checkErr
+				}
+			}
+			f(e)
+		}); err != nil || !retry {
+			return err
+		}
+	}
+}
+
+// build builds request to m with transaction id from id and cached
+// credentials, returning integrity that was used or nil.
+func (l *LongTermCredentials) build(m *Message, id Setter, username string, setters []Setter, quirks Quirks) (MessageIntegrity, error) {
+	if err := m.Build(id); err != nil {
+		return nil, err
+	}
+	for _, s := range setters {
+		if err := s.AddTo(m); err != nil {
+			return nil, err
+		}
+	}
+	return l.add(m, username, quirks)
+}
+
+// AddTo adds cached credentials to m, so m should be complete request
+// without MESSAGE-INTEGRITY; FINGERPRINT is kept as last attribute if
+// set. Nothing is added if credentials are not cached yet, i.e. no
+// challenge was handled by Do.
+//
+// Useful for requests that are sent outside of Client, e.g.
+// ConnectionBind of RFC 6062 on new connection.
+func (l *LongTermCredentials) AddTo(m *Message) error {
+	username, err := l.prepare(l.Username)
+	if err != nil {
+		return err
+	}
+	_, err = l.add(m, username, 0)
+	return err
+}
+
+// add adds cached credentials to m, returning integrity that was used
+// or nil.
+func (l *LongTermCredentials) add(m *Message, username string, quirks Quirks) (MessageIntegrity, error) {
+	l.mux.Lock()
+	realm, nonce, integrity, userhash := l.realm, l.nonce, l.integrity, l.userhash
+	algorithms, algorithm, anonymity := l.algorithms, l.algorithm, l.anonymity
+	l.mux.Unlock()
+	if integrity == nil {
+		return nil, nil
+	}
+	var user Setter = NewUsername(username)
+	if l.Userhash && anonymity && !quirks.Has(QuirkNoUserhash) {
+		user = userhash
+	}
+	fingerprint := m.Contains(AttrFingerprint)
+	if fingerprint {
+		m.Remove(AttrFingerprint)
+	}
+	if quirks.Has(QuirkNoFingerprint) {
+		fingerprint = false
+	}
+	credentials := []Setter{user, realm, nonce}
+	if algorithms != nil {
+		credentials = append(credentials, algorithm, algorithms)
+	}
+	for _, s := range append(credentials, integrity) {
+		if err := s.AddTo(m); err != nil {
+			return nil, err
+		}
+	}
+	if fingerprint {
+		if err := Fingerprint.AddTo(m); err != nil {
+			return nil, err
+		}
+	}
+	return integrity, nil
+}
+
+// challenge updates cached credentials and returns true if e is a
+// challenge and request should be retried. The 401 is retried only for
+// unauthenticated request and 438 is retried once.
+func (l *LongTermCredentials) challenge(e Event, authenticated bool, stale *bool, quirks Quirks) bool {
+	if e.Error != nil || e.Message.Type.Class != ClassErrorResponse {
+		return false
+	}
+	var code ErrorCodeAttribute
+	if err := code.GetFrom(e.Message); err != nil {
+		return false
+	}
+	switch {
+	case code.Code == CodeUnauthorized && !authenticated:
+	case code.Code == CodeStaleNonce && !*stale:
+		*stale = true
+	default:
+		return false
+	}
+	var (
+		realm      Realm
+		nonce      Nonce
+		algorithms PasswordAlgorithms
+		algorithm  = PasswordAlgorithmAttribute{Algorithm: PasswordAlgorithmMD5}
+	)
+	if err := realm.GetFrom(e.Message); err != nil {
+		return false
+	}
+	if err := nonce.GetFrom(e.Message); err != nil {
+		return false
+	}
+	features, _ := nonce.Features()
+	if features.Has(FeaturePasswordAlgorithms) {
+		// Missing PASSWORD-ALGORITHMS means bid-down attack.
+		if err := algorithms.GetFrom(e.Message); err != nil {
+			return false
+		}
+		supported := algorithms
+		if quirks.Has(QuirkNoSHA256) {
+			supported = algorithms.without(PasswordAlgorithmSHA256)
+		}
+		var ok bool
+		if algorithm, ok = supported.Strongest(); !ok {
+			return false
+		}
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.integrity == nil || string(l.realm) != string(realm) || !l.algorithm.Equal(algorithm) {
+		integrity, userhash, err := l.newIntegrity(realm.String(), algorithm.Algorithm)
+		if err != nil {
+			return false
+		}
+		l.realm = append(Realm(nil), realm...)
+		l.integrity = integrity
+		l.userhash = userhash
+	}
+	l.nonce = append(Nonce(nil), nonce...)
+	l.anonymity = features.Has(FeatureUsernameAnonymity)
+	l.algorithms = nil
+	for _, a := range algorithms {
+		l.algorithms = append(l.algorithms, PasswordAlgorithmAttribute{
+			Algorithm:  a.Algorithm,
+			Parameters: append([]byte(nil), a.Parameters...),
+		})
+	}
+	l.algorithm = PasswordAlgorithmAttribute{
+		Algorithm:  algorithm.Algorithm,
+		Parameters: append([]byte(nil), algorithm.Parameters...),
+	}
+	return true
+}
+
+func (l *LongTermCredentials) prepare(s string) (string, error) {
+	if l.Prepare == nil {
+		return s, nil
+	}
+	return l.Prepare(s)
+}
+
+func (l *LongTermCredentials) newIntegrity(realm string, algorithm PasswordAlgorithm) (MessageIntegrity, Userhash, error) {
+	credentials := [3]string{l.Username, realm, l.Password}
+	for i := range credentials {
+		prepared, err := l.prepare(credentials[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		credentials[i] = prepared
+	}
+	integrity, err := algorithm.LongTermIntegrity(credentials[0], credentials[1], credentials[2])
+	if err != nil {
+		return nil, nil, err
+	}
+	return integrity, NewUserhash(credentials[0], credentials[1]), nil
+}
@@ -0,0 +1,206 @@
+package stun
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestLongTermCredentials_Do(t *testing.T) {
+	const (
+		username = "user"
+		realm    = "realm"
+		password = "secret"
+	)
+	var (
+		requests   int32
+		userhashes int32
+		nonce      atomic.Value
+	)
+	nonce.Store(string(NewSecureNonce(FeatureUsernameAnonymity, "nonce-1")))
+	integrity := NewLongTermIntegrity(username, realm, password)
+	addr, closeServer := serveUDP(t, func(req *Message) *Message {
+		atomic.AddInt32(&requests, 1)
+		errorResponse := NewType(req.Type.Method, ClassErrorResponse)
+		current := NewNonce(nonce.Load().(string))
+		var userhash Userhash
+		if userhash.GetFrom(req) == nil {
+			atomic.AddInt32(&userhashes, 1)
+			if userhash.String() != NewUserhash(username, realm).String() {
+				return MustBuild(req, errorResponse, CodeUnauthorized, NewRealm(realm), current)
+			}
+		}
+		if !req.Contains(AttrMessageIntegrity) {
+			return MustBuild(req, errorResponse, CodeUnauthorized, NewRealm(realm), current)
+		}
+		var got Nonce
+		if err := got.GetFrom(req); err != nil || got.String() != current.String() {
+			return MustBuild(req, errorResponse, CodeStaleNonce, NewRealm(realm), current)
+		}
+		if err := integrity.Check(req); err != nil {
+			return MustBuild(req, errorResponse, CodeUnauthorized, NewRealm(realm), current)
+		}
+		if err := Fingerprint.Check(req); err != nil {
+			t.Error("fingerprint should be kept:", err)
+		}
+		return MustBuild(req, BindingSuccess, integrity, Fingerprint)
+	})
+	defer closeServer()
+	c, err := Dial("udp4", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	do := func(t *testing.T, l *LongTermCredentials, expectedRequests int32) Event {
+		atomic.StoreInt32(&requests, 0)
+		var got Event
+		if err := l.Do(c, func(e Event) {
+			got = e
+			got.Message = new(Message)
+			if e.Message != nil {
+				_ = e.Message.CloneTo(got.Message)
+			}
+		}, BindingRequest, Fingerprint); err != nil {
+			t.Fatal(err)
+		}
+		if n := atomic.LoadInt32(&requests); n != expectedRequests {
+			t.Errorf("%d requests, expected %d", n, expectedRequests)
+		}
+		return got
+	}
+	l := &LongTermCredentials{Username: username, Password: password}
+	t.Run("Challenge", func(t *testing.T) {
+		if e := do(t, l, 2); e.Error != nil || e.Message.Type != BindingSuccess {
+			t.Error("unexpected event", e.Error, e.Message)
+		}
+	})
+	t.Run("Cached", func(t *testing.T) {
+		if e := do(t, l, 1); e.Error != nil || e.Message.Type != BindingSuccess {
+			t.Error("unexpected event", e.Error, e.Message)
+		}
+	})
+	t.Run("StaleNonce", func(t *testing.T) {
+		nonce.Store(string(NewSecureNonce(FeatureUsernameAnonymity, "nonce-2")))
+		if e := do(t, l, 2); e.Error != nil || e.Message.Type != BindingSuccess {
+			t.Error("unexpected event", e.Error, e.Message)
+		}
+	})
+	t.Run("AddTo", func(t *testing.T) {
+		m := MustBuild(TransactionID, BindingRequest, Fingerprint)
+		if err := l.AddTo(m); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Check(integrity, Fingerprint); err != nil {
+			t.Error(err)
+		}
+		empty := MustBuild(TransactionID, BindingRequest)
+		if err := new(LongTermCredentials).AddTo(empty); err != nil || len(empty.Attributes) != 0 {
+			t.Error("nothing should be added without challenge", err)
+		}
+	})
+	t.Run("Prepare", func(t *testing.T) {
+		prepared := &LongTermCredentials{
+			Username: username,
+			Password: "secret\u00ad",
+			Prepare:  SASLprep,
+		}
+		if e := do(t, prepared, 2); e.Error != nil || e.Message.Type != BindingSuccess {
+			t.Error("unexpected event", e.Error, e.Message)
+		}
+	})
+	t.Run("Userhash", func(t *testing.T) {
+		anonymous := &LongTermCredentials{
+			Username: username,
+			Password: password,
+			Userhash: true,
+		}
+		atomic.StoreInt32(&userhashes, 0)
+		if e := do(t, anonymous, 2); e.Error != nil || e.Message.Type != BindingSuccess {
+			t.Error("unexpected event", e.Error, e.Message)
+		}
+		if atomic.LoadInt32(&userhashes) != 1 {
+			t.Error("USERHASH should be used")
+		}
+	})
+	t.Run("UserhashNotSignaled", func(t *testing.T) {
+		nonce.Store("nonce-3")
+		anonymous := &LongTermCredentials{
+			Username: username,
+			Password: password,
+			Userhash: true,
+		}
+		atomic.StoreInt32(&userhashes, 0)
+		if e := do(t, anonymous, 2); e.Error != nil || e.Message.Type != BindingSuccess {
+			t.Error("unexpected event", e.Error, e.Message)
+		}
+		if atomic.LoadInt32(&userhashes) != 0 {
+			t.Error("USERHASH should not be used")
+		}
+	})
+	t.Run("WrongPassword", func(t *testing.T) {
+		wrong := &LongTermCredentials{Username: username, Password: "wrong"}
+		e := do(t, wrong, 2)
+		var code ErrorCodeAttribute
+		if err := code.GetFrom(e.Message); err != nil || code.Code != CodeUnauthorized {
+			t.Error("should pass 401 to callback")
+		}
+	})
+}
+
+func TestLongTermCredentials_PasswordAlgorithms(t *testing.T) {
+	const (
+		username = "user"
+		realm    = "realm"
+		password = "secret"
+	)
+	offered := PasswordAlgorithms{
+		{Algorithm: PasswordAlgorithmMD5},
+		{Algorithm: PasswordAlgorithmSHA256},
+	}
+	integrity, err := PasswordAlgorithmSHA256.LongTermIntegrity(username, realm, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, closeServer := serveUDP(t, func(req *Message) *Message {
+		errorResponse := NewType(req.Type.Method, ClassErrorResponse)
+		challenge := MustBuild(req, errorResponse, CodeUnauthorized,
+			NewRealm(realm), NewSecureNonce(FeaturePasswordAlgorithms, "nonce"), offered,
+		)
+		var (
+			algorithm  PasswordAlgorithmAttribute
+			algorithms PasswordAlgorithms
+		)
+		if err := algorithm.GetFrom(req); err != nil {
+			return challenge
+		}
+		if algorithm.Algorithm != PasswordAlgorithmSHA256 {
+			t.Error("unexpected algorithm", algorithm)
+		}
+		if err := algorithms.GetFrom(req); err != nil || algorithms.String() != offered.String() {
+			t.Error("PASSWORD-ALGORITHMS should be sent back", algorithms, err)
+		}
+		if err := integrity.Check(req); err != nil {
+			return challenge
+		}
+		return MustBuild(req, BindingSuccess, integrity)
+	})
+	defer closeServer()
+	c, err := Dial("udp4", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	l := &LongTermCredentials{Username: username, Password: password}
+	var got Event
+	if err := l.Do(c, func(e Event) {
+		got.Error = e.Error
+		got.Message = new(Message)
+		if e.Message != nil {
+			_ = e.Message.CloneTo(got.Message)
+		}
+	}, BindingRequest); err != nil {
+		t.Fatal(err)
+	}
+	if got.Error != nil || got.Message.Type != BindingSuccess {
+		t.Error("unexpected event", got.Error, got.Message)
+	}
+}
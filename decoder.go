@@ -0,0 +1,138 @@
+package stun
+
+import "errors"
+
+// Possible errors of Decoder limits.
+var (
+	ErrSizeLimit       = errors.New("message size limit exceeded")
+	ErrAttributesLimit = errors.New("attributes count limit exceeded")
+)
+
+// ErrInvalidMessageType means that message type is reserved or is not
+// defined combination of method and class.
+var ErrInvalidMessageType = errors.New("invalid message type")
+
+// Decoder decodes messages, calling hooks for decoded attributes.
+//
+// The zero value is valid and decodes like Decode function.
+type Decoder struct {
+	// Known reports whether attribute type is known to application.
+	// Defaults to types of attributes that are defined in this package.
+	Known func(t AttrType) bool
+
+	// Unknown is called for every attribute that is not known, in order
+	// of appearance. Comprehension class is available via a.Type.Required.
+	// Attribute value is valid only until m.Raw is modified.
+	Unknown func(a RawAttribute)
+
+	// MaxSize is maximum size of message, including header.
+	// Zero means no limit.
+	MaxSize int
+
+	// MaxAttributes is maximum count of attributes. Zero means no limit.
+	MaxAttributes int
+
+	// Warn enables soft mode if set: limit violations and malformed
+	// attributes are passed to Warn and decoding continues with the
+	// valid prefix of message, which is kept in m with adjusted length.
+	// Errors of header decoding are still returned.
+	Warn func(err error)
+
+	// CheckType enables rejecting of messages with type that is not
+	// valid, see MessageType.IsValid, with ErrInvalidMessageType. In soft
+	// mode the error is passed to Warn and message is kept.
+	CheckType bool
+
+	// Classic enables decoding of RFC 3489 messages that have no magic
+	// cookie. First 4 bytes of their 128-bit transaction id are not
+	// stored in m.TransactionID, but are kept in m.Raw.
+	Classic bool
+}
+
+func isKnownAttr(t AttrType) bool {
+	_, ok := attrNames[t]
+	return ok
+}
+
+// Decode decodes data to m like Decode function, calling d.Unknown for
+// each unknown attribute. Unknown attributes are not treated as error
+// and are kept in m.Attributes.
+func (d *Decoder) Decode(data []byte, m *Message) error {
+	if err := d.decode(data, m); err != nil {
+		return err
+	}
+	if d.Unknown == nil {
+		return nil
+	}
+	known := d.Known
+	if known == nil {
+		known = isKnownAttr
+	}
+	for _, a := range m.Attributes {
+		if !known(a.Type) {
+			d.Unknown(a)
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) decode(data []byte, m *Message) error {
+	if m == nil {
+		return ErrDecodeToNil
+	}
+	if d.MaxSize > 0 && len(data) > d.MaxSize {
+		if d.Warn == nil {
+			return ErrSizeLimit
+		}
+		d.Warn(ErrSizeLimit)
+		data = data[:d.MaxSize]
+	}
+	m.Raw = append(m.Raw[:0], data...)
+	if err := m.decode(!d.Classic); err != nil {
+		if d.Warn == nil || len(m.Raw) < messageHeaderSize || (!d.Classic && !IsMessage(m.Raw)) {
+			return err
+		}
+		d.Warn(err)
+		decodePrefix(m, !d.Classic)
+	}
+	if d.CheckType && !m.Type.IsValid() {
+		if d.Warn == nil {
+			return ErrInvalidMessageType
+		}
+		d.Warn(ErrInvalidMessageType)
+	}
+	if d.MaxAttributes > 0 && len(m.Attributes) > d.MaxAttributes {
+		if d.Warn == nil {
+			return ErrAttributesLimit
+		}
+		d.Warn(ErrAttributesLimit)
+		truncateAttributes(m, d.MaxAttributes)
+	}
+	return nil
+}
+
+// decodePrefix decodes attributes of m that are fully present in m.Raw,
+// dropping the rest.
+func decodePrefix(m *Message, cookie bool) {
+	if size := len(m.Raw) - messageHeaderSize; size < int(bin.Uint16(m.Raw[2:4])) {
+		// Message is truncated, decoding available bytes.
+		bin.PutUint16(m.Raw[2:4], uint16(size))
+	}
+	if err := m.decode(cookie); err != nil {
+		// Attributes that were decoded before error are valid.
+		truncateAttributes(m, len(m.Attributes))
+	}
+}
+
+// truncateAttributes keeps only first n attributes of m, adjusting m.Raw
+// and m.Length.
+func truncateAttributes(m *Message, n int) {
+	m.Attributes = m.Attributes[:n]
+	end := messageHeaderSize
+	for _, a := range m.Attributes {
+		end += attributeHeaderSize + nearestPaddedValueLength(int(a.Length))
+	}
+	m.Raw = m.Raw[:end]
+	m.Length = uint32(end - messageHeaderSize)
+	m.WriteLength()
+}
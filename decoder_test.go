@@ -0,0 +1,190 @@
+package stun
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	m := New()
+	m.Type = BindingRequest
+	m.Add(AttrSoftware, []byte("software"))
+	m.Add(0x7ffe, []byte{1, 2, 3})
+	m.Add(0xfffe, []byte{4})
+	m.WriteHeader()
+	var unknown []RawAttribute
+	d := &Decoder{
+		Unknown: func(a RawAttribute) {
+			unknown = append(unknown, a)
+		},
+	}
+	decoded := New()
+	if err := d.Decode(m.Raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Attributes) != 3 {
+		t.Errorf("unexpected attributes count %d", len(decoded.Attributes))
+	}
+	if len(unknown) != 2 {
+		t.Fatalf("unexpected unknown count %d", len(unknown))
+	}
+	if unknown[0].Type != 0x7ffe || !unknown[0].Type.Required() {
+		t.Error("unexpected first attribute", unknown[0])
+	}
+	if !bytes.Equal(unknown[0].Value, []byte{1, 2, 3}) {
+		t.Error("unexpected first value", unknown[0].Value)
+	}
+	if unknown[1].Type != 0xfffe || !unknown[1].Type.Optional() {
+		t.Error("unexpected second attribute", unknown[1])
+	}
+	t.Run("Known", func(t *testing.T) {
+		unknown = unknown[:0]
+		d.Known = func(t AttrType) bool {
+			return t != AttrSoftware
+		}
+		if err := d.Decode(m.Raw, decoded); err != nil {
+			t.Fatal(err)
+		}
+		if len(unknown) != 1 || unknown[0].Type != AttrSoftware {
+			t.Error("unexpected unknown", unknown)
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		if err := d.Decode(m.Raw[:10], decoded); err != ErrUnexpectedHeaderEOF {
+			t.Error("unexpected error", err)
+		}
+	})
+	t.Run("ZeroValue", func(t *testing.T) {
+		var zero Decoder
+		if err := zero.Decode(m.Raw, decoded); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestDecoder_Limits(t *testing.T) {
+	m := MustBuild(TransactionID, BindingRequest,
+		NewSoftware("software"),
+		NewUsername("username"),
+		NewRealm("realm"),
+	)
+	t.Run("Hard", func(t *testing.T) {
+		decoded := New()
+		if err := (&Decoder{MaxSize: len(m.Raw) - 1}).Decode(m.Raw, decoded); err != ErrSizeLimit {
+			t.Error("unexpected error", err)
+		}
+		if err := (&Decoder{MaxAttributes: 2}).Decode(m.Raw, decoded); err != ErrAttributesLimit {
+			t.Error("unexpected error", err)
+		}
+		if err := (&Decoder{MaxSize: len(m.Raw), MaxAttributes: 3}).Decode(m.Raw, decoded); err != nil {
+			t.Error(err)
+		}
+	})
+	for _, tc := range []struct {
+		name     string
+		d        Decoder
+		data     []byte
+		warnings []error
+		types    []AttrType
+	}{
+		{
+			name:     "MaxAttributes",
+			d:        Decoder{MaxAttributes: 2},
+			data:     m.Raw,
+			warnings: []error{ErrAttributesLimit},
+			types:    []AttrType{AttrSoftware, AttrUsername},
+		},
+		{
+			name:     "MaxSize",
+			d:        Decoder{MaxSize: len(m.Raw) - 2},
+			data:     m.Raw,
+			warnings: []error{ErrSizeLimit, nil},
+			types:    []AttrType{AttrSoftware, AttrUsername},
+		},
+		{
+			name:     "Truncated",
+			d:        Decoder{},
+			data:     m.Raw[:messageHeaderSize+12+2],
+			warnings: []error{nil},
+			types:    []AttrType{AttrSoftware},
+		},
+		{
+			name:     "AttributeHeader",
+			d:        Decoder{},
+			data:     m.Raw[:messageHeaderSize+2],
+			warnings: []error{nil},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var warnings []error
+			tc.d.Warn = func(err error) {
+				warnings = append(warnings, err)
+			}
+			decoded := New()
+			if err := tc.d.Decode(tc.data, decoded); err != nil {
+				t.Fatal(err)
+			}
+			if len(warnings) != len(tc.warnings) {
+				t.Fatalf("unexpected warnings %v", warnings)
+			}
+			for i, w := range tc.warnings {
+				// Nil means any decoding error.
+				if w != nil && warnings[i] != w {
+					t.Errorf("unexpected warning %v, expected %v", warnings[i], w)
+				}
+			}
+			if len(decoded.Attributes) != len(tc.types) {
+				t.Fatalf("unexpected attributes %v", decoded.Attributes)
+			}
+			for i, typ := range tc.types {
+				if decoded.Attributes[i].Type != typ {
+					t.Errorf("unexpected attribute %s", decoded.Attributes[i].Type)
+				}
+			}
+			// Valid prefix should be decoded as-is.
+			prefix := New()
+			if err := Decode(decoded.Raw, prefix); err != nil {
+				t.Fatal(err)
+			}
+			if !prefix.Equal(decoded) {
+				t.Error("prefix is not equal to decoded message")
+			}
+		})
+	}
+	t.Run("InvalidHeader", func(t *testing.T) {
+		d := &Decoder{Warn: func(err error) {
+			t.Error("should not warn", err)
+		}}
+		if err := d.Decode(m.Raw[:10], New()); err != ErrUnexpectedHeaderEOF {
+			t.Error("unexpected error", err)
+		}
+	})
+}
+
+func TestDecoder_CheckType(t *testing.T) {
+	m := New()
+	m.Type = NewType(MethodSend, ClassRequest)
+	m.WriteHeader()
+	decoded := New()
+	var zero Decoder
+	if err := zero.Decode(m.Raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+	d := &Decoder{CheckType: true}
+	if err := d.Decode(m.Raw, decoded); err != ErrInvalidMessageType {
+		t.Errorf("unexpected error %v", err)
+	}
+	var warnings []error
+	d.Warn = func(err error) {
+		warnings = append(warnings, err)
+	}
+	if err := d.Decode(m.Raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0] != ErrInvalidMessageType {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if decoded.Type != m.Type {
+		t.Errorf("unexpected type %s", decoded.Type)
+	}
+}
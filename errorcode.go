@@ -131,6 +131,13 @@ const (
 	CodePeerAddrFamilyMismatch ErrorCode = 443 // Peer Address Family Mismatch
 )
 
+// Error codes from RFC 8016.
+//
+// RFC 8016 Section 3.5
+const (
+	CodeMobilityForbidden ErrorCode = 405 // Mobility Forbidden
+)
+
 var errorReasons = map[ErrorCode][]byte{
 	CodeTryAlternate:     []byte("Try Alternate"),
 	CodeBadRequest:       []byte("Bad Request"),
@@ -155,4 +162,7 @@ var errorReasons = map[ErrorCode][]byte{
 	// RFC 6156.
 	CodeAddrFamilyNotSupported: []byte("Address Family not Supported"),
 	CodePeerAddrFamilyMismatch: []byte("Peer Address Family Mismatch"),
+
+	// RFC 8016.
+	CodeMobilityForbidden: []byte("Mobility Forbidden"),
 }
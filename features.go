@@ -0,0 +1,111 @@
+package stun
+
+import "strings"
+
+// ServerFeatures is set of RFC 8489 features of server that are
+// detected from single response, e.g. from 401 (Unauthorized)
+// challenge to unauthenticated request.
+type ServerFeatures struct {
+	// Software is value of SOFTWARE attribute, if any.
+	Software string
+	// NonceCookie is true if NONCE starts with nonce cookie.
+	NonceCookie bool
+	// Security is set of security features signaled by nonce cookie.
+	Security SecurityFeatures
+	// PasswordAlgorithms is copy of PASSWORD-ALGORITHMS, if any.
+	PasswordAlgorithms PasswordAlgorithms
+	// SHA256 is true if response is protected by
+	// MESSAGE-INTEGRITY-SHA256 or SHA-256 is listed in
+	// PASSWORD-ALGORITHMS.
+	SHA256 bool
+	// Userhash is true if server signals username anonymity, so
+	// USERHASH can be used instead of USERNAME.
+	Userhash bool
+	// AlternateDomain is true if response contains ALTERNATE-DOMAIN.
+	AlternateDomain bool
+}
+
+// DetectServerFeatures returns features of server that sent m.
+// Absence of feature in response does not mean that server does not
+// support it, e.g. nonce cookie is only sent in challenges.
+func DetectServerFeatures(m *Message) ServerFeatures {
+	var f ServerFeatures
+	var software Software
+	if software.GetFrom(m) == nil {
+		f.Software = software.String()
+	}
+	var nonce Nonce
+	if nonce.GetFrom(m) == nil {
+		f.Security, f.NonceCookie = nonce.Features()
+	}
+	var algorithms PasswordAlgorithms
+	if algorithms.GetFrom(m) == nil {
+		for _, a := range algorithms {
+			f.PasswordAlgorithms = append(f.PasswordAlgorithms, PasswordAlgorithmAttribute{
+				Algorithm:  a.Algorithm,
+				Parameters: append([]byte(nil), a.Parameters...),
+			})
+			if a.Algorithm == PasswordAlgorithmSHA256 {
+				f.SHA256 = true
+			}
+		}
+	}
+	if m.Contains(AttrMessageIntegritySHA256) {
+		f.SHA256 = true
+	}
+	f.Userhash = f.Security.Has(FeatureUsernameAnonymity)
+	f.AlternateDomain = m.Contains(AttrAlternateDomain)
+	return f
+}
+
+// RFC8489 returns true if any of RFC 8489 features is detected.
+func (f ServerFeatures) RFC8489() bool {
+	return f.NonceCookie || f.SHA256 || f.AlternateDomain || len(f.PasswordAlgorithms) > 0
+}
+
+func (f ServerFeatures) String() string {
+	var s []string
+	if f.Software != "" {
+		s = append(s, "software: "+f.Software)
+	}
+	if f.NonceCookie {
+		s = append(s, "nonce cookie: "+f.Security.String())
+	}
+	if len(f.PasswordAlgorithms) > 0 {
+		s = append(s, "password algorithms: "+f.PasswordAlgorithms.String())
+	}
+	if f.SHA256 {
+		s = append(s, "SHA-256")
+	}
+	if f.Userhash {
+		s = append(s, "USERHASH")
+	}
+	if f.AlternateDomain {
+		s = append(s, "ALTERNATE-DOMAIN")
+	}
+	if len(s) == 0 {
+		return "<nil>"
+	}
+	return strings.Join(s, ", ")
+}
+
+// ProbeFeatures performs transaction with request m and returns
+// features detected from response. Request that requires
+// authentication and has no credentials results in challenge, so
+// more features can be detected than from Binding request.
+func (c *Client) ProbeFeatures(m *Message) (ServerFeatures, error) {
+	var (
+		features ServerFeatures
+		eventErr error
+	)
+	if err := c.Do(m, func(e Event) {
+		if e.Error != nil {
+			eventErr = e.Error
+			return
+		}
+		features = DetectServerFeatures(e.Message)
+	}); err != nil {
+		return features, err
+	}
+	return features, eventErr
+}
@@ -0,0 +1,77 @@
+package stun
+
+import "testing"
+
+func TestDetectServerFeatures(t *testing.T) {
+	t.Run("Classic", func(t *testing.T) {
+		m := MustBuild(TransactionID, BindingSuccess, NewSoftware("legacy"))
+		f := DetectServerFeatures(m)
+		if f.RFC8489() {
+			t.Error("should not detect RFC 8489")
+		}
+		if f.String() != "software: legacy" {
+			t.Errorf("unexpected string %q", f)
+		}
+		if (ServerFeatures{}).String() != "<nil>" {
+			t.Error("unexpected string of empty features")
+		}
+	})
+	t.Run("Challenge", func(t *testing.T) {
+		m := MustBuild(TransactionID,
+			NewType(MethodBinding, ClassErrorResponse), CodeUnauthorized,
+			NewRealm("realm"),
+			NewSecureNonce(FeaturePasswordAlgorithms|FeatureUsernameAnonymity, "nonce"),
+			PasswordAlgorithms{
+				{Algorithm: PasswordAlgorithmSHA256},
+				{Algorithm: PasswordAlgorithmMD5},
+			},
+			NewAlternateDomain("example.org"),
+		)
+		f := DetectServerFeatures(m)
+		if !f.RFC8489() || !f.NonceCookie || !f.SHA256 || !f.Userhash || !f.AlternateDomain {
+			t.Errorf("unexpected features %s", f)
+		}
+		if !f.Security.Has(FeaturePasswordAlgorithms) {
+			t.Error("password algorithms feature should be set")
+		}
+		if len(f.PasswordAlgorithms) != 2 {
+			t.Fatalf("unexpected algorithms %s", f.PasswordAlgorithms)
+		}
+		for i := range m.Raw {
+			m.Raw[i] = 0
+		}
+		if f.PasswordAlgorithms[0].Algorithm != PasswordAlgorithmSHA256 {
+			t.Error("algorithms should be copied")
+		}
+	})
+	t.Run("IntegritySHA256", func(t *testing.T) {
+		m := MustBuild(TransactionID, BindingSuccess,
+			MessageIntegritySHA256(NewShortTermIntegrity("pwd")),
+		)
+		if f := DetectServerFeatures(m); !f.SHA256 || f.NonceCookie {
+			t.Errorf("unexpected features %s", f)
+		}
+	})
+}
+
+func TestClient_ProbeFeatures(t *testing.T) {
+	addr, closeServer := serveUDP(t, func(req *Message) *Message {
+		return MustBuild(req, NewType(req.Type.Method, ClassErrorResponse),
+			CodeUnauthorized, NewRealm("realm"),
+			NewSecureNonce(FeatureUsernameAnonymity, "nonce"),
+		)
+	})
+	defer closeServer()
+	c, err := Dial("udp4", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	f, err := c.ProbeFeatures(MustBuild(TransactionID, BindingRequest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.NonceCookie || !f.Userhash || f.SHA256 {
+		t.Errorf("unexpected features %s", f)
+	}
+}
@@ -1,9 +1,6 @@
 package stun
 
-import (
-	"errors"
-	"hash/crc32"
-)
+import "errors"
 
 // FingerprintAttr represents FINGERPRINT attribute.
 //
@@ -33,7 +30,7 @@ const (
 // the 32-bit value 0x5354554e (the XOR helps in cases where an
 // application packet is also using CRC-32 in it).
 func FingerprintValue(b []byte) uint32 {
-	return crc32.ChecksumIEEE(b) ^ fingerprintXORValue // XOR
+	return checksumCRC32(b) ^ fingerprintXORValue // XOR
 }
 
 // AddTo adds fingerprint to message.
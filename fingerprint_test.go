@@ -71,3 +71,27 @@ func BenchmarkFingerprint_Check(b *testing.B) {
 		}
 	}
 }
+
+func TestFingerprint_CheckAfterIntegrity(t *testing.T) {
+	m := new(Message)
+	m.TransactionID = [TransactionIDSize]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	m.WriteHeader()
+	addAttr(t, m, NewSoftware("software"))
+	addAttr(t, m, NewShortTermIntegrity("pwd"))
+	if err := Fingerprint.AddTo(m); err != nil {
+		t.Fatal(err)
+	}
+	dM := new(Message)
+	if err := Decode(m.Raw, dM); err != nil {
+		t.Fatal(err)
+	}
+	if err := Fingerprint.Check(dM); err != nil {
+		t.Error(err)
+	}
+	if err := NewShortTermIntegrity("pwd").Check(dM); err != nil {
+		t.Error(err)
+	}
+	if int(dM.Length)+messageHeaderSize != len(dM.Raw) {
+		t.Error("length in header should include FINGERPRINT")
+	}
+}
@@ -0,0 +1,81 @@
+package stun
+
+import (
+	"crypto/md5" // #nosec
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+)
+
+// Hashes is set of hash implementations used by package, allowing to
+// replace standard library implementations, e.g. with wrappers of
+// FIPS-validated module. Nil fields mean standard library
+// implementation.
+type Hashes struct {
+	// MD5 is used for long-term key derivation with MD5 password
+	// algorithm.
+	MD5 func() hash.Hash
+	// SHA1 is used for HMAC of MESSAGE-INTEGRITY.
+	SHA1 func() hash.Hash
+	// SHA256 is used for HMAC of MESSAGE-INTEGRITY-SHA256, USERHASH and
+	// long-term key derivation with SHA-256 password algorithm.
+	SHA256 func() hash.Hash
+	// CRC32 returns CRC-32 of b for FINGERPRINT, without XOR.
+	CRC32 func(b []byte) uint32
+}
+
+var hashes Hashes
+
+// SetHashes sets hash implementations that are used by package.
+// Fields of h that are nil are reset to standard library implementations,
+// so SetHashes(Hashes{}) restores defaults.
+//
+// Not safe for concurrent use with any other function of package, so
+// should be called on program initialization.
+//
+// Custom HMAC implementations are not pooled, so MESSAGE-INTEGRITY and
+// MESSAGE-INTEGRITY-SHA256 computation allocates.
+func SetHashes(h Hashes) {
+	hashes = h
+}
+
+// HashUsage describes hash algorithm used by package.
+type HashUsage struct {
+	Algorithm string // e.g. "HMAC-SHA1"
+	Usage     string // e.g. "MESSAGE-INTEGRITY"
+	Custom    bool   // implementation is set by SetHashes
+}
+
+// UsedHashes returns list of hash algorithms that are used by package
+// and whether their implementations are replaced by SetHashes.
+func UsedHashes() []HashUsage {
+	return []HashUsage{
+		{Algorithm: "CRC-32", Usage: "FINGERPRINT", Custom: hashes.CRC32 != nil},
+		{Algorithm: "HMAC-SHA1", Usage: "MESSAGE-INTEGRITY", Custom: hashes.SHA1 != nil},
+		{Algorithm: "HMAC-SHA256", Usage: "MESSAGE-INTEGRITY-SHA256", Custom: hashes.SHA256 != nil},
+		{Algorithm: "SHA-256", Usage: "USERHASH", Custom: hashes.SHA256 != nil},
+		{Algorithm: "MD5", Usage: "long-term key (MD5)", Custom: hashes.MD5 != nil},
+		{Algorithm: "SHA-256", Usage: "long-term key (SHA-256)", Custom: hashes.SHA256 != nil},
+	}
+}
+
+func newMD5() hash.Hash {
+	if hashes.MD5 != nil {
+		return hashes.MD5()
+	}
+	return md5.New() // #nosec
+}
+
+func newSHA256() hash.Hash {
+	if hashes.SHA256 != nil {
+		return hashes.SHA256()
+	}
+	return sha256.New()
+}
+
+func checksumCRC32(b []byte) uint32 {
+	if hashes.CRC32 != nil {
+		return hashes.CRC32(b)
+	}
+	return crc32.ChecksumIEEE(b)
+}
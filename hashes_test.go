@@ -0,0 +1,58 @@
+package stun
+
+import (
+	"crypto/md5"  // #nosec
+	"crypto/sha1" // #nosec
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+	"testing"
+)
+
+func TestSetHashes(t *testing.T) {
+	var calls = map[string]int{}
+	counting := func(name string, f func() hash.Hash) func() hash.Hash {
+		return func() hash.Hash {
+			calls[name]++
+			return f()
+		}
+	}
+	SetHashes(Hashes{
+		MD5:    counting("MD5", md5.New),
+		SHA1:   counting("SHA1", sha1.New),
+		SHA256: counting("SHA256", sha256.New),
+		CRC32: func(b []byte) uint32 {
+			calls["CRC32"]++
+			return crc32.ChecksumIEEE(b)
+		},
+	})
+	defer SetHashes(Hashes{})
+	for _, u := range UsedHashes() {
+		if !u.Custom {
+			t.Errorf("%s for %s should be custom", u.Algorithm, u.Usage)
+		}
+	}
+	integrity := NewLongTermIntegrity("user", "realm", "pass")
+	sha256Integrity := MessageIntegritySHA256(integrity)
+	m := MustBuild(BindingRequest, NewUserhash("user", "realm"),
+		integrity, sha256Integrity, Fingerprint,
+	)
+	if err := m.Check(integrity, sha256Integrity, Fingerprint); err != nil {
+		t.Error(err)
+	}
+	for _, name := range []string{"MD5", "SHA1", "SHA256", "CRC32"} {
+		if calls[name] == 0 {
+			t.Errorf("%s is not used", name)
+		}
+	}
+
+	SetHashes(Hashes{})
+	for _, u := range UsedHashes() {
+		if u.Custom {
+			t.Errorf("%s for %s should be default", u.Algorithm, u.Usage)
+		}
+	}
+	if err := m.Check(integrity, sha256Integrity, Fingerprint); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,85 @@
+package stun
+
+// HeaderSize is size of STUN message header in bytes.
+const HeaderSize = messageHeaderSize
+
+// MagicCookie is the fixed value of Header.Cookie for messages that are
+// not RFC 3489 classic ones.
+const MagicCookie uint32 = magicCookie
+
+// Sizes of header fields.
+const (
+	headerTypeSize   = 2
+	headerLengthSize = 2
+	headerCookieSize = 4
+	headerFieldsSize = headerTypeSize + headerLengthSize + headerCookieSize + TransactionIDSize
+)
+
+// Compile-time checks that header fields fill exactly HeaderSize bytes:
+// array length is negative, so build fails, if sizes differ.
+var (
+	_ [HeaderSize - headerFieldsSize]struct{}
+	_ [headerFieldsSize - HeaderSize]struct{}
+)
+
+// Header is fixed-size STUN message header, for tools that inspect or
+// rewrite only headers without decoding attributes.
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|0 0|     STUN Message Type     |         Message Length        |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                         Magic Cookie                          |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                                                               |
+//	|                     Transaction ID (96 bits)                  |
+//	|                                                               |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//
+// RFC 5389 Section 6
+type Header struct {
+	Type          MessageType
+	Length        uint16 // length of attributes, not including header
+	Cookie        uint32
+	TransactionID [TransactionIDSize]byte
+}
+
+// Header returns header of m with MagicCookie.
+func (m *Message) Header() Header {
+	return Header{
+		Type:          m.Type,
+		Length:        uint16(m.Length),
+		Cookie:        MagicCookie,
+		TransactionID: m.TransactionID,
+	}
+}
+
+// Encode returns binary representation of h.
+func (h Header) Encode() [HeaderSize]byte {
+	var b [HeaderSize]byte
+	bin.PutUint16(b[0:2], h.Type.Value())
+	bin.PutUint16(b[2:4], h.Length)
+	bin.PutUint32(b[4:8], h.Cookie)
+	copy(b[8:HeaderSize], h.TransactionID[:])
+	return b
+}
+
+// Decode decodes h from first HeaderSize bytes of b, returning
+// ErrUnexpectedHeaderEOF if b is shorter. Fields are not validated, use
+// HasMagicCookie to check cookie.
+func (h *Header) Decode(b []byte) error {
+	if len(b) < HeaderSize {
+		return ErrUnexpectedHeaderEOF
+	}
+	h.Type.ReadValue(bin.Uint16(b[0:2]))
+	h.Length = bin.Uint16(b[2:4])
+	h.Cookie = bin.Uint32(b[4:8])
+	copy(h.TransactionID[:], b[8:HeaderSize])
+	return nil
+}
+
+// HasMagicCookie returns true if h.Cookie is MagicCookie.
+func (h Header) HasMagicCookie() bool {
+	return h.Cookie == MagicCookie
+}
@@ -0,0 +1,46 @@
+package stun
+
+import "testing"
+
+func TestHeader(t *testing.T) {
+	m := MustBuild(TransactionID, BindingRequest, NewSoftware("software"))
+	h := m.Header()
+	b := h.Encode()
+	if string(b[:]) != string(m.Raw[:HeaderSize]) {
+		t.Errorf("encoded %x != %x", b, m.Raw[:HeaderSize])
+	}
+	var decoded Header
+	if err := decoded.Decode(m.Raw); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != h || !decoded.HasMagicCookie() {
+		t.Errorf("decoded %+v != %+v", decoded, h)
+	}
+	if int(decoded.Length) != len(m.Raw)-HeaderSize {
+		t.Errorf("unexpected length %d", decoded.Length)
+	}
+	if err := decoded.Decode(m.Raw[:HeaderSize-1]); err != ErrUnexpectedHeaderEOF {
+		t.Errorf("unexpected error %v", err)
+	}
+	t.Run("Classic", func(t *testing.T) {
+		raw := append([]byte(nil), m.Raw...)
+		raw[4]++
+		if err := decoded.Decode(raw); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.HasMagicCookie() {
+			t.Error("cookie should differ")
+		}
+	})
+}
+
+func BenchmarkHeader_Decode(b *testing.B) {
+	m := MustBuild(TransactionID, BindingRequest)
+	var h Header
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := h.Decode(m.Raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
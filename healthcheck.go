@@ -0,0 +1,74 @@
+package stun
+
+import "net"
+
+// HealthProbe returns true if request req from addr is health check of
+// load balancer, see WithHealthCheck.
+type HealthProbe func(req *Message, addr net.Addr) bool
+
+// NewHealthProbe returns HealthProbe that matches Binding requests with
+// SOFTWARE attribute that is equal to software, which should be set in
+// health check request of load balancer.
+func NewHealthProbe(software string) HealthProbe {
+	return func(req *Message, addr net.Addr) bool {
+		var s Software
+		return req.Type == BindingRequest && s.GetFrom(req) == nil && s.String() == software
+	}
+}
+
+// WithHealthCheck makes server answer messages that are matched by
+// probe with Binding success response after policy and rate limit, but
+// before required checks, quotas and authentication, so health checks
+// of load balancers are never challenged or rejected. Probes are
+// counted in Stats as received and in HealthChecks, and are dropped
+// while server is not ready, see Server.Ready.
+func WithHealthCheck(probe HealthProbe) ServerOption {
+	return func(s *Server) {
+		s.healthProbe = probe
+	}
+}
+
+// SetReady marks server as ready or not ready to serve clients, e.g.
+// not ready before Shutdown, so load balancer stops routing clients to
+// server while it is still serving. Server is ready by default.
+func (s *Server) SetReady(ready bool) {
+	s.mux.Lock()
+	s.notReady = !ready
+	s.mux.Unlock()
+}
+
+// Ready returns true if server is alive and is not marked as not ready
+// by SetReady. Health checks are answered only if server is ready.
+func (s *Server) Ready() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.alive() && !s.notReady
+}
+
+// Alive returns true if server serves at least one conn or listener and
+// Close or Shutdown was not called.
+func (s *Server) Alive() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.alive()
+}
+
+// alive is Alive with s.mux held.
+func (s *Server) alive() bool {
+	return !s.closed && !s.shutdown && len(s.closers) > 0
+}
+
+// healthCheck builds response to health check probe req, returning
+// false if server is not ready.
+func (s *Server) healthCheck(res, req *Message, addr net.Addr) bool {
+	s.stats.inc(&s.stats.healthChecks)
+	if !s.Ready() {
+		s.stats.inc(&s.stats.dropped)
+		return false
+	}
+	res.Reset()
+	if err := s.bindingResponse(res, req, addr); err != nil {
+		return false
+	}
+	return s.finish(res, nil)
+}
@@ -0,0 +1,65 @@
+package stun
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServer_HealthCheck(t *testing.T) {
+	s := newTestServer(t,
+		WithRequireFingerprint,
+		WithHealthCheck(NewHealthProbe("health")),
+		WithAuth("realm", func(username, realm string) ([]byte, bool) {
+			return nil, false
+		}),
+	)
+	if s.Alive() || s.Ready() {
+		t.Error("server is not serving")
+	}
+	listenServer(t, "udp", s)
+	deadline := time.Now().Add(time.Second * 5)
+	for !s.Alive() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !s.Ready() {
+		t.Error("server should be ready")
+	}
+	var (
+		addr  = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+		probe = MustBuild(TransactionID, BindingRequest, NewSoftware("health"))
+		res   = new(Message)
+	)
+	if !s.process(res, new(Message), probe.Raw, addr) {
+		t.Fatal("no response")
+	}
+	var mapped XORMappedAddress
+	if err := mapped.GetFrom(res); err != nil || res.Type != BindingSuccess || mapped.Port != addr.Port {
+		t.Errorf("unexpected response %s: %v", res, err)
+	}
+	other := MustBuild(TransactionID, BindingRequest, NewSoftware("client"), Fingerprint)
+	if !s.process(res, new(Message), other.Raw, addr) || res.Type.Class != ClassErrorResponse {
+		t.Errorf("request should be challenged: %s", res)
+	}
+	s.SetReady(false)
+	if s.Ready() || !s.Alive() {
+		t.Error("server should be alive, but not ready")
+	}
+	if s.process(res, new(Message), probe.Raw, addr) {
+		t.Error("probe should not be answered if not ready")
+	}
+	stats := s.Stats().Snapshot()
+	if stats.Received != 3 || stats.HealthChecks != 2 || stats.Dropped != 1 {
+		t.Errorf("unexpected stats %+v", stats.StatsCounters)
+	}
+	s.SetReady(true)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Alive() || s.Ready() {
+		t.Error("closed server should not be alive")
+	}
+}
@@ -239,3 +239,71 @@ func BenchmarkMessage_ForEach(b *testing.B) {
 		}
 	}
 }
+
+// Typed attributes should be usable with Build, Parse and Check.
+var (
+	_ Setter  = new(MappedAddress)
+	_ Getter  = new(MappedAddress)
+	_ Setter  = new(AlternateServer)
+	_ Getter  = new(AlternateServer)
+	_ Setter  = new(XORMappedAddress)
+	_ Getter  = new(XORMappedAddress)
+	_ Setter  = new(Username)
+	_ Getter  = new(Username)
+	_ Setter  = new(Realm)
+	_ Getter  = new(Realm)
+	_ Setter  = new(Nonce)
+	_ Getter  = new(Nonce)
+	_ Setter  = new(Userhash)
+	_ Getter  = new(Userhash)
+	_ Setter  = new(PasswordAlgorithmAttribute)
+	_ Getter  = new(PasswordAlgorithmAttribute)
+	_ Setter  = new(PasswordAlgorithms)
+	_ Getter  = new(PasswordAlgorithms)
+	_ Setter  = new(AlternateDomain)
+	_ Getter  = new(AlternateDomain)
+	_ Setter  = new(ChangeRequest)
+	_ Getter  = new(ChangeRequest)
+	_ Setter  = new(ResponseOrigin)
+	_ Getter  = new(ResponseOrigin)
+	_ Setter  = new(OtherAddress)
+	_ Getter  = new(OtherAddress)
+	_ Setter  = new(ResponsePort)
+	_ Getter  = new(ResponsePort)
+	_ Setter  = new(Padding)
+	_ Getter  = new(Padding)
+	_ Setter  = new(SourceAddress)
+	_ Getter  = new(SourceAddress)
+	_ Setter  = new(ChangedAddress)
+	_ Getter  = new(ChangedAddress)
+	_ Getter  = new(ReflexiveAddress)
+	_ Setter  = new(Software)
+	_ Getter  = new(Software)
+	_ Setter  = new(ErrorCodeAttribute)
+	_ Getter  = new(ErrorCodeAttribute)
+	_ Setter  = new(UnknownAttributes)
+	_ Getter  = new(UnknownAttributes)
+	_ Setter  = new(MessageIntegrity)
+	_ Checker = new(MessageIntegrity)
+	_ Setter  = new(MessageIntegritySHA256)
+	_ Checker = new(MessageIntegritySHA256)
+	_ Setter  = new(FingerprintAttr)
+	_ Checker = new(FingerprintAttr)
+	_ Checker = new(KnownAttributes)
+	_ Setter  = new(UnknownAttrsErr)
+	_ Setter  = new(ErrorCode)
+	_ Setter  = new(MessageType)
+	_ Setter  = new(RawAttribute)
+	_ Getter  = new(RawAttribute)
+	_ Setter  = new(Message)
+	_ Setter  = SetterFunc(nil)
+	_ Setter  = new(LongTermCredentials)
+	_ Setter  = new(Priority)
+	_ Getter  = new(Priority)
+	_ Setter  = UseCandidate
+	_ Getter  = UseCandidate
+	_ Setter  = new(ICEControlled)
+	_ Getter  = new(ICEControlled)
+	_ Setter  = new(ICEControlling)
+	_ Getter  = new(ICEControlling)
+)
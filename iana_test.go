@@ -69,6 +69,9 @@ func TestIANA(t *testing.T) {
 		// Not registered in IANA.
 		for k, v := range map[string]AttrType{
 			"ORIGIN": 0x802F,
+			// Reserved, from RFC 3489.
+			"SOURCE-ADDRESS":  0x0004,
+			"CHANGED-ADDRESS": 0x0005,
 		} {
 			m[k] = v
 		}
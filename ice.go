@@ -0,0 +1,266 @@
+package stun
+
+import "crypto/rand"
+
+// Priority represents PRIORITY attribute.
+//
+// RFC 8445 Section 7.1.1
+type Priority uint32
+
+const prioritySize = 4 // 32 bit
+
+// Recommended type preferences of candidate types.
+//
+// RFC 8445 Section 5.1.2.2
+const (
+	TypePreferenceHost            uint8 = 126
+	TypePreferencePeerReflexive   uint8 = 110
+	TypePreferenceServerReflexive uint8 = 100
+	TypePreferenceRelayed         uint8 = 0
+)
+
+// MaxLocalPreference is local preference that should be used when agent
+// is multihomed only on single IP address family.
+//
+// RFC 8445 Section 5.1.2.1
+const MaxLocalPreference uint16 = 65535
+
+// NewPriority computes candidate priority from type preference (0-126),
+// local preference (0-65535) and component ID (1-256):
+//
+//	priority = (2^24)*(type preference) +
+//	           (2^8)*(local preference) +
+//	           (2^0)*(256 - component ID)
+//
+// Type preference above 126 and component ID outside of 1-256 are
+// clamped to those ranges, so priority never exceeds 2^31 - 1 and
+// component ID never overflows into local preference. When computing PRIORITY for
+// connectivity check, use TypePreferencePeerReflexive as type
+// preference.
+//
+// RFC 8445 Section 5.1.2.1
+func NewPriority(typePreference uint8, localPreference, componentID uint16) Priority {
+	if typePreference > maxTypePreference {
+		typePreference = maxTypePreference
+	}
+	switch {
+	case componentID < minComponentID:
+		componentID = minComponentID
+	case componentID > maxComponentID:
+		componentID = maxComponentID
+	}
+	return Priority(uint32(typePreference)<<24 + uint32(localPreference)<<8 + (maxComponentID - uint32(componentID)))
+}
+
+// Ranges of type preference and component ID, see NewPriority.
+const (
+	maxTypePreference = 126
+	minComponentID    = 1
+	maxComponentID    = 256
+)
+
+// AddTo adds PRIORITY attribute to message.
+func (p Priority) AddTo(m *Message) error {
+	v := make([]byte, prioritySize)
+	bin.PutUint32(v, uint32(p))
+	m.Add(AttrPriority, v)
+	return nil
+}
+
+// GetFrom decodes PRIORITY attribute from message.
+func (p *Priority) GetFrom(m *Message) error {
+	v, err := m.Get(AttrPriority)
+	if err != nil {
+		return err
+	}
+	if err = CheckSize(AttrPriority, len(v), prioritySize); err != nil {
+		return err
+	}
+	*p = Priority(bin.Uint32(v))
+	return nil
+}
+
+// UseCandidateAttr represents USE-CANDIDATE attribute.
+//
+// RFC 8445 Section 7.1.2
+type UseCandidateAttr struct{}
+
+// UseCandidate is shorthand for UseCandidateAttr.
+var UseCandidate UseCandidateAttr
+
+// AddTo adds USE-CANDIDATE attribute to message.
+func (UseCandidateAttr) AddTo(m *Message) error {
+	m.Add(AttrUseCandidate, nil)
+	return nil
+}
+
+// GetFrom checks that USE-CANDIDATE attribute is present in message and
+// has no value.
+func (UseCandidateAttr) GetFrom(m *Message) error {
+	v, err := m.Get(AttrUseCandidate)
+	if err != nil {
+		return err
+	}
+	return CheckSize(AttrUseCandidate, len(v), 0)
+}
+
+// IsSet returns true if USE-CANDIDATE attribute is set.
+func (UseCandidateAttr) IsSet(m *Message) bool {
+	return m.Contains(AttrUseCandidate)
+}
+
+const tiebreakerSize = 8 // 64 bit
+
+// NewTiebreaker returns random 64-bit tiebreaker using crypto/rand,
+// panicking on read error.
+//
+// RFC 8445 Section 16.1
+func NewTiebreaker() uint64 {
+	var b [tiebreakerSize]byte
+	readFullOrPanic(rand.Reader, b[:])
+	return bin.Uint64(b[:])
+}
+
+func addTiebreaker(m *Message, t AttrType, v uint64) {
+	b := make([]byte, tiebreakerSize)
+	bin.PutUint64(b, v)
+	m.Add(t, b)
+}
+
+func getTiebreaker(m *Message, t AttrType) (uint64, error) {
+	v, err := m.Get(t)
+	if err != nil {
+		return 0, err
+	}
+	if err = CheckSize(t, len(v), tiebreakerSize); err != nil {
+		return 0, err
+	}
+	return bin.Uint64(v), nil
+}
+
+// ICEControlled represents ICE-CONTROLLED attribute, the tiebreaker of
+// agent that is in controlled role.
+//
+// RFC 8445 Section 7.1.3
+type ICEControlled uint64
+
+// AddTo adds ICE-CONTROLLED attribute to message.
+func (c ICEControlled) AddTo(m *Message) error {
+	addTiebreaker(m, AttrICEControlled, uint64(c))
+	return nil
+}
+
+// GetFrom decodes ICE-CONTROLLED attribute from message.
+func (c *ICEControlled) GetFrom(m *Message) error {
+	v, err := getTiebreaker(m, AttrICEControlled)
+	if err != nil {
+		return err
+	}
+	*c = ICEControlled(v)
+	return nil
+}
+
+// ICEControlling represents ICE-CONTROLLING attribute, the tiebreaker
+// of agent that is in controlling role.
+//
+// RFC 8445 Section 7.1.3
+type ICEControlling uint64
+
+// AddTo adds ICE-CONTROLLING attribute to message.
+func (c ICEControlling) AddTo(m *Message) error {
+	addTiebreaker(m, AttrICEControlling, uint64(c))
+	return nil
+}
+
+// GetFrom decodes ICE-CONTROLLING attribute from message.
+func (c *ICEControlling) GetFrom(m *Message) error {
+	v, err := getTiebreaker(m, AttrICEControlling)
+	if err != nil {
+		return err
+	}
+	*c = ICEControlling(v)
+	return nil
+}
+
+// Role is ICE agent role.
+//
+// RFC 8445 Section 6.1.1
+type Role byte
+
+// Possible ICE agent roles.
+const (
+	RoleControlled Role = iota
+	RoleControlling
+)
+
+func (r Role) String() string {
+	if r == RoleControlling {
+		return "controlling"
+	}
+	return "controlled"
+}
+
+// Switch returns opposite role.
+func (r Role) Switch() Role {
+	if r == RoleControlling {
+		return RoleControlled
+	}
+	return RoleControlling
+}
+
+// Attribute returns ICE-CONTROLLING or ICE-CONTROLLED attribute of role
+// with tiebreaker, for connectivity check requests.
+func (r Role) Attribute(tiebreaker uint64) Setter {
+	if r == RoleControlling {
+		return ICEControlling(tiebreaker)
+	}
+	return ICEControlled(tiebreaker)
+}
+
+// ResolveRoleConflict detects role conflict between request m and agent
+// with role and tiebreaker. It returns role that agent should use from
+// now on, and true if request should be rejected with 487 (Role
+// Conflict) error response, see NewRoleConflictResponse.
+//
+// RFC 8445 Section 7.3.1.1
+func ResolveRoleConflict(m *Message, role Role, tiebreaker uint64) (Role, bool) {
+	if role == RoleControlling {
+		var remote ICEControlling
+		if remote.GetFrom(m) != nil {
+			return role, false
+		}
+		if tiebreaker >= uint64(remote) {
+			return role, true
+		}
+		return RoleControlled, false
+	}
+	var remote ICEControlled
+	if remote.GetFrom(m) != nil {
+		return role, false
+	}
+	if tiebreaker >= uint64(remote) {
+		return RoleControlling, false
+	}
+	return role, true
+}
+
+// NewRoleConflictResponse returns 487 (Role Conflict) error response to
+// req.
+func NewRoleConflictResponse(req *Message) (*Message, error) {
+	return Build(req, NewType(req.Type.Method, ClassErrorResponse), CodeRoleConflict)
+}
+
+// IsRoleConflict returns true if m is 487 (Role Conflict) error
+// response, so agent should switch role and retry the check.
+//
+// RFC 8445 Section 7.2.5.1
+func IsRoleConflict(m *Message) bool {
+	if m.Type.Class != ClassErrorResponse {
+		return false
+	}
+	var code ErrorCodeAttribute
+	if err := code.GetFrom(m); err != nil {
+		return false
+	}
+	return code.Code == CodeRoleConflict
+}
@@ -0,0 +1,168 @@
+package stun
+
+import (
+	"testing"
+)
+
+func TestNewPriority(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		typePreference  uint8
+		localPreference uint16
+		component       uint16
+		priority        Priority
+	}{
+		{"HostRTP", TypePreferenceHost, MaxLocalPreference, 1, 2130706431},
+		{"HostRTCP", TypePreferenceHost, MaxLocalPreference, 2, 2130706430},
+		{"ServerReflexive", TypePreferenceServerReflexive, MaxLocalPreference, 1, 1694498815},
+		{"PeerReflexive", TypePreferencePeerReflexive, 0, 1, 1845494015},
+		{"Relayed", TypePreferenceRelayed, 0, 1, 255},
+		{"ComponentZero", TypePreferenceHost, MaxLocalPreference, 0, 2130706431},
+		{"ComponentMax", TypePreferenceHost, MaxLocalPreference, 256, 2130706176},
+		{"ComponentOverflow", TypePreferenceHost, MaxLocalPreference, 257, 2130706176},
+		{"ComponentWrap", TypePreferenceRelayed, 0, 65535, 0},
+		{"TypeMax", 126, MaxLocalPreference, 1, 2130706431},
+		{"TypeOverflow", 127, MaxLocalPreference, 1, 2130706431},
+		{"TypeWrap", 255, MaxLocalPreference, 1, 2130706431},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if p := NewPriority(tc.typePreference, tc.localPreference, tc.component); p != tc.priority {
+				t.Errorf("got %d, expected %d", p, tc.priority)
+			}
+		})
+	}
+}
+
+func TestPriority_GetFrom(t *testing.T) {
+	m := new(Message)
+	var p Priority
+	if err := p.GetFrom(m); err != ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+	if err := m.Build(BindingRequest, NewPriority(TypePreferencePeerReflexive, MaxLocalPreference, 1)); err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(Message)
+	if _, err := m1.Write(m.Raw); err != nil {
+		t.Fatal(err)
+	}
+	var p1 Priority
+	if err := p1.GetFrom(m1); err != nil {
+		t.Fatal(err)
+	}
+	if p1 != NewPriority(TypePreferencePeerReflexive, MaxLocalPreference, 1) {
+		t.Error("not equal:", p1)
+	}
+	t.Run("IncorrectSize", func(t *testing.T) {
+		m3 := new(Message)
+		m3.Add(AttrPriority, make([]byte, 100))
+		var p2 Priority
+		if err := p2.GetFrom(m3); !IsAttrSizeInvalid(err) {
+			t.Error("should error")
+		}
+	})
+}
+
+func TestUseCandidate(t *testing.T) {
+	m := new(Message)
+	if UseCandidate.IsSet(m) {
+		t.Error("should not be set")
+	}
+	if err := UseCandidate.GetFrom(m); err != ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+	m = MustBuild(BindingRequest, UseCandidate)
+	if !UseCandidate.IsSet(m) {
+		t.Error("should be set")
+	}
+	if err := UseCandidate.GetFrom(m); err != nil {
+		t.Error(err)
+	}
+	invalid := new(Message)
+	invalid.Add(AttrUseCandidate, []byte{1})
+	if err := UseCandidate.GetFrom(invalid); !IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestICEControlledControlling(t *testing.T) {
+	tiebreaker := NewTiebreaker()
+	m := MustBuild(BindingRequest, ICEControlling(tiebreaker), ICEControlled(tiebreaker+1))
+	var (
+		controlling ICEControlling
+		controlled  ICEControlled
+	)
+	if err := controlling.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := controlled.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if uint64(controlling) != tiebreaker || uint64(controlled) != tiebreaker+1 {
+		t.Errorf("unexpected tiebreakers %d, %d", controlling, controlled)
+	}
+	if v, _ := m.Get(AttrICEControlling); len(v) != tiebreakerSize {
+		t.Errorf("unexpected size %d", len(v))
+	}
+	invalid := new(Message)
+	invalid.Add(AttrICEControlled, []byte{1, 2, 3, 4})
+	if err := controlled.GetFrom(invalid); !IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+	if err := controlling.GetFrom(invalid); err != ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestResolveRoleConflict(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		local    Role
+		remote   Setter
+		role     Role
+		conflict bool
+	}{
+		{"NoConflictControlling", RoleControlling, ICEControlled(10), RoleControlling, false},
+		{"NoConflictControlled", RoleControlled, ICEControlling(10), RoleControlled, false},
+		{"ControllingWins", RoleControlling, ICEControlling(4), RoleControlling, true},
+		{"ControllingEqual", RoleControlling, ICEControlling(5), RoleControlling, true},
+		{"ControllingLoses", RoleControlling, ICEControlling(6), RoleControlled, false},
+		{"ControlledSwitches", RoleControlled, ICEControlled(4), RoleControlling, false},
+		{"ControlledLoses", RoleControlled, ICEControlled(6), RoleControlled, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := MustBuild(TransactionID, BindingRequest, tc.remote)
+			role, conflict := ResolveRoleConflict(m, tc.local, 5)
+			if role != tc.role || conflict != tc.conflict {
+				t.Errorf("got %s, %v; expected %s, %v", role, conflict, tc.role, tc.conflict)
+			}
+		})
+	}
+	if role, conflict := ResolveRoleConflict(MustBuild(TransactionID, BindingRequest), RoleControlled, 0); role != RoleControlled || conflict {
+		t.Error("request without role attribute should not conflict")
+	}
+}
+
+func TestRoleConflictResponse(t *testing.T) {
+	req := MustBuild(TransactionID, BindingRequest, RoleControlling.Attribute(1))
+	var controlling ICEControlling
+	if err := controlling.GetFrom(req); err != nil || controlling != 1 {
+		t.Error("unexpected attribute", controlling, err)
+	}
+	res, err := NewRoleConflictResponse(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TransactionID != req.TransactionID || res.Type != BindingError {
+		t.Errorf("unexpected response %s", res)
+	}
+	if !IsRoleConflict(res) {
+		t.Error("should be role conflict")
+	}
+	if IsRoleConflict(req) || IsRoleConflict(MustBuild(TransactionID, BindingError, CodeBadRequest)) {
+		t.Error("should not be role conflict")
+	}
+	if RoleControlled.Switch() != RoleControlling || RoleControlling.Switch().String() != "controlled" {
+		t.Error("unexpected switch")
+	}
+}
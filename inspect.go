@@ -0,0 +1,257 @@
+package stun
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportReport is result of Binding transaction with server over
+// single transport.
+type TransportReport struct {
+	Network string        // "udp" or "tcp"
+	Latency time.Duration // round trip time of transaction
+	Err     error         // nil if server responded
+}
+
+// InspectReport is compatibility report of server, see Inspect.
+type InspectReport struct {
+	Address    string
+	Transports []TransportReport
+
+	// Software is value of SOFTWARE attribute of server, if any.
+	Software string
+	// RFC5780 is true if Binding response has OTHER-ADDRESS, so NAT
+	// behavior discovery is supported.
+	RFC5780 bool
+	// BindingAuth is true if Binding requests are challenged.
+	BindingAuth bool
+	// TURN is true if Allocate request is answered with success or error
+	// other than 400 (Bad Request) and 420 (Unknown Attribute).
+	TURN bool
+	// LongTermAuth is true if server challenged request with REALM and
+	// NONCE of long-term credential mechanism in Realm.
+	LongTermAuth bool
+	Realm        string
+	// Features are RFC 8489 features detected from responses, e.g.
+	// SHA-256 support and password algorithms from challenge.
+	Features ServerFeatures
+}
+
+// Supports returns true if server responded over network.
+func (r *InspectReport) Supports(network string) bool {
+	for _, t := range r.Transports {
+		if t.Network == network && t.Err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Latency returns smallest round trip time of transports, or zero if
+// server is not reachable.
+func (r *InspectReport) Latency() time.Duration {
+	var latency time.Duration
+	for _, t := range r.Transports {
+		if t.Err == nil && (latency == 0 || t.Latency < latency) {
+			latency = t.Latency
+		}
+	}
+	return latency
+}
+
+func (r *InspectReport) String() string {
+	var s []string
+	for _, t := range r.Transports {
+		if t.Err == nil {
+			s = append(s, t.Network+" "+t.Latency.Round(time.Millisecond).String())
+		}
+	}
+	if r.Software != "" {
+		s = append(s, "software: "+r.Software)
+	}
+	if r.RFC5780 {
+		s = append(s, "RFC 5780")
+	}
+	if r.TURN {
+		s = append(s, "TURN")
+	}
+	if r.BindingAuth {
+		s = append(s, "Binding authentication")
+	}
+	if r.LongTermAuth {
+		s = append(s, "long-term credentials, realm: "+r.Realm)
+	}
+	if r.Features.RFC8489() {
+		s = append(s, "RFC 8489")
+	}
+	if r.Features.SHA256 {
+		s = append(s, "SHA-256")
+	}
+	if len(s) == 0 {
+		return "<nil>"
+	}
+	return r.Address + ": " + strings.Join(s, ", ")
+}
+
+// inspectNetworks are transports that are checked by Inspect.
+var inspectNetworks = []string{"udp", "tcp"}
+
+// ErrServerUnreachable means that server did not respond over any of
+// transports.
+var ErrServerUnreachable = errors.New("server is unreachable")
+
+// Inspect returns compatibility report of server at addr, which
+// should be "host:port", for operators that choose between STUN and
+// TURN providers. Binding requests are sent over UDP and TCP
+// concurrently, and then unauthenticated Allocate request is sent over
+// the fastest transport to detect TURN support and authentication
+// mechanisms from challenge. Each transaction times out in 3.5
+// seconds with ErrTransactionTimeOut, use ctx to limit total time.
+//
+// If server is not reachable, ErrServerUnreachable is returned with
+// report of transports.
+func Inspect(ctx context.Context, addr string) (*InspectReport, error) {
+	r := &InspectReport{
+		Address:    addr,
+		Transports: make([]TransportReport, len(inspectNetworks)),
+	}
+	var (
+		wg        sync.WaitGroup
+		clients   = make([]*Client, len(inspectNetworks))
+		responses = make([]*Message, len(inspectNetworks))
+	)
+	for i, network := range inspectNetworks {
+		wg.Add(1)
+		go func(i int, network string) {
+			defer wg.Done()
+			r.Transports[i].Network = network
+			clients[i], responses[i], r.Transports[i].Latency, r.Transports[i].Err = inspectTransport(ctx, network, addr)
+		}(i, network)
+	}
+	wg.Wait()
+	var (
+		c   *Client
+		res *Message
+	)
+	for i := range clients {
+		if clients[i] == nil {
+			continue
+		}
+		defer clients[i].Close()
+		if r.Transports[i].Latency == r.Latency() {
+			c, res = clients[i], responses[i]
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return r, err
+	}
+	if c == nil {
+		return r, ErrServerUnreachable
+	}
+	r.inspect(res)
+
+	allocate, err := inspectDo(ctx, c, MustBuild(TransactionID,
+		NewType(MethodAllocate, ClassRequest),
+		RawAttribute{Type: AttrRequestedTransport, Value: []byte{17, 0, 0, 0}}, // UDP
+	))
+	if err == nil {
+		r.TURN = allocate.Type.Class == ClassSuccessResponse
+		var code ErrorCodeAttribute
+		if allocate.Type.Class == ClassErrorResponse && code.GetFrom(allocate) == nil {
+			r.TURN = code.Code != CodeBadRequest && code.Code != CodeUnknownAttribute
+		}
+		r.inspect(allocate)
+	} else if ctxErr := ctx.Err(); ctxErr != nil {
+		return r, ctxErr
+	}
+	return r, nil
+}
+
+// inspect updates report from response, keeping features that are
+// already detected.
+func (r *InspectReport) inspect(m *Message) {
+	f := DetectServerFeatures(m)
+	if r.Software == "" {
+		r.Software = f.Software
+	}
+	if m.Type.Method == MethodBinding {
+		r.RFC5780 = m.Contains(AttrOtherAddress)
+	}
+	var (
+		code  ErrorCodeAttribute
+		realm Realm
+	)
+	if m.Type.Class == ClassErrorResponse && code.GetFrom(m) == nil && code.Code == CodeUnauthorized {
+		if m.Type.Method == MethodBinding {
+			r.BindingAuth = true
+		}
+		if realm.GetFrom(m) == nil && m.Contains(AttrNonce) {
+			r.LongTermAuth = true
+			r.Realm = realm.String()
+		}
+	}
+	r.Features.NonceCookie = r.Features.NonceCookie || f.NonceCookie
+	r.Features.Security |= f.Security
+	if len(f.PasswordAlgorithms) > 0 {
+		r.Features.PasswordAlgorithms = f.PasswordAlgorithms
+	}
+	r.Features.SHA256 = r.Features.SHA256 || f.SHA256
+	r.Features.Userhash = r.Features.Userhash || f.Userhash
+	r.Features.AlternateDomain = r.Features.AlternateDomain || f.AlternateDomain
+	r.Features.Software = r.Software
+}
+
+// inspectTransport performs Binding transaction over network, returning
+// client and copy of response if server responded.
+func inspectTransport(ctx context.Context, network, addr string) (*Client, *Message, time.Duration, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	c, err := NewClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, 0, err
+	}
+	start := time.Now()
+	res, err := inspectDo(ctx, c, MustBuild(TransactionID, BindingRequest))
+	if err != nil {
+		_ = c.Close()
+		return nil, nil, 0, err
+	}
+	return c, res, time.Since(start), nil
+}
+
+// inspectTimeout is timeout of transaction of Inspect, so UDP requests
+// are sent at 0, 500 and 1500 ms.
+const inspectTimeout = time.Millisecond * 3500
+
+// inspectDo performs transaction with m on c, returning copy of
+// response.
+func inspectDo(ctx context.Context, c *Client, m *Message) (*Message, error) {
+	var (
+		res    *Message
+		resErr error
+	)
+	timeoutCtx, cancel := context.WithTimeout(ctx, inspectTimeout)
+	defer cancel()
+	if err := c.DoCtx(timeoutCtx, m, func(e Event) {
+		if e.Error != nil {
+			resErr = e.Error
+			return
+		}
+		res = new(Message)
+		e.Message.CloneTo(res)
+	}); err != nil {
+		return nil, err
+	}
+	if resErr == context.DeadlineExceeded && ctx.Err() == nil {
+		return nil, ErrTransactionTimeOut
+	}
+	return res, resErr
+}
@@ -0,0 +1,84 @@
+package stun
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func serveInspect(t *testing.T, options ...ServerOption) (string, func()) {
+	s, err := NewServer(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := conn.LocalAddr().String()
+	l, err := net.Listen("tcp4", addr)
+	if err != nil {
+		_ = conn.Close()
+		t.Skip("unable to listen on TCP port of UDP:", err)
+	}
+	go func() {
+		_ = s.ServePacket(conn)
+	}()
+	go func() {
+		_ = s.Serve(l)
+	}()
+	return addr, func() {
+		_ = conn.Close()
+		_ = l.Close()
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("STUN", func(t *testing.T) {
+		addr, closer := serveInspect(t, WithSoftware("inspect"))
+		defer closer()
+		r, err := Inspect(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.Supports("udp") || !r.Supports("tcp") || r.Latency() <= 0 {
+			t.Errorf("unexpected transports: %+v", r.Transports)
+		}
+		if r.Software != "inspect" || r.Features.Software != "inspect" {
+			t.Errorf("unexpected software: %q", r.Software)
+		}
+		if r.TURN || r.BindingAuth || r.LongTermAuth || r.RFC5780 {
+			t.Errorf("unexpected report: %s", r)
+		}
+	})
+	t.Run("TURN", func(t *testing.T) {
+		addr, closer := serveInspect(t, WithAuth("realm", func(username, realm string) ([]byte, bool) {
+			return nil, false
+		}))
+		defer closer()
+		r, err := Inspect(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.TURN || !r.BindingAuth || !r.LongTermAuth || r.Realm != "realm" {
+			t.Errorf("unexpected report: %s", r)
+		}
+	})
+	t.Run("Unreachable", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		defer cancel()
+		r, err := Inspect(ctx, conn.LocalAddr().String())
+		if err != context.DeadlineExceeded {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if r.Supports("udp") || r.Supports("tcp") || r.Latency() != 0 || r.String() != "<nil>" {
+			t.Errorf("unexpected report: %s", r)
+		}
+	})
+}
@@ -1,8 +1,8 @@
 package stun
 
 import (
-	"crypto/md5"  // #nosec
 	"crypto/sha1" // #nosec
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"strings"
@@ -17,18 +17,30 @@ const credentialsSep = ":"
 // credentials. Password, username, and realm must be SASL-prepared.
 func NewLongTermIntegrity(username, realm, password string) MessageIntegrity {
 	k := strings.Join([]string{username, realm, password}, credentialsSep)
-	// #nosec
-	h := md5.New()
+	h := newMD5()
 	fmt.Fprint(h, k)
 	return MessageIntegrity(h.Sum(nil))
 }
 
 // NewShortTermIntegrity returns new MessageIntegrity with key for short-term
-// credentials. Password must be SASL-prepared.
+// credentials. Password must be SASL-prepared, see
+// NewShortTermIntegrityPrepared.
 func NewShortTermIntegrity(password string) MessageIntegrity {
 	return MessageIntegrity(password)
 }
 
+// NewShortTermIntegrityPrepared is NewShortTermIntegrity that prepares
+// password with SASLprep first, deriving the key as in RFC 5389
+// Section 15.4, and returns error if password has prohibited
+// characters.
+func NewShortTermIntegrityPrepared(password string) (MessageIntegrity, error) {
+	prepared, err := SASLprep(password)
+	if err != nil {
+		return nil, err
+	}
+	return NewShortTermIntegrity(prepared), nil
+}
+
 // MessageIntegrity represents MESSAGE-INTEGRITY attribute.
 //
 // AddTo and Check methods are using zero-allocation version of hmac, see
@@ -38,6 +50,11 @@ func NewShortTermIntegrity(password string) MessageIntegrity {
 type MessageIntegrity []byte
 
 func newHMAC(key, message, buf []byte) []byte {
+	if hashes.SHA1 != nil {
+		mac := hmac.New(hashes.SHA1, key)
+		writeOrPanic(mac, message)
+		return mac.Sum(buf)
+	}
 	mac := hmac.AcquireSHA1(key)
 	writeOrPanic(mac, message)
 	defer hmac.PutSHA1(mac)
@@ -54,6 +71,11 @@ const messageIntegritySize = 20
 // message, so MESSAGE-INTEGRITY attribute cannot be added.
 var ErrFingerprintBeforeIntegrity = errors.New("FINGERPRINT before MESSAGE-INTEGRITY attribute")
 
+// ErrIntegritySHA256BeforeIntegrity means that MESSAGE-INTEGRITY-SHA256
+// attribute is already in message, so MESSAGE-INTEGRITY attribute cannot
+// be added.
+var ErrIntegritySHA256BeforeIntegrity = errors.New("MESSAGE-INTEGRITY-SHA256 before MESSAGE-INTEGRITY attribute")
+
 // AddTo adds MESSAGE-INTEGRITY attribute to message.
 //
 // CPU costly, see BenchmarkMessageIntegrity_AddTo.
@@ -64,6 +86,10 @@ func (i MessageIntegrity) AddTo(m *Message) error {
 		if a.Type == AttrFingerprint {
 			return ErrFingerprintBeforeIntegrity
 		}
+		// MESSAGE-INTEGRITY-SHA256 should follow MESSAGE-INTEGRITY.
+		if a.Type == AttrMessageIntegritySHA256 {
+			return ErrIntegritySHA256BeforeIntegrity
+		}
 	}
 	// The text used as input to HMAC is the STUN message,
 	// including the header, up to and including the attribute preceding the
@@ -117,8 +143,127 @@ func (i MessageIntegrity) Check(m *Message) error {
 	// startOfHMAC should be first byte of integrity attribute.
 	startOfHMAC := messageHeaderSize + m.Length - (attributeHeaderSize + messageIntegritySize)
 	b := m.Raw[:startOfHMAC] // data before integrity attribute
-	expected := newHMAC(i, b, m.Raw[len(m.Raw):])
+	// Using pooled buffer for computed HMAC to prevent allocations.
+	buf := bufferPool.Get().(*buffer)
+	defer bufferPool.Put(buf)
+	expected := newHMAC(i, b, buf.buf[:0])
 	m.Length = length
 	m.WriteLength() // writing length back
 	return checkHMAC(v, expected)
 }
+
+// Recompute removes MESSAGE-INTEGRITY, MESSAGE-INTEGRITY-SHA256 and
+// FINGERPRINT attributes from m and adds them again in that order,
+// computed over current message attributes with the same key.
+// MESSAGE-INTEGRITY-SHA256 and FINGERPRINT are added only if they
+// were present.
+//
+// Useful for proxies that add or remove attributes of parsed message.
+func (i MessageIntegrity) Recompute(m *Message) error {
+	fingerprint := m.Contains(AttrFingerprint)
+	withSHA256 := m.Contains(AttrMessageIntegritySHA256)
+	m.Remove(AttrFingerprint)
+	m.Remove(AttrMessageIntegritySHA256)
+	m.Remove(AttrMessageIntegrity)
+	if err := i.AddTo(m); err != nil {
+		return err
+	}
+	if withSHA256 {
+		if err := MessageIntegritySHA256(i).AddTo(m); err != nil {
+			return err
+		}
+	}
+	if !fingerprint {
+		return nil
+	}
+	return Fingerprint.AddTo(m)
+}
+
+// MessageIntegritySHA256 represents MESSAGE-INTEGRITY-SHA256 attribute,
+// the HMAC-SHA256 of message. Key is the same as for MessageIntegrity,
+// so it can be obtained by conversion, e.g.
+//
+//	MessageIntegritySHA256(NewShortTermIntegrity(password))
+//
+// If both attributes are used, MESSAGE-INTEGRITY should be added first.
+//
+// RFC 8489 Section 14.6
+type MessageIntegritySHA256 []byte
+
+const (
+	messageIntegritySHA256Size    = sha256.Size
+	messageIntegritySHA256MinSize = 16
+)
+
+func newHMACSHA256(key, message, buf []byte) []byte {
+	if hashes.SHA256 != nil {
+		mac := hmac.New(hashes.SHA256, key)
+		writeOrPanic(mac, message)
+		return mac.Sum(buf)
+	}
+	mac := hmac.AcquireSHA256(key)
+	writeOrPanic(mac, message)
+	defer hmac.PutSHA256(mac)
+	return mac.Sum(buf)
+}
+
+func (i MessageIntegritySHA256) String() string {
+	return fmt.Sprintf("KEY: 0x%x", []byte(i))
+}
+
+// AddTo adds MESSAGE-INTEGRITY-SHA256 attribute to message. The value
+// is not truncated.
+func (i MessageIntegritySHA256) AddTo(m *Message) error {
+	for _, a := range m.Attributes {
+		if a.Type == AttrFingerprint {
+			return ErrFingerprintBeforeIntegrity
+		}
+	}
+	length := m.Length
+	m.Length += messageIntegritySHA256Size + attributeHeaderSize
+	m.WriteLength()
+	v := newHMACSHA256(i, m.Raw, m.Raw[len(m.Raw):])
+	m.Length = length
+
+	vBuf := make([]byte, sha256.Size)
+	copy(vBuf, v)
+
+	m.Add(AttrMessageIntegritySHA256, vBuf)
+	return nil
+}
+
+// Check checks MESSAGE-INTEGRITY-SHA256 attribute. Value truncated to
+// at least 16 bytes (in multiples of 4) is accepted.
+func (i MessageIntegritySHA256) Check(m *Message) error {
+	v, err := m.Get(AttrMessageIntegritySHA256)
+	if err != nil {
+		return err
+	}
+	if len(v) < messageIntegritySHA256MinSize || len(v) > messageIntegritySHA256Size || len(v)%4 != 0 {
+		return CheckSize(AttrMessageIntegritySHA256, len(v), messageIntegritySHA256Size)
+	}
+	var (
+		length         = m.Length
+		afterIntegrity = false
+		sizeReduced    int
+	)
+	for _, a := range m.Attributes {
+		if afterIntegrity {
+			sizeReduced += nearestPaddedValueLength(int(a.Length))
+			sizeReduced += attributeHeaderSize
+		}
+		if a.Type == AttrMessageIntegritySHA256 {
+			afterIntegrity = true
+		}
+	}
+	m.Length -= uint32(sizeReduced)
+	m.WriteLength()
+	startOfHMAC := messageHeaderSize + m.Length - uint32(attributeHeaderSize+len(v))
+	b := m.Raw[:startOfHMAC]
+	buf := bufferPool.Get().(*buffer)
+	defer bufferPool.Put(buf)
+	expected := newHMACSHA256(i, b, buf.buf[:0])
+	m.Length = length
+	m.WriteLength()
+	return checkHMAC(v, expected[:len(v)])
+}
@@ -65,6 +65,31 @@ func TestMessageIntegrityWithFingerprint(t *testing.T) {
 	}
 }
 
+func TestNewShortTermIntegrityPrepared(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		password string
+		key      string
+		err      error
+	}{
+		{"ASCII", "password", "password", nil},
+		{"MappedToNothing", "pass\u00ADword", "password", nil},
+		{"NonASCIISpace", "pass\u00A0word", "pass word", nil},
+		{"NonASCII", "pässwörd", "pässwörd", nil},
+		{"Prohibited", "pass\u0007word", "", ErrDisallowedCharacter},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			i, err := NewShortTermIntegrityPrepared(tc.password)
+			if err != tc.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err == nil && !bytes.Equal(i, NewShortTermIntegrity(tc.key)) {
+				t.Errorf("unexpected key %s", i)
+			}
+		})
+	}
+}
+
 func TestMessageIntegrity(t *testing.T) {
 	m := new(Message)
 	i := NewShortTermIntegrity("password")
@@ -88,6 +113,118 @@ func TestMessageIntegrityBeforeFingerprint(t *testing.T) {
 	}
 }
 
+func TestMessageIntegritySHA256(t *testing.T) {
+	var (
+		legacy    = NewShortTermIntegrity("password")
+		integrity = MessageIntegritySHA256(legacy)
+	)
+	m := MustBuild(TransactionID, BindingRequest, NewSoftware("software"),
+		legacy, integrity, Fingerprint,
+	)
+	decoded := new(Message)
+	if _, err := decoded.Write(m.Raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []Checker{legacy, integrity, Fingerprint} {
+		if err := c.Check(decoded); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := MessageIntegritySHA256("wrong").Check(decoded); err == nil {
+		t.Error("should fail with wrong key")
+	}
+	if _, err := Build(BindingRequest, integrity, legacy); err != ErrIntegritySHA256BeforeIntegrity {
+		t.Error("unexpected error", err)
+	}
+	if _, err := Build(BindingRequest, Fingerprint, integrity); err != ErrFingerprintBeforeIntegrity {
+		t.Error("unexpected error", err)
+	}
+	t.Run("Truncated", func(t *testing.T) {
+		for _, size := range []int{16, 20, 28} {
+			truncated := MustBuild(TransactionID, BindingRequest, NewSoftware("software"))
+			truncated.Length += uint32(attributeHeaderSize + size)
+			truncated.WriteLength()
+			v := newHMACSHA256(integrity, truncated.Raw, nil)
+			truncated.Length -= uint32(attributeHeaderSize + size)
+			truncated.Add(AttrMessageIntegritySHA256, v[:size])
+			if err := integrity.Check(truncated); err != nil {
+				t.Errorf("%d: %v", size, err)
+			}
+		}
+	})
+	t.Run("InvalidSize", func(t *testing.T) {
+		for _, size := range []int{0, 12, 18, 36} {
+			invalid := MustBuild(TransactionID, BindingRequest)
+			invalid.Add(AttrMessageIntegritySHA256, make([]byte, size))
+			if err := integrity.Check(invalid); !IsAttrSizeInvalid(err) {
+				t.Errorf("%d: unexpected error %v", size, err)
+			}
+		}
+	})
+}
+
+func TestMessageIntegrity_Recompute(t *testing.T) {
+	i := NewLongTermIntegrity("user", "realm", "pass")
+	m := MustBuild(TransactionID, BindingRequest, NewUsername("user"), i, Fingerprint)
+	proxied := new(Message)
+	if err := Decode(m.Raw, proxied); err != nil {
+		t.Fatal(err)
+	}
+	NewSoftware("proxy").AddTo(proxied)
+	if err := i.Recompute(proxied); err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(Message)
+	if err := Decode(proxied.Raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.Check(i, Fingerprint); err != nil {
+		t.Error(err)
+	}
+	last := decoded.Attributes[len(decoded.Attributes)-1]
+	if last.Type != AttrFingerprint {
+		t.Error("FINGERPRINT should be last, got", last.Type)
+	}
+	t.Run("NoFingerprint", func(t *testing.T) {
+		noFP := MustBuild(TransactionID, BindingRequest, NewUsername("user"))
+		if err := i.Recompute(noFP); err != nil {
+			t.Fatal(err)
+		}
+		if noFP.Contains(AttrFingerprint) {
+			t.Error("FINGERPRINT should not be added")
+		}
+		if err := i.Check(noFP); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("SHA256", func(t *testing.T) {
+		withSHA256 := MustBuild(TransactionID, BindingRequest, NewUsername("user"),
+			i, MessageIntegritySHA256(i), Fingerprint,
+		)
+		NewSoftware("proxy").AddTo(withSHA256)
+		if err := i.Recompute(withSHA256); err != nil {
+			t.Fatal(err)
+		}
+		got := new(Message)
+		if err := Decode(withSHA256.Raw, got); err != nil {
+			t.Fatal(err)
+		}
+		if err := got.Check(i, MessageIntegritySHA256(i), Fingerprint); err != nil {
+			t.Error(err)
+		}
+		var order []AttrType
+		for _, a := range got.Attributes[len(got.Attributes)-3:] {
+			order = append(order, a.Type)
+		}
+		expected := []AttrType{AttrMessageIntegrity, AttrMessageIntegritySHA256, AttrFingerprint}
+		for n := range expected {
+			if order[n] != expected[n] {
+				t.Fatalf("unexpected order of attributes %v", order)
+			}
+		}
+	})
+}
+
 func BenchmarkMessageIntegrity_AddTo(b *testing.B) {
 	m := new(Message)
 	integrity := NewShortTermIntegrity("password")
@@ -0,0 +1,108 @@
+package stun
+
+import "fmt"
+
+// IntegrityStatus is result of inspection of integrity attribute.
+type IntegrityStatus byte
+
+// Possible integrity statuses.
+const (
+	// IntegrityUnverified means that attribute is well-formed, but is
+	// not verified because key is not available.
+	IntegrityUnverified IntegrityStatus = iota
+	// IntegrityVerified means that attribute is verified with key.
+	IntegrityVerified
+	// IntegrityInvalid means that attribute is malformed or does not
+	// match key, see IntegrityInfo.Err.
+	IntegrityInvalid
+)
+
+func (s IntegrityStatus) String() string {
+	switch s {
+	case IntegrityUnverified:
+		return "unverified"
+	case IntegrityVerified:
+		return "verified"
+	case IntegrityInvalid:
+		return "invalid"
+	default:
+		return "unknown"
+	}
+}
+
+// IntegrityInfo describes MESSAGE-INTEGRITY or MESSAGE-INTEGRITY-SHA256
+// attribute of message.
+type IntegrityInfo struct {
+	Type   AttrType
+	Value  []byte // valid only until m.Raw is modified
+	Status IntegrityStatus
+	Err    error // reason of IntegrityInvalid
+}
+
+func (i IntegrityInfo) String() string {
+	if i.Err != nil {
+		return fmt.Sprintf("%s: %s (%s)", i.Type, i.Status, i.Err)
+	}
+	return fmt.Sprintf("%s: 0x%x (%s)", i.Type, i.Value, i.Status)
+}
+
+// InspectIntegrity parses integrity attributes of m, returning info for
+// each of them in order of appearance. If key is nil, e.g. analysis of
+// captures without credentials, only size and placement of attributes
+// are checked and well-formed ones are reported as IntegrityUnverified
+// instead of failing. Otherwise both attributes are verified with key.
+func InspectIntegrity(m *Message, key []byte) []IntegrityInfo {
+	var (
+		infos       []IntegrityInfo
+		fingerprint bool
+		sha256      bool
+	)
+	for _, a := range m.Attributes {
+		switch a.Type {
+		case AttrFingerprint:
+			fingerprint = true
+			continue
+		case AttrMessageIntegrity, AttrMessageIntegritySHA256:
+		default:
+			continue
+		}
+		info := IntegrityInfo{Type: a.Type, Value: a.Value}
+		sha256 = sha256 || a.Type == AttrMessageIntegritySHA256
+		switch {
+		case fingerprint:
+			info.Err = ErrFingerprintBeforeIntegrity
+		case a.Type == AttrMessageIntegrity && sha256:
+			info.Err = ErrIntegritySHA256BeforeIntegrity
+		default:
+			info.Err = checkIntegritySize(a)
+		}
+		if info.Err == nil && key != nil {
+			if a.Type == AttrMessageIntegrity {
+				info.Err = MessageIntegrity(key).Check(m)
+			} else {
+				info.Err = MessageIntegritySHA256(key).Check(m)
+			}
+			if info.Err == nil {
+				info.Status = IntegrityVerified
+			}
+		}
+		if info.Err != nil {
+			info.Status = IntegrityInvalid
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// checkIntegritySize returns error if value of integrity attribute a
+// has invalid size.
+func checkIntegritySize(a RawAttribute) error {
+	n := len(a.Value)
+	if a.Type == AttrMessageIntegrity {
+		return CheckSize(a.Type, n, messageIntegritySize)
+	}
+	if n < messageIntegritySHA256MinSize || n > messageIntegritySHA256Size || n%4 != 0 {
+		return CheckSize(a.Type, n, messageIntegritySHA256Size)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package stun
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspectIntegrity(t *testing.T) {
+	key := NewShortTermIntegrity("password")
+	m := MustBuild(TransactionID, BindingRequest, NewUsername("user"),
+		key, MessageIntegritySHA256(key), Fingerprint,
+	)
+	t.Run("Unverified", func(t *testing.T) {
+		infos := InspectIntegrity(m, nil)
+		if len(infos) != 2 {
+			t.Fatalf("unexpected infos %v", infos)
+		}
+		if infos[0].Type != AttrMessageIntegrity || infos[1].Type != AttrMessageIntegritySHA256 {
+			t.Errorf("unexpected types %v", infos)
+		}
+		for _, info := range infos {
+			if info.Status != IntegrityUnverified || info.Err != nil {
+				t.Errorf("unexpected %s", info)
+			}
+			if !strings.HasSuffix(info.String(), "(unverified)") {
+				t.Errorf("unverified should be reported: %s", info)
+			}
+		}
+	})
+	t.Run("Verified", func(t *testing.T) {
+		for _, info := range InspectIntegrity(m, key) {
+			if info.Status != IntegrityVerified {
+				t.Errorf("unexpected %s", info)
+			}
+		}
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		for _, info := range InspectIntegrity(m, NewShortTermIntegrity("other")) {
+			if info.Status != IntegrityInvalid || info.Err == nil {
+				t.Errorf("unexpected %s", info)
+			}
+		}
+	})
+	t.Run("Malformed", func(t *testing.T) {
+		for _, tc := range []struct {
+			name string
+			m    *Message
+		}{
+			{"Size", MustBuild(TransactionID, BindingRequest, RawAttribute{Type: AttrMessageIntegrity, Value: make([]byte, 10)})},
+			{"SHA256Size", MustBuild(TransactionID, BindingRequest, RawAttribute{Type: AttrMessageIntegritySHA256, Value: make([]byte, 18)})},
+			{"AfterFingerprint", MustBuild(TransactionID, BindingRequest, Fingerprint, RawAttribute{Type: AttrMessageIntegrity, Value: make([]byte, 20)})},
+			{"AfterSHA256", MustBuild(TransactionID, BindingRequest, MessageIntegritySHA256(key), RawAttribute{Type: AttrMessageIntegrity, Value: make([]byte, 20)})},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				infos := InspectIntegrity(tc.m, nil)
+				if len(infos) == 0 {
+					t.Fatal("no infos")
+				}
+				if last := infos[len(infos)-1]; last.Status != IntegrityInvalid || last.Err == nil {
+					t.Errorf("unexpected %s", last)
+				}
+			})
+		}
+	})
+	if infos := InspectIntegrity(MustBuild(TransactionID, BindingRequest), nil); len(infos) != 0 {
+		t.Errorf("unexpected infos %v", infos)
+	}
+}
+
+func TestIntegrityStatus_String(t *testing.T) {
+	for s, v := range map[IntegrityStatus]string{
+		IntegrityUnverified: "unverified",
+		IntegrityVerified:   "verified",
+		IntegrityInvalid:    "invalid",
+		IntegrityStatus(10): "unknown",
+	} {
+		if s.String() != v {
+			t.Errorf("%q, expected %q", s, v)
+		}
+	}
+}
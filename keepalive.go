@@ -0,0 +1,160 @@
+package stun
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultKeepaliveInterval is default interval between keepalives,
+// which is less than common timeout of UDP NAT bindings.
+//
+// RFC 8489 Section 5
+const DefaultKeepaliveInterval = time.Second * 15
+
+// Possible errors of keepalive control.
+var (
+	ErrKeepaliveStarted = errors.New("keepalive is already started")
+	ErrKeepaliveStopped = errors.New("keepalive is not started")
+)
+
+// Keepalive is configuration of client keepalives, see WithKeepalive.
+type Keepalive struct {
+	// Interval between Binding Indications, defaults to
+	// DefaultKeepaliveInterval.
+	Interval time.Duration
+
+	// RefreshInterval is interval between Binding requests that are
+	// sent instead of indications to refresh reflexive address, which
+	// is not reported by indications. Zero disables refreshes.
+	RefreshInterval time.Duration
+
+	// OnAddressChange is called with new XOR-MAPPED-ADDRESS if it
+	// differs from one of previous Binding response of client, e.g.
+	// after NAT rebinding. Responses to requests of Client.Do are
+	// observed too.
+	OnAddressChange func(addr XORMappedAddress)
+}
+
+// WithKeepalive enables sending of periodic Binding Indications to
+// server to keep NAT bindings alive. Keepalives are started by NewClient
+// and can be controlled by Client.StopKeepalive and
+// Client.StartKeepalive. Client with running keepalives is not garbage
+// collected, so it should be closed.
+func WithKeepalive(k Keepalive) ClientOption {
+	return func(c *Client) {
+		c.keepalive.config = k
+		c.keepalive.autostart = true
+	}
+}
+
+// keepalive is state of client keepalives.
+type keepalive struct {
+	config    Keepalive
+	autostart bool
+
+	mux   sync.Mutex // guards fields below
+	stop  chan struct{}
+	done  chan struct{}
+	addr  XORMappedAddress
+	known bool // addr is set
+}
+
+func (k *keepalive) validate() error {
+	switch {
+	case k.config.Interval < 0:
+		return OptionErr{Option: "KeepaliveInterval", Value: k.config.Interval}
+	case k.config.RefreshInterval < 0:
+		return OptionErr{Option: "KeepaliveRefreshInterval", Value: k.config.RefreshInterval}
+	}
+	if k.config.Interval == 0 {
+		k.config.Interval = DefaultKeepaliveInterval
+	}
+	return nil
+}
+
+// StartKeepalive starts sending keepalives with configuration of
+// WithKeepalive or with default one.
+func (c *Client) StartKeepalive() error {
+	if err := c.checkInit(); err != nil {
+		return err
+	}
+	k := &c.keepalive
+	k.mux.Lock()
+	defer k.mux.Unlock()
+	// Checking under k.mux, so Close, which sets closed before
+	// stopping keepalive, always stops it.
+	c.mux.RLock()
+	closed := c.closed
+	c.mux.RUnlock()
+	if closed {
+		return ErrClientClosed
+	}
+	if k.stop != nil {
+		return ErrKeepaliveStarted
+	}
+	k.stop, k.done = make(chan struct{}), make(chan struct{})
+	go c.keepaliveLoop(k.config, k.stop, k.done)
+	return nil
+}
+
+// StopKeepalive stops sending keepalives, waiting until keepalive that
+// is being sent is complete.
+func (c *Client) StopKeepalive() error {
+	k := &c.keepalive
+	k.mux.Lock()
+	stop, done := k.stop, k.done
+	k.stop, k.done = nil, nil
+	k.mux.Unlock()
+	if stop == nil {
+		return ErrKeepaliveStopped
+	}
+	close(stop)
+	<-done
+	return nil
+}
+
+func (c *Client) keepaliveLoop(config Keepalive, stop, done chan struct{}) {
+	defer close(done)
+	lastRefresh := c.clock.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-after(c.clock, config.Interval):
+		}
+		var (
+			m   *Message
+			err error
+		)
+		if now := c.clock.Now(); config.RefreshInterval > 0 && now.Sub(lastRefresh) >= config.RefreshInterval {
+			lastRefresh = now
+			if m, err = Build(c.ids, BindingRequest); err == nil {
+				// Response is observed by detectAddress.
+				err = c.Start(m, func(Event) {})
+			}
+		} else if m, err = Build(c.ids, bindingIndication); err == nil {
+			err = c.Indicate(m)
+		}
+		if err == ErrClientClosed {
+			return
+		}
+	}
+}
+
+// detectAddress calls OnAddressChange if reflexive address of Binding
+// response m differs from previous one.
+func (c *Client) detectAddress(m *Message) {
+	var addr XORMappedAddress
+	if err := addr.GetFrom(m); err != nil {
+		return
+	}
+	k := &c.keepalive
+	k.mux.Lock()
+	changed := k.known && !(k.addr.IP.Equal(addr.IP) && k.addr.Port == addr.Port)
+	k.addr, k.known = addr, true
+	k.mux.Unlock()
+	if changed {
+		k.config.OnAddressChange(addr)
+	}
+}
@@ -0,0 +1,149 @@
+package stun
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClient_Keepalive(t *testing.T) {
+	connL, connR := net.Pipe()
+	defer connL.Close()
+	changes := make(chan XORMappedAddress, 10)
+	c, err := NewClient(connR, WithKeepalive(Keepalive{
+		Interval:        time.Millisecond * 5,
+		RefreshInterval: time.Millisecond * 20,
+		OnAddressChange: func(addr XORMappedAddress) {
+			changes <- addr
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	indications := make(chan struct{}, 100)
+	go func() {
+		var (
+			buf  = make([]byte, 1500)
+			port = 1000
+		)
+		for {
+			n, readErr := connL.Read(buf)
+			if readErr != nil {
+				return
+			}
+			m := new(Message)
+			if _, decodeErr := m.Write(buf[:n]); decodeErr != nil {
+				t.Error(decodeErr)
+				return
+			}
+			switch m.Type {
+			case bindingIndication:
+				select {
+				case indications <- struct{}{}:
+				default:
+				}
+			case BindingRequest:
+				// Reflexive address changes on every refresh.
+				port++
+				res := MustBuild(m, BindingSuccess, &XORMappedAddress{IP: net.IPv4(1, 2, 3, 4), Port: port})
+				if _, writeErr := connL.Write(res.Raw); writeErr != nil {
+					return
+				}
+			}
+		}
+	}()
+	select {
+	case <-indications:
+	case <-time.After(time.Second):
+		t.Fatal("no keepalive")
+	}
+	select {
+	case addr := <-changes:
+		if addr.Port < 1002 {
+			t.Errorf("first address should not be reported as change: %s", addr)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("no address change")
+	}
+	if err = c.StartKeepalive(); err != ErrKeepaliveStarted {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err = c.StopKeepalive(); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.StopKeepalive(); err != ErrKeepaliveStopped {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err = c.StartKeepalive(); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.StartKeepalive(); err != ErrClientClosed {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestWithKeepalive_Invalid(t *testing.T) {
+	for _, k := range []Keepalive{
+		{Interval: -time.Second},
+		{RefreshInterval: -time.Second},
+	} {
+		conn, _ := net.Pipe()
+		if _, err := NewClient(conn, WithKeepalive(k)); err == nil {
+			t.Errorf("%+v should be invalid", k)
+		}
+		conn.Close()
+	}
+	c := &Client{}
+	WithKeepalive(Keepalive{})(c)
+	if err := c.keepalive.validate(); err != nil || c.keepalive.config.Interval != DefaultKeepaliveInterval {
+		t.Errorf("unexpected interval %s, %v", c.keepalive.config.Interval, err)
+	}
+}
+
+func TestClient_KeepaliveClock(t *testing.T) {
+	connL, connR := net.Pipe()
+	defer connL.Close()
+	clock := &timerClock{current: time.Now()}
+	c, err := NewClient(connR,
+		WithClock(clock),
+		WithKeepalive(Keepalive{Interval: time.Minute}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
+	indications := make(chan MessageType, 10)
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, readErr := connL.Read(buf)
+			if readErr != nil {
+				return
+			}
+			m := new(Message)
+			if _, decodeErr := m.Write(buf[:n]); decodeErr == nil {
+				indications <- m.Type
+			}
+		}
+	}()
+	// Timers of default collector are pending too, so time is moved
+	// until keepalives are sent.
+	for sent := 0; sent < 3; {
+		select {
+		case typ := <-indications:
+			if typ != bindingIndication {
+				t.Errorf("unexpected type: %s", typ)
+			}
+			sent++
+		default:
+			clock.Add(time.Second * 10)
+		}
+	}
+}
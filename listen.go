@@ -0,0 +1,128 @@
+package stun
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+)
+
+// Defaults of servers that are started by Listen and ListenTLS.
+const (
+	listenSoftware  = "pion/stun"
+	listenRateLimit = 20 // messages per second of each source IP
+	listenRateBurst = 50
+)
+
+// listenOptions returns default options of Listen and ListenTLS with
+// handler h followed by options, so they can override defaults.
+func listenOptions(h ServerHandler, options []ServerOption) []ServerOption {
+	defaults := []ServerOption{
+		WithFingerprint,
+		WithSoftware(listenSoftware),
+		WithRateLimit(listenRateLimit, listenRateBurst),
+	}
+	if h != nil {
+		defaults = append(defaults, WithServerHandler(h))
+	}
+	return append(defaults, options...)
+}
+
+// Listen listens on network and address like net.Listen or
+// net.ListenPacket and serves messages in background with handler h,
+// which can be nil to serve only Binding requests, until Close or
+// Shutdown of returned server. Network is "udp", "udp4", "udp6", "tcp",
+// "tcp4" or "tcp6". If address has no port, DefaultPort is used, use
+// Server.Addrs to get address of ":0" listener.
+//
+// Server adds FINGERPRINT to responses and SOFTWARE to Binding
+// responses, and limits rate of messages from each source IP to 20 per
+// second with burst of 50. Options are applied after the defaults, so
+// they can override them. Errors of serving are logged to error log,
+// see WithServerErrorLog.
+func Listen(network, address string, h ServerHandler, options ...ServerOption) (*Server, error) {
+	s, err := NewServer(listenOptions(h, options)...)
+	if err != nil {
+		return nil, err
+	}
+	address = withDefaultPort(address, DefaultPort)
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		l, listenErr := net.Listen(network, address)
+		if listenErr != nil {
+			return nil, listenErr
+		}
+		s.serveInBackground(l, func() error { return s.serve(l) })
+	default:
+		conn, listenErr := net.ListenPacket(network, address)
+		if listenErr != nil {
+			return nil, listenErr
+		}
+		s.serveInBackground(conn, func() error { return s.servePacket(conn) })
+	}
+	return s, nil
+}
+
+// ListenTLS is Listen for TLS over TCP with config, which should
+// contain server certificates. If address has no port, DefaultTLSPort
+// is used.
+func ListenTLS(address string, config *tls.Config, h ServerHandler, options ...ServerOption) (*Server, error) {
+	s, err := NewServer(listenOptions(h, options)...)
+	if err != nil {
+		return nil, err
+	}
+	l, err := tls.Listen("tcp", withDefaultPort(address, DefaultTLSPort), config)
+	if err != nil {
+		return nil, err
+	}
+	s.serveInBackground(l, func() error { return s.serve(l) })
+	return s, nil
+}
+
+// withDefaultPort returns address with port if it has none.
+func withDefaultPort(address string, port int) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, strconv.Itoa(port))
+	}
+	return address
+}
+
+// serveInBackground tracks c and calls serve in goroutine, logging
+// errors other than ErrServerClosed.
+func (s *Server) serveInBackground(c io.Closer, serve func() error) {
+	if !s.track(c) {
+		return
+	}
+	go func() {
+		defer s.untrack(c)
+		if err := serve(); err != nil && err != ErrServerClosed {
+			printf := log.Printf
+			if s.errorLog != nil {
+				printf = s.errorLog.Printf
+			}
+			printf("server: serving stopped: %v", err)
+		}
+	}()
+}
+
+// Addrs returns local addresses of listeners and packet conns that are
+// served, sorted by string representation.
+func (s *Server) Addrs() []net.Addr {
+	s.mux.Lock()
+	var addrs []net.Addr
+	for c := range s.closers {
+		switch v := c.(type) {
+		case net.Listener:
+			addrs = append(addrs, v.Addr())
+		case net.PacketConn:
+			addrs = append(addrs, v.LocalAddr())
+		}
+	}
+	s.mux.Unlock()
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].String() < addrs[j].String()
+	})
+	return addrs
+}
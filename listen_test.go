@@ -0,0 +1,69 @@
+package stun
+
+import "testing"
+
+func TestListen(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfig(t)
+	for _, network := range []string{"udp", "tcp", "tls"} {
+		t.Run(network, func(t *testing.T) {
+			var (
+				s   *Server
+				err error
+			)
+			if network == "tls" {
+				s, err = ListenTLS("127.0.0.1:0", serverConfig, nil)
+			} else {
+				s, err = Listen(network+"4", "127.0.0.1:0", nil)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			addrs := s.Addrs()
+			if len(addrs) != 1 {
+				t.Fatalf("unexpected addrs %v", addrs)
+			}
+			var c *Client
+			if network == "tls" {
+				c, err = DialTLS(addrs[0].String(), clientConfig)
+			} else {
+				c, err = Dial(network, addrs[0].String())
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if err = c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+				if e.Error != nil {
+					t.Error(e.Error)
+					return
+				}
+				var software Software
+				if getErr := software.GetFrom(e.Message); getErr != nil || software.String() != listenSoftware {
+					t.Errorf("unexpected software %q: %v", software, getErr)
+				}
+				if checkErr := Fingerprint.Check(e.Message); checkErr != nil {
+					t.Error(checkErr)
+				}
+			}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	t.Run("Options", func(t *testing.T) {
+		s, err := Listen("udp4", "127.0.0.1:0", nil, WithSoftware("custom"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if s.software.String() != "custom" || s.limiter.rate != listenRateLimit {
+			t.Errorf("unexpected options %+v", s)
+		}
+		if _, err = Listen("udp4", "127.0.0.1:0", nil, WithRateLimit(-1, 0)); err == nil {
+			t.Error("should error")
+		}
+	})
+	if got := withDefaultPort("127.0.0.1", DefaultPort); got != "127.0.0.1:3478" {
+		t.Errorf("unexpected address %s", got)
+	}
+}
@@ -0,0 +1,62 @@
+package stun
+
+import "sync/atomic"
+
+// WithMemoryLimit limits approximate memory in bytes that is used by
+// buffers of stream connections and by state of each source IP in rate
+// limiter and quotas. Stream connections beyond the limit are closed
+// after accept, messages from new source IPs are treated as rate
+// limited, and requests that would take quota beyond the limit are
+// rejected with 500 (Server Error) or 486 (Allocation Quota Reached).
+// Buffers of packet conns are accounted, but not limited, because
+// their count is controlled by application. Use StatsSnapshot.Memory
+// to monitor usage. Zero means no limit.
+func WithMemoryLimit(bytes int64) ServerOption {
+	return func(s *Server) {
+		s.stats.memory.limit = bytes
+	}
+}
+
+// Approximate memory of server state, including overhead of maps.
+const (
+	rateBucketMemory = 64 // key and tokenBucket
+	quotaMemory      = 48 // key and count of source IP
+	tupleMemory      = 32 // 5-tuple of allocation, without string bytes
+)
+
+// connMemory returns approximate memory of conn that is served with
+// buffer of size, i.e. buffer and request, response and raw messages.
+func connMemory(size int) int64 {
+	return 4 * int64(size)
+}
+
+// memoryBudget accounts approximate memory that is used by server. The
+// zero value does not limit, and nil budget does not account.
+type memoryBudget struct {
+	used  int64 // first to be 64-bit aligned
+	limit int64
+}
+
+// reserve accounts n bytes, returning false if limit would be exceeded.
+func (b *memoryBudget) reserve(n int64) bool {
+	if b == nil {
+		return true
+	}
+	if used := atomic.AddInt64(&b.used, n); b.limit > 0 && used > b.limit {
+		atomic.AddInt64(&b.used, -n)
+		return false
+	}
+	return true
+}
+
+// add accounts n bytes regardless of limit.
+func (b *memoryBudget) add(n int64) {
+	if b != nil {
+		atomic.AddInt64(&b.used, n)
+	}
+}
+
+// free returns n bytes that are accounted by reserve or add.
+func (b *memoryBudget) free(n int64) {
+	b.add(-n)
+}
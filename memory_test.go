@@ -0,0 +1,133 @@
+package stun
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	b := &memoryBudget{limit: 100}
+	if !b.reserve(60) || b.reserve(60) {
+		t.Error("reserve should respect limit")
+	}
+	b.add(60)
+	if b.used != 120 {
+		t.Errorf("unexpected usage %d", b.used)
+	}
+	b.free(120)
+	if !b.reserve(100) {
+		t.Error("freed memory should be reserved")
+	}
+	var unlimited *memoryBudget
+	if !unlimited.reserve(1 << 40) {
+		t.Error("nil budget should not limit")
+	}
+	unlimited.free(1)
+}
+
+func TestServer_MemoryLimit(t *testing.T) {
+	var (
+		addr  = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+		other = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 3478}
+		res   = new(Message)
+		code  ErrorCodeAttribute
+	)
+	t.Run("Allocations", func(t *testing.T) {
+		allocation := tupleMemory + quotaMemory + int64(len(fiveTuple(addr)))
+		s := newTestServer(t,
+			WithAllocationLimit(10),
+			WithMemoryLimit(allocation),
+			WithServerHandler(func(res, req *Message, a net.Addr) {
+				_ = res.Build(req, allocateSuccess)
+			}),
+		)
+		req := MustBuild(TransactionID, allocateRequest)
+		if !s.process(res, new(Message), req.Raw, addr) || res.Type != allocateSuccess {
+			t.Fatalf("unexpected response %s", res)
+		}
+		if memory := s.Stats().Snapshot().Memory; memory != allocation {
+			t.Errorf("unexpected memory %d, expected %d", memory, allocation)
+		}
+		if !s.process(res, new(Message), req.Raw, other) {
+			t.Fatal("no response")
+		}
+		if err := code.GetFrom(res); err != nil || code.Code != CodeAllocQuotaReached {
+			t.Errorf("unexpected %s, %v", code, err)
+		}
+		s.ReleaseAllocation(addr)
+		if memory := s.Stats().Snapshot().Memory; memory != 0 {
+			t.Errorf("memory should be freed, got %d", memory)
+		}
+		if !s.process(res, new(Message), req.Raw, other) || res.Type != allocateSuccess {
+			t.Errorf("unexpected response %s", res)
+		}
+	})
+	t.Run("Transactions", func(t *testing.T) {
+		s := newTestServer(t, WithTransactionLimit(10), WithMemoryLimit(quotaMemory-1))
+		if !s.process(res, new(Message), MustBuild(TransactionID, BindingRequest).Raw, addr) {
+			t.Fatal("no response")
+		}
+		if err := code.GetFrom(res); err != nil || code.Code != CodeServerError {
+			t.Errorf("unexpected %s, %v", code, err)
+		}
+	})
+	t.Run("RateLimit", func(t *testing.T) {
+		clock := &manualClock{current: time.Unix(1000, 0)}
+		s := newTestServer(t,
+			WithServerClock(clock),
+			WithRateLimit(1, 10),
+			WithMemoryLimit(rateBucketMemory),
+		)
+		req := MustBuild(TransactionID, BindingRequest)
+		if !s.process(res, new(Message), req.Raw, addr) {
+			t.Fatal("no response")
+		}
+		if s.process(res, new(Message), req.Raw, other) {
+			t.Error("message from new IP should be limited")
+		}
+		// Bucket of first IP is refilled and pruned.
+		clock.Add(time.Minute)
+		if !s.process(res, new(Message), req.Raw, other) {
+			t.Error("message should be allowed after pruning")
+		}
+		if stats := s.Stats().Snapshot(); stats.Dropped != 1 || stats.Memory != rateBucketMemory {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	})
+	t.Run("Stream", func(t *testing.T) {
+		s := newTestServer(t, WithMemoryLimit(connMemory(defaultServerBufferSize)))
+		addr, _ := listenServer(t, "tcp", s)
+		c, err := Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if err = c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+			}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if err = conn.SetReadDeadline(time.Now().Add(time.Second * 5)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = conn.Read(make([]byte, 1)); err == nil {
+			t.Error("connection beyond limit should be closed")
+		} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			t.Error("connection is not closed")
+		}
+		if err = s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if memory := s.Stats().Snapshot().Memory; memory != 0 {
+			t.Errorf("memory should be freed, got %d", memory)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 )
 
 const (
@@ -26,15 +27,57 @@ const (
 )
 
 // NewTransactionID returns new random transaction ID using crypto/rand
-// as source.
+// as source, see SetTransactionIDReader.
 func NewTransactionID() (b [TransactionIDSize]byte) {
-	readFullOrPanic(rand.Reader, b[:])
+	if err := transactionIDs.read(&b); err != nil {
+		panic(err)
+	}
 	return b
 }
 
+// transactionIDReader is Setter that reads transaction id from r.
+// Reads are serialized, so r is not required to be safe for concurrent
+// use, e.g. math/rand source of reproducible ids in tests.
+type transactionIDReader struct {
+	mux sync.Mutex
+	r   io.Reader
+}
+
+func (t *transactionIDReader) read(id *[TransactionIDSize]byte) error {
+	t.mux.Lock()
+	_, err := io.ReadFull(t.r, id[:])
+	t.mux.Unlock()
+	return err
+}
+
+// AddTo sets m.TransactionID to value from r.
+func (t *transactionIDReader) AddTo(m *Message) error {
+	if err := t.read(&m.TransactionID); err != nil {
+		return err
+	}
+	m.WriteTransactionID()
+	return nil
+}
+
+// transactionIDs is package source of transaction ids.
+var transactionIDs = &transactionIDReader{r: rand.Reader}
+
+// SetTransactionIDReader sets source of transaction ids for
+// NewTransactionID, Message.NewTransactionID and TransactionID setter,
+// e.g. own CSPRNG or deterministic reader for reproducible tests. Nil r
+// resets source to crypto/rand. See also WithTransactionIDReader.
+func SetTransactionIDReader(r io.Reader) {
+	if r == nil {
+		r = rand.Reader
+	}
+	transactionIDs.mux.Lock()
+	transactionIDs.r = r
+	transactionIDs.mux.Unlock()
+}
+
 // IsMessage returns true if b looks like STUN message.
-// Useful for multiplexing. IsMessage does not guarantee
-// that decoding will be successful.
+// Useful for multiplexing, see also Classify. IsMessage does not
+// guarantee that decoding will be successful.
 func IsMessage(b []byte) bool {
 	return len(b) >= messageHeaderSize && bin.Uint32(b[4:8]) == magicCookie
 }
@@ -47,6 +90,43 @@ func New() *Message {
 	}
 }
 
+var messagePool = &sync.Pool{
+	New: func() interface{} {
+		m := New()
+		m.Attributes = messageAttrsArena.alloc()
+		return m
+	},
+}
+
+// AcquireMessage returns Message from pool, reusing Raw and Attributes
+// buffers of previously released messages. Use ReleaseMessage to return
+// message to pool when it is no longer used.
+//
+// Attributes buffers are allocated from arena with capacity of observed
+// attribute counts, so decoding rarely grows them.
+func AcquireMessage() *Message {
+	m := messagePool.Get().(*Message)
+	if cap(m.Attributes) < messageAttrsArena.capacity() {
+		m.Attributes = messageAttrsArena.alloc()
+	}
+	return m
+}
+
+// ReleaseMessage resets m and puts it to pool. Message, its fields and
+// results of m.Get or any attribute a.GetFrom must not be used after
+// ReleaseMessage call.
+func ReleaseMessage(m *Message) {
+	messageAttrsArena.observe(len(m.Attributes))
+	if cap(m.Attributes) > maxAttrsHint {
+		// Not retaining buffers of abnormally large messages.
+		m.Attributes = nil
+	}
+	m.Reset()
+	m.Type = MessageType{}
+	m.TransactionID = [TransactionIDSize]byte{}
+	messagePool.Put(m)
+}
+
 // ErrDecodeToNil occurs on Decode(data, nil) call.
 var ErrDecodeToNil = errors.New("attempt to decode to nil message")
 
@@ -83,13 +163,9 @@ func (m *Message) AddTo(b *Message) error {
 }
 
 // NewTransactionID sets m.TransactionID to random value from crypto/rand
-// and returns error if any.
+// and returns error if any, see SetTransactionIDReader.
 func (m *Message) NewTransactionID() error {
-	_, err := io.ReadFull(rand.Reader, m.TransactionID[:])
-	if err == nil {
-		m.WriteTransactionID()
-	}
-	return err
+	return transactionIDs.AddTo(m)
 }
 
 func (m *Message) String() string {
@@ -171,6 +247,50 @@ func (m *Message) Add(t AttrType, v []byte) {
 	m.WriteLength()
 }
 
+// Remove removes all attributes with type t from message, re-encoding
+// m.Raw and adjusting m.Length. Not goroutine-safe.
+//
+// Values of attributes obtained before Remove call are invalid after it.
+func (m *Message) Remove(t AttrType) {
+	attributes := m.Attributes[:0]
+	for _, a := range m.Attributes {
+		if a.Type != t {
+			attributes = append(attributes, a)
+		}
+	}
+	m.Attributes = attributes
+	m.Encode()
+}
+
+// AttrPosition is location of encoded attribute in Message.Raw.
+type AttrPosition struct {
+	Offset int // offset of attribute header
+	Length int // length of header, value and padding
+}
+
+// ValueOffset returns offset of attribute value. Value length without
+// padding is RawAttribute.Length.
+func (p AttrPosition) ValueOffset() int {
+	return p.Offset + attributeHeaderSize
+}
+
+// Positions returns positions of attributes in m.Raw, where i-th position
+// corresponds to m.Attributes[i].
+//
+// Positions are valid only until m.Raw or m.Attributes are modified.
+func (m *Message) Positions() []AttrPosition {
+	positions := make([]AttrPosition, len(m.Attributes))
+	offset := messageHeaderSize
+	for i, a := range m.Attributes {
+		positions[i] = AttrPosition{
+			Offset: offset,
+			Length: attributeHeaderSize + nearestPaddedValueLength(int(a.Length)),
+		}
+		offset += positions[i].Length
+	}
+	return positions
+}
+
 func attrSliceEqual(a, b Attributes) bool {
 	for _, attr := range a {
 		found := false
@@ -319,6 +439,11 @@ var ErrUnexpectedHeaderEOF = errors.New("unexpected EOF: not enough bytes to rea
 
 // Decode decodes m.Raw into m.
 func (m *Message) Decode() error {
+	return m.decode(true)
+}
+
+// decode decodes m.Raw into m, checking magic cookie if cookie is true.
+func (m *Message) decode(cookie bool) error {
 	// decoding message header
 	buf := m.Raw
 	if len(buf) < messageHeaderSize {
@@ -327,11 +452,11 @@ func (m *Message) Decode() error {
 	var (
 		t        = bin.Uint16(buf[0:2])      // first 2 bytes
 		size     = int(bin.Uint16(buf[2:4])) // second 2 bytes
-		cookie   = bin.Uint32(buf[4:8])      // last 4 bytes
+		value    = bin.Uint32(buf[4:8])      // last 4 bytes
 		fullSize = messageHeaderSize + size  // len(m.Raw)
 	)
-	if cookie != magicCookie {
-		msg := fmt.Sprintf("%x is invalid magic cookie (should be %x)", cookie, magicCookie)
+	if cookie && value != magicCookie {
+		msg := fmt.Sprintf("%x is invalid magic cookie (should be %x)", value, magicCookie)
 		return newDecodeErr("message", "cookie", msg)
 	}
 	if len(buf) < fullSize {
@@ -386,10 +511,30 @@ func (m *Message) Write(tBuf []byte) (int, error) {
 }
 
 // CloneTo clones m to b securing any further m mutations.
+//
+// Attributes are copied with values re-pointed to b.Raw without decoding,
+// so m should be consistent with m.Raw, e.g. decoded or built. Otherwise
+// b is decoded from copy of m.Raw.
 func (m *Message) CloneTo(b *Message) error {
-	// TODO(ar): implement low-level copy.
 	b.Raw = append(b.Raw[:0], m.Raw...)
-	return b.Decode()
+	b.Attributes = append(b.Attributes[:0], m.Attributes...)
+	offset := messageHeaderSize
+	for i, a := range b.Attributes {
+		start := offset + attributeHeaderSize
+		end := start + int(a.Length)
+		if end > len(b.Raw) || len(a.Value) != int(a.Length) {
+			return b.Decode()
+		}
+		b.Attributes[i].Value = b.Raw[start:end:end]
+		offset = start + nearestPaddedValueLength(int(a.Length))
+	}
+	if offset != len(b.Raw) || m.Length != uint32(offset-messageHeaderSize) {
+		return b.Decode()
+	}
+	b.Type = m.Type
+	b.Length = m.Length
+	b.TransactionID = m.TransactionID
+	return nil
 }
 
 // MessageClass is 8-bit representation of 2-bit class of STUN Message Class.
@@ -584,6 +729,31 @@ func (t MessageType) String() string {
 	return fmt.Sprintf("%s %s", t.Method, t.Class)
 }
 
+// Reserved methods, 0x002 was SharedSecret of RFC 3489.
+const (
+	methodReserved     Method = 0x000
+	methodSharedSecret Method = 0x002
+)
+
+// IsValid returns false if t is reserved method or combination of known
+// method and class that is not defined, e.g. Send request or Allocate
+// indication. Unknown methods are valid with any class.
+//
+// RFC 5389 Section 18.1, RFC 5766 Section 13, RFC 6062 Section 6.1
+func (t MessageType) IsValid() bool {
+	switch t.Method {
+	case methodReserved, methodSharedSecret:
+		return false
+	case MethodSend, MethodData, MethodConnectionAttempt:
+		return t.Class == ClassIndication
+	case MethodAllocate, MethodRefresh, MethodCreatePermission, MethodChannelBind,
+		MethodConnect, MethodConnectionBind:
+		return t.Class != ClassIndication
+	default:
+		return true
+	}
+}
+
 // Contains return true if message contain t attribute.
 func (m *Message) Contains(t AttrType) bool {
 	for _, a := range m.Attributes {
@@ -594,6 +764,24 @@ func (m *Message) Contains(t AttrType) bool {
 	return false
 }
 
+// Has is alias for Contains.
+func (m *Message) Has(t AttrType) bool {
+	return m.Contains(t)
+}
+
+// Missing returns types from ts that are not present in message, in the
+// same order, or nil if message contains all of them. Allocates only if
+// some attributes are missing.
+func (m *Message) Missing(ts ...AttrType) []AttrType {
+	var missing []AttrType
+	for _, t := range ts {
+		if !m.Contains(t) {
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
+
 type transactionIDValueSetter [TransactionIDSize]byte
 
 // NewTransactionIDSetter returns new Setter that sets message transaction id
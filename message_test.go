@@ -15,6 +15,8 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/pion/stun/internal/testutil"
 )
 
 type attributeEncoder interface {
@@ -122,6 +124,29 @@ func TestMessageType_ReadWriteValue(t *testing.T) {
 	}
 }
 
+func TestMessageType_IsValid(t *testing.T) {
+	for _, tt := range []struct {
+		in    MessageType
+		valid bool
+	}{
+		{BindingRequest, true},
+		{NewType(MethodBinding, ClassIndication), true},
+		{NewType(0x000, ClassRequest), false},
+		{NewType(0x002, ClassSuccessResponse), false},
+		{NewType(MethodSend, ClassIndication), true},
+		{NewType(MethodSend, ClassRequest), false},
+		{NewType(MethodData, ClassSuccessResponse), false},
+		{NewType(MethodAllocate, ClassErrorResponse), true},
+		{NewType(MethodAllocate, ClassIndication), false},
+		{NewType(MethodConnectionAttempt, ClassIndication), true},
+		{NewType(0xb6d, ClassIndication), true},
+	} {
+		if v := tt.in.IsValid(); v != tt.valid {
+			t.Errorf("%s: IsValid() = %v, want %v", tt.in, v, tt.valid)
+		}
+	}
+}
+
 func TestMessage_WriteTo(t *testing.T) {
 	m := New()
 	m.Type = MessageType{Method: MethodBinding, Class: ClassRequest}
@@ -600,6 +625,25 @@ func TestMessageFromBrowsers(t *testing.T) {
 	}
 }
 
+func TestSetTransactionIDReader(t *testing.T) {
+	defer SetTransactionIDReader(nil)
+	SetTransactionIDReader(bytes.NewReader(make([]byte, TransactionIDSize*2)))
+	if id := NewTransactionID(); id != [TransactionIDSize]byte{} {
+		t.Errorf("unexpected id: %x", id)
+	}
+	m := MustBuild(TransactionID, BindingRequest)
+	if m.TransactionID != [TransactionIDSize]byte{} {
+		t.Errorf("unexpected id: %x", m.TransactionID)
+	}
+	if err := m.NewTransactionID(); err != io.EOF {
+		t.Errorf("unexpected error: %v", err)
+	}
+	SetTransactionIDReader(nil)
+	if id := NewTransactionID(); id == [TransactionIDSize]byte{} {
+		t.Error("unexpected zero id")
+	}
+}
+
 func BenchmarkMessage_NewTransactionID(b *testing.B) {
 	b.ReportAllocs()
 	m := new(Message)
@@ -840,6 +884,159 @@ func TestMessageFullSize(t *testing.T) {
 	}
 }
 
+func TestAllocationsCheckers(t *testing.T) {
+	integrity := NewShortTermIntegrity("pwd")
+	m := MustBuild(TransactionID, BindingSuccess,
+		&XORMappedAddress{IP: net.IPv4(11, 22, 33, 44), Port: 334},
+		integrity,
+		MessageIntegritySHA256(integrity),
+		Fingerprint,
+	)
+	for _, tc := range []struct {
+		name string
+		c    Checker
+	}{
+		{"MessageIntegrity", integrity},
+		{"MessageIntegritySHA256", MessageIntegritySHA256(integrity)},
+		{"Fingerprint", Fingerprint},
+	} {
+		c := tc.c
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.ShouldNotAllocate(t, func() {
+				if err := c.Check(m); err != nil {
+					t.Error(err)
+				}
+			})
+		})
+	}
+}
+
+func TestAcquireMessage(t *testing.T) {
+	raw := MustBuild(TransactionID, BindingRequest, NewSoftware("pion/stun"), Fingerprint).Raw
+	m := AcquireMessage()
+	if err := Decode(raw, m); err != nil {
+		t.Fatal(err)
+	}
+	ReleaseMessage(m)
+	if len(m.Raw) != 0 || len(m.Attributes) != 0 || m.Length != 0 {
+		t.Error("message should be reset")
+	}
+	if m.Type != (MessageType{}) || m.TransactionID != ([TransactionIDSize]byte{}) {
+		t.Error("header should be reset")
+	}
+	testutil.ShouldNotAllocate(t, func() {
+		pooled := AcquireMessage()
+		if err := Decode(raw, pooled); err != nil {
+			t.Fatal(err)
+		}
+		ReleaseMessage(pooled)
+	})
+}
+
+func BenchmarkAcquireMessage(b *testing.B) {
+	b.ReportAllocs()
+	raw := MustBuild(TransactionID, BindingRequest, NewSoftware("pion/stun"), Fingerprint).Raw
+	b.SetBytes(int64(len(raw)))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m := AcquireMessage()
+			if err := Decode(raw, m); err != nil {
+				b.Fatal(err)
+			}
+			ReleaseMessage(m)
+		}
+	})
+}
+
+func TestMessage_Remove(t *testing.T) {
+	m := MustBuild(TransactionID, BindingRequest,
+		NewUsername("username"),
+		NewSoftware("software"),
+		NewNonce("nonce"),
+		NewSoftware("software 2"),
+	)
+	m.Remove(AttrSoftware)
+	if m.Contains(AttrSoftware) {
+		t.Error("SOFTWARE should be removed")
+	}
+	decoded := new(Message)
+	if err := Decode(m.Raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(m) {
+		t.Error("decoded message should be equal")
+	}
+	var (
+		username Username
+		nonce    Nonce
+	)
+	if err := decoded.Parse(&username, &nonce); err != nil {
+		t.Fatal(err)
+	}
+	if username.String() != "username" || nonce.String() != "nonce" {
+		t.Error("unexpected values:", username, nonce)
+	}
+	m.Remove(AttrRealm)
+	if !decoded.Equal(m) {
+		t.Error("removing absent attribute should not change message")
+	}
+}
+
+func TestMessage_Positions(t *testing.T) {
+	m := MustBuild(TransactionID, BindingRequest,
+		NewSoftware("abc"),
+		NewUsername("user"),
+		Fingerprint,
+	)
+	decoded := new(Message)
+	if _, err := decoded.Write(m.Raw); err != nil {
+		t.Fatal(err)
+	}
+	positions := decoded.Positions()
+	if len(positions) != 3 {
+		t.Fatalf("unexpected positions count %d", len(positions))
+	}
+	for i, expected := range []AttrPosition{
+		{Offset: 20, Length: 8},
+		{Offset: 28, Length: 8},
+		{Offset: 36, Length: 8},
+	} {
+		if positions[i] != expected {
+			t.Errorf("position %d is %+v, expected %+v", i, positions[i], expected)
+		}
+	}
+	for i, a := range decoded.Attributes {
+		p := positions[i]
+		if AttrType(bin.Uint16(decoded.Raw[p.Offset:])) != a.Type {
+			t.Errorf("unexpected type at %d", p.Offset)
+		}
+		offset := p.ValueOffset()
+		if !bytes.Equal(decoded.Raw[offset:offset+int(a.Length)], a.Value) {
+			t.Errorf("unexpected value at %d", offset)
+		}
+	}
+	if last := positions[2]; last.Offset+last.Length != len(decoded.Raw) {
+		t.Error("last attribute should end at message end")
+	}
+}
+
+func TestMessage_Missing(t *testing.T) {
+	m := MustBuild(TransactionID, BindingSuccess, NewSoftware("software"), Fingerprint)
+	if !m.Has(AttrSoftware) || m.Has(AttrRealm) {
+		t.Error("unexpected Has result")
+	}
+	if missing := m.Missing(AttrSoftware, AttrFingerprint); missing != nil {
+		t.Error("nothing should be missing", missing)
+	}
+	missing := m.Missing(AttrRealm, AttrSoftware, AttrNonce)
+	if len(missing) != 2 || missing[0] != AttrRealm || missing[1] != AttrNonce {
+		t.Error("unexpected missing", missing)
+	}
+	testutil.ShouldNotAllocate(t, func() {
+		m.Missing(AttrSoftware, AttrFingerprint)
+	})
+}
+
 func TestMessage_CloneTo(t *testing.T) {
 	m := new(Message)
 	if err := m.Build(BindingRequest,
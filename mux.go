@@ -0,0 +1,199 @@
+package stun
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrMuxClosed is returned by Mux methods after Close.
+var ErrMuxClosed = errors.New("mux closed")
+
+// MuxHandler handles STUN message m from addr that is read by Mux,
+// e.g. by passing it to Agent.Process. The m is valid only until
+// handler returns.
+type MuxHandler func(m *Message, addr net.Addr)
+
+// muxQueueSize is count of non-STUN packets that are queued until read,
+// further packets are dropped like by full socket buffer.
+const muxQueueSize = 64
+
+type muxPacket struct {
+	b    []byte
+	addr net.Addr
+}
+
+// muxTimeoutErr is returned by Mux.ReadFrom if read deadline is
+// exceeded.
+type muxTimeoutErr struct{}
+
+func (muxTimeoutErr) Error() string   { return "i/o timeout" }
+func (muxTimeoutErr) Timeout() bool   { return true }
+func (muxTimeoutErr) Temporary() bool { return true }
+
+// Mux shares single datagram connection between STUN and application
+// data, e.g. like ICE does: STUN messages are routed to handler, and
+// Mux is net.PacketConn for everything else, so ReadFrom returns only
+// packets that are not STUN messages. WriteTo writes to the underlying
+// connection, so both STUN messages and data can be sent.
+type Mux struct {
+	conn    net.PacketConn
+	handler MuxHandler
+	packets chan muxPacket
+	done    chan struct{} // closed when read loop returns
+	err     error         // error of read loop, valid after done
+
+	mux        sync.Mutex // guards fields below
+	closed     bool
+	deadline   time.Time
+	deadlineCh chan struct{} // closed when deadline is changed
+}
+
+// NewMux starts reading conn, routing STUN messages to h. Close Mux to
+// stop reading and close conn.
+func NewMux(conn net.PacketConn, h MuxHandler) *Mux {
+	m := &Mux{
+		conn:       conn,
+		handler:    h,
+		packets:    make(chan muxPacket, muxQueueSize),
+		done:       make(chan struct{}),
+		deadlineCh: make(chan struct{}),
+	}
+	go m.readUntilClosed()
+	return m
+}
+
+func (m *Mux) readUntilClosed() {
+	defer close(m.done)
+	var (
+		buf = make([]byte, defaultServerBufferSize)
+		msg = new(Message)
+	)
+	for {
+		n, addr, err := m.conn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() && !ne.Timeout() {
+				continue
+			}
+			m.err = err
+			return
+		}
+		if IsMessage(buf[:n]) {
+			if _, err = msg.Write(buf[:n]); err == nil {
+				m.handler(msg, addr)
+			}
+			continue
+		}
+		select {
+		case m.packets <- muxPacket{b: append([]byte(nil), buf[:n]...), addr: addr}:
+		default:
+			// Queue is full, dropping.
+		}
+	}
+}
+
+// ReadFrom implements net.PacketConn, reading packet that is not STUN
+// message.
+func (m *Mux) ReadFrom(p []byte) (int, net.Addr, error) {
+	for {
+		m.mux.Lock()
+		closed, deadline, deadlineCh := m.closed, m.deadline, m.deadlineCh
+		m.mux.Unlock()
+		if closed {
+			return 0, nil, ErrMuxClosed
+		}
+		var (
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, nil, muxTimeoutErr{}
+			}
+			timer = time.NewTimer(d)
+			timeout = timer.C
+		}
+		n, addr, ok, err := m.read(p, timeout, deadlineCh)
+		if timer != nil {
+			timer.Stop()
+		}
+		if ok {
+			return n, addr, err
+		}
+		// Deadline is changed, waiting with new one.
+	}
+}
+
+// read waits for packet, returning false if deadlineCh is closed first.
+func (m *Mux) read(p []byte, timeout <-chan time.Time, deadlineCh <-chan struct{}) (int, net.Addr, bool, error) {
+	select {
+	case pkt := <-m.packets:
+		return copy(p, pkt.b), pkt.addr, true, nil
+	case <-m.done:
+		if m.isClosed() {
+			return 0, nil, true, ErrMuxClosed
+		}
+		return 0, nil, true, m.err
+	case <-timeout:
+		return 0, nil, true, muxTimeoutErr{}
+	case <-deadlineCh:
+		return 0, nil, false, nil
+	}
+}
+
+// WriteTo implements net.PacketConn, writing to the underlying
+// connection.
+func (m *Mux) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return m.conn.WriteTo(p, addr)
+}
+
+func (m *Mux) isClosed() bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.closed
+}
+
+// Close stops reading and closes the underlying connection.
+func (m *Mux) Close() error {
+	m.mux.Lock()
+	if m.closed {
+		m.mux.Unlock()
+		return ErrMuxClosed
+	}
+	m.closed = true
+	m.mux.Unlock()
+	err := m.conn.Close()
+	<-m.done
+	return err
+}
+
+// LocalAddr implements net.PacketConn.
+func (m *Mux) LocalAddr() net.Addr {
+	return m.conn.LocalAddr()
+}
+
+// SetDeadline implements net.PacketConn.
+func (m *Mux) SetDeadline(t time.Time) error {
+	if err := m.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return m.SetWriteDeadline(t)
+}
+
+// SetReadDeadline implements net.PacketConn. The underlying connection
+// is read by Mux continuously, so deadline applies only to ReadFrom.
+func (m *Mux) SetReadDeadline(t time.Time) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.deadline = t
+	close(m.deadlineCh)
+	m.deadlineCh = make(chan struct{})
+	return nil
+}
+
+// SetWriteDeadline implements net.PacketConn.
+func (m *Mux) SetWriteDeadline(t time.Time) error {
+	return m.conn.SetWriteDeadline(t)
+}
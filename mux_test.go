@@ -0,0 +1,120 @@
+package stun
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+var _ net.PacketConn = new(Mux)
+
+func TestMux(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(chan [TransactionIDSize]byte, 1)
+	mux := NewMux(conn, func(m *Message, addr net.Addr) {
+		messages <- m.TransactionID
+	})
+	if mux.LocalAddr() != conn.LocalAddr() {
+		t.Error("unexpected local address")
+	}
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+	req := MustBuild(TransactionID, BindingRequest)
+	for _, b := range [][]byte{req.Raw, []byte("data")} {
+		if _, err = peer.WriteTo(b, conn.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case id := <-messages:
+		if id != req.TransactionID {
+			t.Error("unexpected transaction id")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no STUN message")
+	}
+	if err = mux.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 100)
+	n, addr, err := mux.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "data" || addr.String() != peer.LocalAddr().String() {
+		t.Errorf("unexpected %q from %s", buf[:n], addr)
+	}
+	if _, err = mux.WriteTo([]byte("reply"), peer.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	if err = peer.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if n, _, err = peer.ReadFrom(buf); err != nil || string(buf[:n]) != "reply" {
+		t.Errorf("unexpected %q, %v", buf[:n], err)
+	}
+	t.Run("Deadline", func(t *testing.T) {
+		if err := mux.SetReadDeadline(time.Now().Add(time.Millisecond * 10)); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err := mux.ReadFrom(buf)
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			t.Errorf("unexpected error %v", err)
+		}
+		if _, _, err = mux.ReadFrom(buf); err == nil {
+			t.Error("deadline should be exceeded")
+		}
+	})
+	t.Run("DeadlineChange", func(t *testing.T) {
+		if err := mux.SetReadDeadline(time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		errs := make(chan error, 1)
+		go func() {
+			_, _, readErr := mux.ReadFrom(buf)
+			errs <- readErr
+		}()
+		time.Sleep(time.Millisecond * 10)
+		if err := mux.SetReadDeadline(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-errs:
+			if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+				t.Errorf("unexpected error %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("read should be interrupted")
+		}
+	})
+	if err = mux.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = mux.Close(); err != ErrMuxClosed {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, _, err = mux.ReadFrom(buf); err != ErrMuxClosed {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestMux_ReadError(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := NewMux(conn, func(m *Message, addr net.Addr) {})
+	// Closing underlying connection stops Mux.
+	if err = conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = mux.ReadFrom(make([]byte, 10)); err == nil || err == ErrMuxClosed {
+		t.Errorf("unexpected error %v", err)
+	}
+}
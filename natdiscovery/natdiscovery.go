@@ -0,0 +1,243 @@
+// Package natdiscovery implements NAT behavior discovery tests from
+// RFC 5780 on top of stun.Client.
+package natdiscovery
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+// Behavior is mapping or filtering behavior of NAT.
+//
+// RFC 4787 Section 4.1 and Section 5
+type Behavior byte
+
+// Possible behaviors.
+const (
+	BehaviorUnknown Behavior = iota
+	EndpointIndependent
+	AddressDependent
+	AddressAndPortDependent
+)
+
+func (b Behavior) String() string {
+	switch b {
+	case EndpointIndependent:
+		return "endpoint-independent"
+	case AddressDependent:
+		return "address-dependent"
+	case AddressAndPortDependent:
+		return "address and port-dependent"
+	default:
+		return "unknown"
+	}
+}
+
+// Report is result of NAT behavior discovery.
+type Report struct {
+	LocalAddr  *net.UDPAddr // local address of socket
+	MappedAddr *net.UDPAddr // XOR-MAPPED-ADDRESS of first test
+	OtherAddr  *net.UDPAddr // OTHER-ADDRESS of server
+
+	// NoNAT is true if mapped address is equal to local address.
+	// Detected only if socket is bound to specified IP address.
+	NoNAT bool
+
+	Mapping   Behavior
+	Filtering Behavior
+}
+
+// ErrNoOtherAddress means that server does not support RFC 5780 and
+// responds without OTHER-ADDRESS.
+var ErrNoOtherAddress = errors.New("no OTHER-ADDRESS in response")
+
+const defaultTimeout = 3 * time.Second
+
+// Discoverer runs RFC 5780 mapping and filtering behavior tests
+// against server that supports OTHER-ADDRESS.
+//
+// RFC 5780 Section 4.3 and Section 4.4
+type Discoverer struct {
+	// Server is address of STUN server, e.g. "stun.example.org:3478".
+	Server string
+
+	// Conn is local UDP socket to test. If nil, new socket is used.
+	// Conn is not closed and should not be read concurrently with
+	// Discover.
+	Conn net.PacketConn
+
+	// Timeout of single test, including retransmissions. Defaults to
+	// 3 seconds. Filtering tests wait for full timeout if response is
+	// filtered by NAT.
+	Timeout time.Duration
+}
+
+// Discover runs tests and returns report.
+func (d *Discoverer) Discover() (*Report, error) {
+	server, err := net.ResolveUDPAddr("udp", d.Server)
+	if err != nil {
+		return nil, err
+	}
+	conn := d.Conn
+	if conn == nil {
+		if conn, err = net.ListenUDP("udp", nil); err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+	}
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	p := &packetConn{PacketConn: conn, remote: server}
+	// Requests are sent at 0, RTO and 3*RTO, timing out at 7*RTO.
+	c, err := stun.NewClient(p,
+		stun.WithRTO(timeout/7), stun.WithRc(3), stun.WithRm(4),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = c.Close()
+		// Resetting deadline that is set by packetConn.Close.
+		_ = conn.SetReadDeadline(time.Time{})
+	}()
+	r := &Report{}
+	if local, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		r.LocalAddr = local
+	}
+	if err = r.discover(c, p, server); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// result of single test.
+type result struct {
+	mapped *net.UDPAddr
+	other  *net.UDPAddr
+}
+
+func test(c *stun.Client, setters ...stun.Setter) (*result, error) {
+	var (
+		res    *result
+		resErr error
+	)
+	m := stun.MustBuild(append([]stun.Setter{stun.TransactionID, stun.BindingRequest}, setters...)...)
+	if err := c.Do(m, func(e stun.Event) {
+		if e.Error != nil {
+			resErr = e.Error
+			return
+		}
+		var mapped stun.XORMappedAddress
+		if resErr = mapped.GetFrom(e.Message); resErr != nil {
+			return
+		}
+		res = &result{
+			mapped: &net.UDPAddr{IP: mapped.IP, Port: mapped.Port},
+		}
+		var other stun.OtherAddress
+		if other.GetFrom(e.Message) == nil {
+			res.other = &net.UDPAddr{IP: other.IP, Port: other.Port}
+		}
+	}); err != nil {
+		return nil, err
+	}
+	return res, resErr
+}
+
+func equal(a, b *net.UDPAddr) bool {
+	return a.IP.Equal(b.IP) && a.Port == b.Port
+}
+
+func (r *Report) discover(c *stun.Client, p *packetConn, server *net.UDPAddr) error {
+	// Test I: binding request to primary address.
+	first, err := test(c)
+	if err != nil {
+		return err
+	}
+	if first.other == nil {
+		return ErrNoOtherAddress
+	}
+	r.MappedAddr, r.OtherAddr = first.mapped, first.other
+	if r.LocalAddr != nil && !r.LocalAddr.IP.IsUnspecified() && equal(r.LocalAddr, r.MappedAddr) {
+		r.NoNAT = true
+	}
+
+	// Mapping test II: alternate address, primary port.
+	p.setRemote(&net.UDPAddr{IP: r.OtherAddr.IP, Port: server.Port})
+	second, err := test(c)
+	if err != nil {
+		return err
+	}
+	if equal(second.mapped, r.MappedAddr) {
+		r.Mapping = EndpointIndependent
+	} else {
+		// Mapping test III: alternate address and port.
+		p.setRemote(r.OtherAddr)
+		third, thirdErr := test(c)
+		if thirdErr != nil {
+			return thirdErr
+		}
+		if equal(third.mapped, second.mapped) {
+			r.Mapping = AddressDependent
+		} else {
+			r.Mapping = AddressAndPortDependent
+		}
+	}
+
+	// Filtering test II: response from alternate address and port.
+	p.setRemote(server)
+	if _, err = test(c, stun.ChangeRequest{ChangeIP: true, ChangePort: true}); err == nil {
+		r.Filtering = EndpointIndependent
+		return nil
+	} else if err != stun.ErrTransactionTimeOut {
+		return err
+	}
+	// Filtering test III: response from primary address, alternate port.
+	if _, err = test(c, stun.ChangeRequest{ChangePort: true}); err == nil {
+		r.Filtering = AddressDependent
+		return nil
+	} else if err != stun.ErrTransactionTimeOut {
+		return err
+	}
+	r.Filtering = AddressAndPortDependent
+	return nil
+}
+
+// packetConn adapts unconnected UDP socket to stun.Connection, writing
+// to remote and reading from any address, because filtering tests
+// expect responses from other addresses.
+type packetConn struct {
+	net.PacketConn
+
+	mux    sync.Mutex // guards remote
+	remote *net.UDPAddr
+}
+
+func (c *packetConn) setRemote(addr *net.UDPAddr) {
+	c.mux.Lock()
+	c.remote = addr
+	c.mux.Unlock()
+}
+
+func (c *packetConn) Read(b []byte) (int, error) {
+	n, _, err := c.ReadFrom(b)
+	return n, err
+}
+
+func (c *packetConn) Write(b []byte) (int, error) {
+	c.mux.Lock()
+	remote := c.remote
+	c.mux.Unlock()
+	return c.WriteTo(b, remote)
+}
+
+// Close unblocks pending Read without closing underlying socket.
+func (c *packetConn) Close() error {
+	return c.SetReadDeadline(time.Now())
+}
@@ -0,0 +1,196 @@
+package natdiscovery
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+// server simulates RFC 5780 server behind simulated NAT.
+type server struct {
+	conns [4]*net.UDPConn // primary, alternate port, alternate IP, both
+
+	// mapping returns mapped port for request received on conns[i].
+	mapping func(i int) int
+	// filtered returns true if response from conns[responder] to
+	// request received on conns[i] is dropped by NAT.
+	filtered func(i, responder int) bool
+}
+
+func newServer(t *testing.T, mapping func(i int) int, filtered func(i, responder int) bool) *server {
+	s := &server{mapping: mapping, filtered: filtered}
+	primary, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alternate, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.conns[0], s.conns[1] = primary, alternate
+	for i, port := range []int{s.addr(0).Port, s.addr(1).Port} {
+		if s.conns[2+i], err = net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: port}); err != nil {
+			s.close()
+			t.Skip("unable to listen on 127.0.0.2:", err)
+		}
+	}
+	for i := range s.conns {
+		go s.serve(i)
+	}
+	return s
+}
+
+func (s *server) addr(i int) *net.UDPAddr {
+	return s.conns[i].LocalAddr().(*net.UDPAddr)
+}
+
+func (s *server) close() {
+	for _, c := range s.conns {
+		if c != nil {
+			_ = c.Close()
+		}
+	}
+}
+
+func (s *server) serve(i int) {
+	buf := make([]byte, 1024)
+	req := new(stun.Message)
+	for {
+		n, addr, err := s.conns[i].ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if stun.Decode(buf[:n], req) != nil {
+			continue
+		}
+		responder := i
+		var change stun.ChangeRequest
+		if change.GetFrom(req) == nil {
+			if change.ChangeIP {
+				responder ^= 2
+			}
+			if change.ChangePort {
+				responder ^= 1
+			}
+		}
+		if s.filtered != nil && s.filtered(i, responder) {
+			continue
+		}
+		other := s.addr(3)
+		res := stun.MustBuild(req, stun.BindingSuccess,
+			&stun.XORMappedAddress{IP: net.IPv4(203, 0, 113, 1), Port: s.mapping(i)},
+			&stun.OtherAddress{IP: other.IP, Port: other.Port},
+		)
+		_, _ = s.conns[responder].WriteToUDP(res.Raw, addr)
+	}
+}
+
+func TestDiscoverer_Discover(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		mapping  func(i int) int
+		filtered func(i, responder int) bool
+		expected Report
+	}{
+		{
+			name:     "EndpointIndependent",
+			mapping:  func(int) int { return 1000 },
+			expected: Report{Mapping: EndpointIndependent, Filtering: EndpointIndependent},
+		},
+		{
+			name:    "AddressDependent",
+			mapping: func(i int) int { return 1000 + i>>1 },
+			filtered: func(i, responder int) bool {
+				return i>>1 != responder>>1
+			},
+			expected: Report{Mapping: AddressDependent, Filtering: AddressDependent},
+		},
+		{
+			name:    "AddressAndPortDependent",
+			mapping: func(i int) int { return 1000 + i },
+			filtered: func(i, responder int) bool {
+				return i != responder
+			},
+			expected: Report{Mapping: AddressAndPortDependent, Filtering: AddressAndPortDependent},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newServer(t, tc.mapping, tc.filtered)
+			defer s.close()
+			conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			d := &Discoverer{
+				Server:  s.addr(0).String(),
+				Conn:    conn,
+				Timeout: 140 * time.Millisecond,
+			}
+			r, err := d.Discover()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Mapping != tc.expected.Mapping || r.Filtering != tc.expected.Filtering {
+				t.Errorf("mapping %s, filtering %s", r.Mapping, r.Filtering)
+			}
+			if r.NoNAT {
+				t.Error("should be behind NAT")
+			}
+			if !r.OtherAddr.IP.Equal(net.IPv4(127, 0, 0, 2)) || r.OtherAddr.Port != s.addr(1).Port {
+				t.Errorf("unexpected other address %s", r.OtherAddr)
+			}
+			// Conn should be readable after Discover.
+			if _, err = s.conns[0].WriteToUDP([]byte("ping"), conn.LocalAddr().(*net.UDPAddr)); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err = conn.ReadFromUDP(make([]byte, 16)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDiscoverer_NoOtherAddress(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		req := new(stun.Message)
+		for {
+			n, addr, readErr := conn.ReadFromUDP(buf)
+			if readErr != nil {
+				return
+			}
+			if stun.Decode(buf[:n], req) != nil {
+				continue
+			}
+			res := stun.MustBuild(req, stun.BindingSuccess,
+				&stun.XORMappedAddress{IP: addr.IP, Port: addr.Port},
+			)
+			_, _ = conn.WriteToUDP(res.Raw, addr)
+		}
+	}()
+	d := &Discoverer{Server: conn.LocalAddr().String(), Timeout: 140 * time.Millisecond}
+	if _, err := d.Discover(); err != ErrNoOtherAddress {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestBehavior_String(t *testing.T) {
+	for b, s := range map[Behavior]string{
+		BehaviorUnknown:         "unknown",
+		EndpointIndependent:     "endpoint-independent",
+		AddressDependent:        "address-dependent",
+		AddressAndPortDependent: "address and port-dependent",
+	} {
+		if b.String() != s {
+			t.Errorf("%q != %q", b, s)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package stun
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// SecurityFeatures is 24-bit set of STUN security features that are
+// signaled by server in nonce cookie.
+//
+// RFC 8489 Section 9.2
+type SecurityFeatures uint32
+
+// Security features from RFC 8489 Section 18.1, bit 0 is the most
+// significant one.
+const (
+	FeaturePasswordAlgorithms SecurityFeatures = 1 << 23
+	FeatureUsernameAnonymity  SecurityFeatures = 1 << 22
+)
+
+// Has returns true if all features from f are set.
+func (s SecurityFeatures) Has(f SecurityFeatures) bool {
+	return s&f == f
+}
+
+func (s SecurityFeatures) String() string {
+	var names []string
+	if s.Has(FeaturePasswordAlgorithms) {
+		names = append(names, "password algorithms")
+	}
+	if s.Has(FeatureUsernameAnonymity) {
+		names = append(names, "username anonymity")
+	}
+	if len(names) == 0 {
+		return "<nil>"
+	}
+	return strings.Join(names, ", ")
+}
+
+// constants for nonce cookie encoding.
+const (
+	nonceCookie         = "obMatJos2"
+	nonceFeaturesSize   = 3 // 24 bits
+	nonceFeaturesB64Len = 4 // base64 of 3 bytes
+	nonceCookieLen      = len(nonceCookie) + nonceFeaturesB64Len
+	securityFeaturesMax = 1<<24 - 1
+)
+
+// NewSecureNonce returns nonce with nonce cookie that signals features,
+// followed by opaque nonce value. Features outside of 24 bits are
+// ignored.
+func NewSecureNonce(features SecurityFeatures, nonce string) Nonce {
+	features &= securityFeaturesMax
+	b := [nonceFeaturesSize]byte{
+		byte(features >> 16), byte(features >> 8), byte(features),
+	}
+	v := make([]byte, nonceCookieLen, nonceCookieLen+len(nonce))
+	copy(v, nonceCookie)
+	base64.StdEncoding.Encode(v[len(nonceCookie):], b[:])
+	return Nonce(append(v, nonce...))
+}
+
+// Features returns security features signaled by nonce cookie, or false
+// if n does not start with valid nonce cookie.
+func (n Nonce) Features() (SecurityFeatures, bool) {
+	if len(n) < nonceCookieLen || string(n[:len(nonceCookie)]) != nonceCookie {
+		return 0, false
+	}
+	var b [nonceFeaturesSize]byte
+	if _, err := base64.StdEncoding.Decode(b[:], n[len(nonceCookie):nonceCookieLen]); err != nil {
+		return 0, false
+	}
+	return SecurityFeatures(b[0])<<16 | SecurityFeatures(b[1])<<8 | SecurityFeatures(b[2]), true
+}
@@ -0,0 +1,50 @@
+package stun
+
+import "testing"
+
+func TestNonce_Features(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		features SecurityFeatures
+		nonce    string
+	}{
+		{"None", 0, "AAAA"},
+		{"PasswordAlgorithms", FeaturePasswordAlgorithms, "gAAA"},
+		{"UsernameAnonymity", FeatureUsernameAnonymity, "QAAA"},
+		{"Both", FeaturePasswordAlgorithms | FeatureUsernameAnonymity, "wAAA"},
+		{"Overflow", 1<<24 | FeatureUsernameAnonymity, "QAAA"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := NewSecureNonce(tc.features, "opaque")
+			if expected := "obMatJos2" + tc.nonce + "opaque"; n.String() != expected {
+				t.Errorf("%q != %q", n, expected)
+			}
+			features, ok := n.Features()
+			if !ok {
+				t.Fatal("should have nonce cookie")
+			}
+			if features != tc.features&securityFeaturesMax {
+				t.Errorf("%s != %s", features, tc.features)
+			}
+		})
+	}
+	t.Run("NoCookie", func(t *testing.T) {
+		for _, n := range []string{"", "nonce", "obMatJos2", "obMatJos2AA", "obMatJos2!!!!nonce"} {
+			if _, ok := NewNonce(n).Features(); ok {
+				t.Errorf("%q should not have nonce cookie", n)
+			}
+		}
+	})
+}
+
+func TestSecurityFeatures_String(t *testing.T) {
+	for f, s := range map[SecurityFeatures]string{
+		0:                         "<nil>",
+		FeaturePasswordAlgorithms: "password algorithms",
+		FeaturePasswordAlgorithms | FeatureUsernameAnonymity: "password algorithms, username anonymity",
+	} {
+		if f.String() != s {
+			t.Errorf("%q != %q", f, s)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package stun
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// PasswordAlgorithm is algorithm number for PASSWORD-ALGORITHM and
+// PASSWORD-ALGORITHMS attributes.
+//
+// RFC 8489 Section 18.5
+type PasswordAlgorithm uint16
+
+// Possible password algorithms.
+const (
+	PasswordAlgorithmMD5    PasswordAlgorithm = 0x0001
+	PasswordAlgorithmSHA256 PasswordAlgorithm = 0x0002
+)
+
+var passwordAlgorithmNames = map[PasswordAlgorithm]string{
+	PasswordAlgorithmMD5:    "MD5",
+	PasswordAlgorithmSHA256: "SHA-256",
+}
+
+func (a PasswordAlgorithm) String() string {
+	s, ok := passwordAlgorithmNames[a]
+	if !ok {
+		return fmt.Sprintf("0x%x", uint16(a))
+	}
+	return s
+}
+
+// passwordAlgorithmsPreference lists supported algorithms, strongest
+// first.
+var passwordAlgorithmsPreference = []PasswordAlgorithm{
+	PasswordAlgorithmSHA256,
+	PasswordAlgorithmMD5,
+}
+
+// ErrUnsupportedPasswordAlgorithm means that password algorithm is not
+// supported.
+var ErrUnsupportedPasswordAlgorithm = errors.New("unsupported password algorithm")
+
+// LongTermIntegrity returns new MessageIntegrity with key for long-term
+// credentials derived by a, like NewLongTermIntegrity does for MD5.
+// Password, username, and realm must be prepared.
+//
+// RFC 8489 Section 9.2.2
+func (a PasswordAlgorithm) LongTermIntegrity(username, realm, password string) (MessageIntegrity, error) {
+	var h hash.Hash
+	switch a {
+	case PasswordAlgorithmMD5:
+		h = newMD5()
+	case PasswordAlgorithmSHA256:
+		h = newSHA256()
+	default:
+		return nil, ErrUnsupportedPasswordAlgorithm
+	}
+	fmt.Fprint(h, strings.Join([]string{username, realm, password}, credentialsSep))
+	return MessageIntegrity(h.Sum(nil)), nil
+}
+
+// PasswordAlgorithmAttribute represents PASSWORD-ALGORITHM attribute.
+//
+// RFC 8489 Section 14.5
+type PasswordAlgorithmAttribute struct {
+	Algorithm  PasswordAlgorithm
+	Parameters []byte
+}
+
+func (a PasswordAlgorithmAttribute) String() string {
+	if len(a.Parameters) == 0 {
+		return a.Algorithm.String()
+	}
+	return fmt.Sprintf("%s: 0x%x", a.Algorithm, a.Parameters)
+}
+
+// Equal returns true if a == b.
+func (a PasswordAlgorithmAttribute) Equal(b PasswordAlgorithmAttribute) bool {
+	return a.Algorithm == b.Algorithm && string(a.Parameters) == string(b.Parameters)
+}
+
+// constants for PASSWORD-ALGORITHM encoding.
+const (
+	passwordAlgorithmHeaderSize = 4
+	passwordAlgorithmMaxParams  = 0xFFFF
+)
+
+// appendPasswordAlgorithm appends encoded a to v, padding parameters if
+// pad is true.
+func appendPasswordAlgorithm(v []byte, a PasswordAlgorithmAttribute, pad bool) []byte {
+	var header [passwordAlgorithmHeaderSize]byte
+	bin.PutUint16(header[0:2], uint16(a.Algorithm))
+	bin.PutUint16(header[2:4], uint16(len(a.Parameters)))
+	v = append(v, header[:]...)
+	v = append(v, a.Parameters...)
+	if pad {
+		for i := len(a.Parameters); i < nearestPaddedValueLength(len(a.Parameters)); i++ {
+			v = append(v, 0)
+		}
+	}
+	return v
+}
+
+// parsePasswordAlgorithm decodes a from v, returning rest of v after
+// padded parameters. The a.Parameters refers to v.
+func parsePasswordAlgorithm(v []byte, a *PasswordAlgorithmAttribute) ([]byte, error) {
+	if len(v) < passwordAlgorithmHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	a.Algorithm = PasswordAlgorithm(bin.Uint16(v[0:2]))
+	length := int(bin.Uint16(v[2:4]))
+	v = v[passwordAlgorithmHeaderSize:]
+	if len(v) < length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	a.Parameters = v[:length]
+	if padded := nearestPaddedValueLength(length); padded <= len(v) {
+		return v[padded:], nil
+	}
+	return v[length:], nil
+}
+
+// AddTo adds PASSWORD-ALGORITHM attribute to message.
+func (a PasswordAlgorithmAttribute) AddTo(m *Message) error {
+	if err := CheckOverflow(AttrPasswordAlgorithm,
+		len(a.Parameters), passwordAlgorithmMaxParams,
+	); err != nil {
+		return err
+	}
+	v := make([]byte, 0, passwordAlgorithmHeaderSize+len(a.Parameters))
+	m.Add(AttrPasswordAlgorithm, appendPasswordAlgorithm(v, a, false))
+	return nil
+}
+
+// GetFrom decodes PASSWORD-ALGORITHM from message. Parameters are valid
+// until m.Raw is valid.
+func (a *PasswordAlgorithmAttribute) GetFrom(m *Message) error {
+	v, err := m.Get(AttrPasswordAlgorithm)
+	if err != nil {
+		return err
+	}
+	_, err = parsePasswordAlgorithm(v, a)
+	return err
+}
+
+// PasswordAlgorithms represents PASSWORD-ALGORITHMS attribute, the list
+// of algorithms supported by server in order of preference.
+//
+// RFC 8489 Section 14.11
+type PasswordAlgorithms []PasswordAlgorithmAttribute
+
+func (a PasswordAlgorithms) String() string {
+	if len(a) == 0 {
+		return "<nil>"
+	}
+	s := make([]string, len(a))
+	for i := range a {
+		s[i] = a[i].String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// AddTo adds PASSWORD-ALGORITHMS attribute to message.
+func (a PasswordAlgorithms) AddTo(m *Message) error {
+	v := make([]byte, 0, passwordAlgorithmHeaderSize*len(a))
+	for _, algorithm := range a {
+		if err := CheckOverflow(AttrPasswordAlgorithms,
+			len(algorithm.Parameters), passwordAlgorithmMaxParams,
+		); err != nil {
+			return err
+		}
+		v = appendPasswordAlgorithm(v, algorithm, true)
+	}
+	m.Add(AttrPasswordAlgorithms, v)
+	return nil
+}
+
+// GetFrom decodes PASSWORD-ALGORITHMS from message. Parameters are valid
+// until m.Raw is valid.
+func (a *PasswordAlgorithms) GetFrom(m *Message) error {
+	v, err := m.Get(AttrPasswordAlgorithms)
+	if err != nil {
+		return err
+	}
+	*a = (*a)[:0]
+	for len(v) > 0 {
+		var algorithm PasswordAlgorithmAttribute
+		if v, err = parsePasswordAlgorithm(v, &algorithm); err != nil {
+			return err
+		}
+		*a = append(*a, algorithm)
+	}
+	return nil
+}
+
+// without returns copy of a without algorithm.
+func (a PasswordAlgorithms) without(algorithm PasswordAlgorithm) PasswordAlgorithms {
+	var filtered PasswordAlgorithms
+	for _, candidate := range a {
+		if candidate.Algorithm != algorithm {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// Strongest returns the strongest algorithm from a that is supported by
+// this package, or false if there is no such algorithm.
+func (a PasswordAlgorithms) Strongest() (PasswordAlgorithmAttribute, bool) {
+	for _, supported := range passwordAlgorithmsPreference {
+		for _, algorithm := range a {
+			if algorithm.Algorithm == supported {
+				return algorithm, true
+			}
+		}
+	}
+	return PasswordAlgorithmAttribute{}, false
+}
@@ -0,0 +1,118 @@
+package stun
+
+import (
+	"crypto/sha256"
+	"io"
+	"testing"
+)
+
+func TestPasswordAlgorithm_LongTermIntegrity(t *testing.T) {
+	const (
+		username = "user"
+		realm    = "realm"
+		password = "pass"
+	)
+	md5, err := PasswordAlgorithmMD5.LongTermIntegrity(username, realm, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md5.String() != NewLongTermIntegrity(username, realm, password).String() {
+		t.Error("MD5 key should be equal to NewLongTermIntegrity")
+	}
+	sha, err := PasswordAlgorithmSHA256.LongTermIntegrity(username, realm, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := sha256.Sum256([]byte("user:realm:pass")); string(sha) != string(expected[:]) {
+		t.Errorf("unexpected SHA-256 key %s", sha)
+	}
+	if _, err := PasswordAlgorithm(0x1234).LongTermIntegrity(username, realm, password); err != ErrUnsupportedPasswordAlgorithm {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestPasswordAlgorithm_String(t *testing.T) {
+	for a, s := range map[PasswordAlgorithm]string{
+		PasswordAlgorithmMD5:    "MD5",
+		PasswordAlgorithmSHA256: "SHA-256",
+		0x1234:                  "0x1234",
+	} {
+		if a.String() != s {
+			t.Errorf("%q != %q", a, s)
+		}
+	}
+}
+
+func TestPasswordAlgorithms(t *testing.T) {
+	algorithms := PasswordAlgorithms{
+		{Algorithm: 0x1234, Parameters: []byte{1, 2, 3}},
+		{Algorithm: PasswordAlgorithmMD5},
+		{Algorithm: PasswordAlgorithmSHA256},
+	}
+	algorithm := PasswordAlgorithmAttribute{Algorithm: 0x1234, Parameters: []byte{1, 2, 3}}
+	m := MustBuild(BindingRequest, algorithm, algorithms)
+	decoded := new(Message)
+	if _, err := decoded.Write(m.Raw); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		gotAlgorithms PasswordAlgorithms
+		gotAlgorithm  PasswordAlgorithmAttribute
+	)
+	if err := gotAlgorithms.GetFrom(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if gotAlgorithms.String() != algorithms.String() {
+		t.Errorf("%s != %s", gotAlgorithms, algorithms)
+	}
+	if err := gotAlgorithm.GetFrom(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !gotAlgorithm.Equal(algorithm) {
+		t.Errorf("%s != %s", gotAlgorithm, algorithm)
+	}
+	t.Run("Strongest", func(t *testing.T) {
+		if a, ok := gotAlgorithms.Strongest(); !ok || a.Algorithm != PasswordAlgorithmSHA256 {
+			t.Error("unexpected strongest", a, ok)
+		}
+		if a, ok := gotAlgorithms[:2].Strongest(); !ok || a.Algorithm != PasswordAlgorithmMD5 {
+			t.Error("unexpected strongest", a, ok)
+		}
+		if _, ok := gotAlgorithms[:1].Strongest(); ok {
+			t.Error("should not find supported algorithm")
+		}
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		if err := gotAlgorithms.GetFrom(new(Message)); err != ErrAttributeNotFound {
+			t.Error("unexpected error", err)
+		}
+		if err := gotAlgorithm.GetFrom(new(Message)); err != ErrAttributeNotFound {
+			t.Error("unexpected error", err)
+		}
+	})
+	t.Run("Truncated", func(t *testing.T) {
+		for _, v := range [][]byte{
+			{0, 1, 0},
+			{0, 1, 0, 4, 1, 2},
+		} {
+			invalid := new(Message)
+			invalid.Add(AttrPasswordAlgorithms, v)
+			invalid.Add(AttrPasswordAlgorithm, v)
+			if err := gotAlgorithms.GetFrom(invalid); err != io.ErrUnexpectedEOF {
+				t.Error("unexpected error", err)
+			}
+			if err := gotAlgorithm.GetFrom(invalid); err != io.ErrUnexpectedEOF {
+				t.Error("unexpected error", err)
+			}
+		}
+	})
+	t.Run("Overflow", func(t *testing.T) {
+		big := PasswordAlgorithmAttribute{Parameters: make([]byte, 0x10000)}
+		if err := big.AddTo(new(Message)); !IsAttrSizeOverflow(err) {
+			t.Error("unexpected error", err)
+		}
+		if err := (PasswordAlgorithms{big}).AddTo(new(Message)); !IsAttrSizeOverflow(err) {
+			t.Error("unexpected error", err)
+		}
+	})
+}
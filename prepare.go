@@ -0,0 +1,112 @@
+package stun
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Possible errors of credentials preparation.
+var (
+	ErrEmptyString         = errors.New("empty string is not allowed")
+	ErrDisallowedCharacter = errors.New("string contains disallowed character")
+)
+
+// OpaqueString prepares username or password with OpaqueString profile
+// of PRECIS framework, as required by RFC 8489 Section 9.2 for
+// long-term credentials: non-ASCII spaces are mapped to ASCII space,
+// and empty strings or strings with control, surrogate, non-character
+// or unassigned code points are rejected.
+//
+// Unicode normalization is not performed, so s should be in NFC already,
+// which is the case for most input methods.
+//
+// RFC 8265 Section 4.2
+func OpaqueString(s string) (string, error) {
+	if s == "" {
+		return "", ErrEmptyString
+	}
+	var invalid bool
+	prepared := strings.Map(func(r rune) rune {
+		switch {
+		case r != ' ' && unicode.Is(unicode.Zs, r):
+			return ' '
+		case unicode.IsControl(r) || unicode.Is(unicode.Cs, r) || isNonCharacter(r) || !isAssigned(r):
+			invalid = true
+		}
+		return r
+	}, s)
+	if invalid {
+		return "", ErrDisallowedCharacter
+	}
+	return prepared, nil
+}
+
+// SASLprep prepares username or password with legacy SASLprep profile
+// of stringprep, as required by RFC 5389: characters commonly mapped to
+// nothing are removed, non-ASCII spaces are mapped to ASCII space, and
+// strings with prohibited characters are rejected. Use it instead of
+// OpaqueString for servers that implement only RFC 5389.
+//
+// Unicode normalization and bidirectional checks are not performed.
+//
+// RFC 4013
+func SASLprep(s string) (string, error) {
+	var invalid bool
+	prepared := strings.Map(func(r rune) rune {
+		switch {
+		case isMappedToNothing(r):
+			return -1
+		case r != ' ' && unicode.Is(unicode.Zs, r):
+			return ' '
+		case isSASLProhibited(r):
+			invalid = true
+		}
+		return r
+	}, s)
+	if invalid {
+		return "", ErrDisallowedCharacter
+	}
+	return prepared, nil
+}
+
+func isAssigned(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z, unicode.C)
+}
+
+func isNonCharacter(r rune) bool {
+	return (r >= 0xFDD0 && r <= 0xFDEF) || r&0xFFFE == 0xFFFE
+}
+
+// isMappedToNothing reports whether r is in RFC 3454 Table B.1.
+func isMappedToNothing(r rune) bool {
+	switch {
+	case r == 0x00AD, r == 0x034F, r == 0x1806, r == 0x2060, r == 0xFEFF:
+		return true
+	case r >= 0x180B && r <= 0x180D, r >= 0x200B && r <= 0x200D, r >= 0xFE00 && r <= 0xFE0F:
+		return true
+	}
+	return false
+}
+
+// isSASLProhibited reports whether r is in RFC 3454 tables C.2 — C.9,
+// which are prohibited by RFC 4013 Section 2.3.
+func isSASLProhibited(r rune) bool {
+	switch {
+	case unicode.IsControl(r), unicode.Is(unicode.Co, r), unicode.Is(unicode.Cs, r), isNonCharacter(r):
+		return true
+	case r >= 0xFFF9 && r <= 0xFFFD, r >= 0x2FF0 && r <= 0x2FFB:
+		return true
+	case r == 0x0340, r == 0x0341, r == 0x200E, r == 0x200F:
+		return true
+	case r >= 0x202A && r <= 0x202E, r >= 0x206A && r <= 0x206F:
+		return true
+	case r == 0x06DD, r == 0x070F, r == 0x180E, r >= 0x1D173 && r <= 0x1D17A:
+		return true
+	case r >= 0x2028 && r <= 0x2029, r >= 0x2061 && r <= 0x2063:
+		return true
+	case r == 0xE0001, r >= 0xE0020 && r <= 0xE007F:
+		return true
+	}
+	return false
+}
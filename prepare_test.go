@@ -0,0 +1,54 @@
+package stun
+
+import "testing"
+
+func TestOpaqueString(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+		err     error
+	}{
+		{"user", "user", nil},
+		{"correct horse battery staple", "correct horse battery staple", nil},
+		{"Correct Horse Battery Staple", "Correct Horse Battery Staple", nil},
+		{"πßå", "πßå", nil},
+		{"Jack of ♦s", "Jack of ♦s", nil},
+		{"Foo\u1680Bar", "Foo Bar", nil},
+		{"foo\u00a0bar", "foo bar", nil},
+		{"", "", ErrEmptyString},
+		{"my cat is a \u0009by", "", ErrDisallowedCharacter},
+		{"bad\ufdd0", "", ErrDisallowedCharacter},
+		{"bad\U0001ffff", "", ErrDisallowedCharacter},
+	} {
+		out, err := OpaqueString(tc.in)
+		if err != tc.err {
+			t.Errorf("%q: unexpected error %v, expected %v", tc.in, err, tc.err)
+		}
+		if out != tc.out {
+			t.Errorf("%q: got %q, expected %q", tc.in, out, tc.out)
+		}
+	}
+}
+
+func TestSASLprep(t *testing.T) {
+	// Based on RFC 4013 Section 3 examples, excluding normalization ones.
+	for _, tc := range []struct {
+		in, out string
+		err     error
+	}{
+		{"I\u00adX", "IX", nil},
+		{"user", "user", nil},
+		{"USER", "USER", nil},
+		{"a\u2003b", "a b", nil},
+		{"\u0007", "", ErrDisallowedCharacter},
+		{"pass\ue000", "", ErrDisallowedCharacter},
+		{"\u200etext", "", ErrDisallowedCharacter},
+	} {
+		out, err := SASLprep(tc.in)
+		if err != tc.err {
+			t.Errorf("%q: unexpected error %v, expected %v", tc.in, err, tc.err)
+		}
+		if out != tc.out {
+			t.Errorf("%q: got %q, expected %q", tc.in, out, tc.out)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package stun
+
+import "strings"
+
+// Quirks is set of workarounds for servers that do not follow RFC.
+type Quirks uint32
+
+// Possible quirks.
+const (
+	// QuirkNoSHA256 disables SHA-256 password algorithm, e.g. for
+	// servers that advertise it in PASSWORD-ALGORITHMS but reject it.
+	QuirkNoSHA256 Quirks = 1 << iota
+	// QuirkNoUserhash disables USERHASH, sending USERNAME even if
+	// server signals username anonymity.
+	QuirkNoUserhash
+	// QuirkNoFingerprint removes FINGERPRINT from authenticated
+	// requests for servers that fail to check it.
+	QuirkNoFingerprint
+)
+
+// Has returns true if all quirks from f are set.
+func (q Quirks) Has(f Quirks) bool {
+	return q&f == f
+}
+
+// QuirksTable maps prefix of server SOFTWARE to its quirks.
+//
+// Example:
+//
+//	stun.WithQuirks(stun.QuirksTable{
+//		"example-server/1.": stun.QuirkNoSHA256,
+//	})
+type QuirksTable map[string]Quirks
+
+// Lookup returns quirks of server with SOFTWARE s, using entry with
+// the longest matching prefix.
+func (t QuirksTable) Lookup(s string) Quirks {
+	var (
+		quirks  Quirks
+		longest = -1
+	)
+	for prefix, q := range t {
+		if len(prefix) > longest && strings.HasPrefix(s, prefix) {
+			quirks, longest = q, len(prefix)
+		}
+	}
+	return quirks
+}
+
+// WithQuirks sets table that is consulted with SOFTWARE of responses to
+// detect quirks of server, see Client.Quirks.
+func WithQuirks(t QuirksTable) ClientOption {
+	return func(c *Client) {
+		c.quirksTable = t
+	}
+}
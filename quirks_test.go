@@ -0,0 +1,87 @@
+package stun
+
+import (
+	"net"
+	"testing"
+)
+
+func TestQuirksTable_Lookup(t *testing.T) {
+	table := QuirksTable{
+		"server":      QuirkNoFingerprint,
+		"server/1.":   QuirkNoSHA256,
+		"server/1.2.": QuirkNoSHA256 | QuirkNoUserhash,
+	}
+	for software, quirks := range map[string]Quirks{
+		"":               0,
+		"other":          0,
+		"server/2.0":     QuirkNoFingerprint,
+		"server/1.0":     QuirkNoSHA256,
+		"server/1.2.3":   QuirkNoSHA256 | QuirkNoUserhash,
+		"server/1.2.3 x": QuirkNoSHA256 | QuirkNoUserhash,
+	} {
+		if got := table.Lookup(software); got != quirks {
+			t.Errorf("%q: %b != %b", software, got, quirks)
+		}
+	}
+	if QuirksTable(nil).Lookup("server") != 0 {
+		t.Error("nil table should have no quirks")
+	}
+}
+
+func TestClient_Quirks(t *testing.T) {
+	offered := PasswordAlgorithms{
+		{Algorithm: PasswordAlgorithmMD5},
+		{Algorithm: PasswordAlgorithmSHA256},
+	}
+	const (
+		username = "user"
+		realm    = "realm"
+		password = "secret"
+	)
+	software := NewSoftware("buggy/1.0")
+	integrity := NewLongTermIntegrity(username, realm, password)
+	addr, closeServer := serveUDP(t, func(req *Message) *Message {
+		challenge := MustBuild(req, NewType(req.Type.Method, ClassErrorResponse), CodeUnauthorized,
+			NewRealm(realm), NewSecureNonce(FeaturePasswordAlgorithms, "nonce"), offered, software,
+		)
+		if err := integrity.Check(req); err != nil {
+			return challenge
+		}
+		if req.Contains(AttrFingerprint) {
+			t.Error("FINGERPRINT should be removed")
+		}
+		return MustBuild(req, BindingSuccess, software, integrity)
+	})
+	defer closeServer()
+	conn, err := net.Dial("udp4", addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(conn, WithQuirks(QuirksTable{
+		"buggy/": QuirkNoSHA256 | QuirkNoFingerprint,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.Quirks() != 0 {
+		t.Error("quirks should not be detected before response")
+	}
+	l := &LongTermCredentials{Username: username, Password: password}
+	var e Event
+	if err := l.Do(c, func(event Event) {
+		e.Error = event.Error
+		if event.Message != nil {
+			e.Message = new(Message)
+			_ = event.Message.CloneTo(e.Message)
+		}
+	}, BindingRequest, Fingerprint); err != nil {
+		t.Fatal(err)
+	}
+	if e.Error != nil || e.Message == nil || e.Message.Type != BindingSuccess {
+		t.Fatal("unexpected event", e.Error, e.Message)
+	}
+	if !c.Quirks().Has(QuirkNoSHA256 | QuirkNoFingerprint) {
+		t.Error("quirks should be detected", c.Quirks())
+	}
+}
@@ -0,0 +1,207 @@
+package stun
+
+import (
+	"net"
+	"sync"
+)
+
+// WithTransactionLimit limits count of requests from each source IP
+// that are processed concurrently. Messages of each packet conn and
+// stream connection are processed one by one, so requests from one IP
+// are concurrent only on different conns, e.g. when client opens
+// multiple TCP connections or sends to multiple packet conns of server
+// while handler is slow. Requests beyond the limit are rejected with
+// 500 (Server Error), so client retries later. Zero means no limit.
+//
+// Long-living state of client, like allocations, is limited by
+// WithAllocationLimit.
+func WithTransactionLimit(n int) ServerOption {
+	return func(s *Server) {
+		s.transactions.limit = n
+	}
+}
+
+// WithAllocationLimit limits count of TURN allocations of each source
+// IP. Allocate requests beyond the limit are rejected with 486
+// (Allocation Quota Reached) without calling handler. Allocation is
+// counted once per 5-tuple when handler responds to Allocate request
+// with success response, so retransmitted requests are not counted
+// again, and should be released by Server.ReleaseAllocation when it
+// expires or is deleted. Zero means no limit.
+//
+// RFC 8656 Section 7.2
+func WithAllocationLimit(n int) ServerOption {
+	return func(s *Server) {
+		s.allocations.limit = n
+	}
+}
+
+// ipQuota counts resources that are used by each source IP. The zero
+// value does not limit.
+type ipQuota struct {
+	limit int
+
+	mux    sync.Mutex
+	count  map[[net.IPv6len]byte]int
+	memory *memoryBudget
+}
+
+func quotaKey(ip net.IP) [net.IPv6len]byte {
+	var key [net.IPv6len]byte
+	copy(key[:], ip.To16())
+	return key
+}
+
+// acquire takes resource for ip, returning false if limit is reached.
+func (q *ipQuota) acquire(ip net.IP) bool {
+	if q.limit <= 0 || ip == nil {
+		return true
+	}
+	key := quotaKey(ip)
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.count[key] >= q.limit {
+		return false
+	}
+	if q.count[key] == 0 && !q.memory.reserve(quotaMemory) {
+		return false
+	}
+	if q.count == nil {
+		q.count = make(map[[net.IPv6len]byte]int)
+	}
+	q.count[key]++
+	return true
+}
+
+// release returns resource that is taken by acquire.
+func (q *ipQuota) release(ip net.IP) {
+	if q.limit <= 0 || ip == nil {
+		return
+	}
+	key := quotaKey(ip)
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	switch n := q.count[key]; {
+	case n > 1:
+		q.count[key] = n - 1
+	case n == 1:
+		delete(q.count, key)
+		q.memory.free(quotaMemory)
+	}
+}
+
+// used returns count of resources that are taken by ip.
+func (q *ipQuota) used(ip net.IP) int {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return q.count[quotaKey(ip)]
+}
+
+// allocationQuota is ipQuota of allocations, which are identified by
+// 5-tuple, so each allocation is counted once.
+//
+// RFC 8656 Section 2.2
+type allocationQuota struct {
+	ipQuota
+	tuples map[string]struct{} // guarded by ipQuota.mux
+}
+
+// fiveTuple returns key of allocation of client at addr. Server
+// transport address is the same for all requests that are read from
+// one listener or connection, so it is omitted.
+func fiveTuple(addr net.Addr) string {
+	return addr.Network() + "/" + addr.String()
+}
+
+// acquire takes resource for ip if 5-tuple has no allocation yet,
+// returning true as counted in that case, and ok as false if limit is
+// reached.
+func (q *allocationQuota) acquire(ip net.IP, tuple string) (counted, ok bool) {
+	if q.limit <= 0 || ip == nil {
+		return false, true
+	}
+	key := quotaKey(ip)
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if _, exists := q.tuples[tuple]; exists {
+		return false, true
+	}
+	if q.count[key] >= q.limit {
+		return false, false
+	}
+	memory := tupleMemory + int64(len(tuple))
+	if q.count[key] == 0 {
+		memory += quotaMemory
+	}
+	if !q.memory.reserve(memory) {
+		return false, false
+	}
+	if q.count == nil {
+		q.count = make(map[[net.IPv6len]byte]int)
+		q.tuples = make(map[string]struct{})
+	}
+	q.count[key]++
+	q.tuples[tuple] = struct{}{}
+	return true, true
+}
+
+// release returns resource of 5-tuple that is taken by acquire, if any.
+func (q *allocationQuota) release(ip net.IP, tuple string) {
+	if q.limit <= 0 || ip == nil {
+		return
+	}
+	q.mux.Lock()
+	_, exists := q.tuples[tuple]
+	delete(q.tuples, tuple)
+	q.mux.Unlock()
+	if exists {
+		q.memory.free(tupleMemory + int64(len(tuple)))
+		q.ipQuota.release(ip)
+	}
+}
+
+var (
+	allocateRequest = NewType(MethodAllocate, ClassRequest)
+	allocateSuccess = NewType(MethodAllocate, ClassSuccessResponse)
+)
+
+// ReleaseAllocation releases allocation of client at addr that is
+// counted by WithAllocationLimit.
+func (s *Server) ReleaseAllocation(addr net.Addr) {
+	s.allocations.release(sourceIP(addr), fiveTuple(addr))
+}
+
+// Allocations returns count of allocations of source IP of addr that
+// are counted by WithAllocationLimit.
+func (s *Server) Allocations(addr net.Addr) int {
+	return s.allocations.used(sourceIP(addr))
+}
+
+// handle calls handler for req, rejecting Allocate requests that exceed
+// allocation limit.
+func (s *Server) handle(res, req *Message, addr net.Addr) {
+	if req.Type != allocateRequest {
+		s.handler(res, req, addr)
+		return
+	}
+	ip, tuple := sourceIP(addr), fiveTuple(addr)
+	counted, ok := s.allocations.acquire(ip, tuple)
+	if !ok {
+		_ = res.Build(req, NewType(req.Type.Method, ClassErrorResponse), CodeAllocQuotaReached)
+		return
+	}
+	if !counted {
+		// Retransmission or request on existing allocation.
+		s.handler(res, req, addr)
+		return
+	}
+	allocated := false
+	defer func() {
+		// Also releasing if handler panics.
+		if !allocated {
+			s.allocations.release(ip, tuple)
+		}
+	}()
+	s.handler(res, req, addr)
+	allocated = len(res.Raw) != 0 && res.Type == allocateSuccess
+}
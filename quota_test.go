@@ -0,0 +1,220 @@
+package stun
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIPQuota(t *testing.T) {
+	var (
+		q = ipQuota{limit: 2}
+		a = net.IPv4(127, 0, 0, 1)
+		b = net.ParseIP("::1")
+	)
+	for i, ok := range []bool{true, true, false} {
+		if q.acquire(a) != ok {
+			t.Errorf("%d: acquire should be %v", i, ok)
+		}
+	}
+	if !q.acquire(b) || q.used(b) != 1 {
+		t.Error("quotas of IPs should be independent")
+	}
+	q.release(a)
+	if !q.acquire(a) {
+		t.Error("released resource should be available")
+	}
+	q.release(a)
+	q.release(a)
+	q.release(a)
+	if q.used(a) != 0 || len(q.count) != 1 {
+		t.Errorf("unexpected count %v", q.count)
+	}
+	zero := ipQuota{}
+	for i := 0; i < 10; i++ {
+		if !zero.acquire(a) {
+			t.Fatal("zero quota should not limit")
+		}
+	}
+}
+
+func TestServer_TransactionLimit(t *testing.T) {
+	var (
+		slow    = NewType(0x100, ClassRequest)
+		addr    = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+		other   = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 3478}
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	s := newTestServer(t, WithTransactionLimit(1), WithServerHandler(func(res, req *Message, a net.Addr) {
+		if req.Type == slow {
+			started <- struct{}{}
+			<-release
+		}
+		_ = res.Build(req, NewType(req.Type.Method, ClassSuccessResponse))
+	}))
+	done := make(chan bool)
+	go func() {
+		done <- s.process(new(Message), new(Message), MustBuild(TransactionID, slow).Raw, addr)
+	}()
+	<-started
+	res := new(Message)
+	req := MustBuild(TransactionID, NewType(0x101, ClassRequest))
+	if !s.process(res, new(Message), req.Raw, addr) {
+		t.Fatal("no response")
+	}
+	var code ErrorCodeAttribute
+	if err := code.GetFrom(res); err != nil || code.Code != CodeServerError {
+		t.Errorf("unexpected %s, %v", code, err)
+	}
+	if !s.process(res, new(Message), req.Raw, other) || res.Type.Class != ClassSuccessResponse {
+		t.Errorf("request from other IP should be handled: %s", res)
+	}
+	indication := MustBuild(TransactionID, NewType(0x101, ClassIndication))
+	if !s.process(res, new(Message), indication.Raw, addr) {
+		t.Error("indications should not be limited")
+	}
+	close(release)
+	if !<-done {
+		t.Error("no response to slow request")
+	}
+	if !s.process(res, new(Message), req.Raw, addr) || res.Type.Class != ClassSuccessResponse {
+		t.Errorf("request should be handled after release: %s", res)
+	}
+}
+
+func TestServer_TransactionLimitUDP(t *testing.T) {
+	var (
+		slow    = NewType(0x100, ClassRequest)
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	s := newTestServer(t, WithTransactionLimit(1), WithServerHandler(func(res, req *Message, a net.Addr) {
+		if req.Type == slow {
+			started <- struct{}{}
+			<-release
+		}
+		_ = res.Build(req, NewType(req.Type.Method, ClassSuccessResponse))
+	}))
+	defer s.Close()
+	// Requests to one packet conn are processed one by one, so limit is
+	// exceeded by sending to other conn of server.
+	first, _ := listenServer(t, "udp", s)
+	second, _ := listenServer(t, "udp", s)
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	send := func(addr string, m *Message) {
+		t.Helper()
+		udpAddr, resolveErr := net.ResolveUDPAddr("udp4", addr)
+		if resolveErr != nil {
+			t.Fatal(resolveErr)
+		}
+		if _, writeErr := conn.WriteTo(m.Raw, udpAddr); writeErr != nil {
+			t.Fatal(writeErr)
+		}
+	}
+	receive := func() *Message {
+		t.Helper()
+		buf := make([]byte, 1024)
+		if deadlineErr := conn.SetReadDeadline(time.Now().Add(time.Second * 5)); deadlineErr != nil {
+			t.Fatal(deadlineErr)
+		}
+		n, _, readErr := conn.ReadFrom(buf)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+		m := new(Message)
+		if decodeErr := Decode(buf[:n], m); decodeErr != nil {
+			t.Fatal(decodeErr)
+		}
+		return m
+	}
+	send(first, MustBuild(TransactionID, slow))
+	<-started
+	send(second, MustBuild(TransactionID, NewType(0x101, ClassRequest)))
+	res := receive()
+	var code ErrorCodeAttribute
+	if err = code.GetFrom(res); err != nil || code.Code != CodeServerError {
+		t.Errorf("unexpected %s, %v", code, err)
+	}
+	close(release)
+	if res = receive(); res.Type != NewType(slow.Method, ClassSuccessResponse) {
+		t.Errorf("unexpected response to slow request: %s", res)
+	}
+	send(second, MustBuild(TransactionID, NewType(0x101, ClassRequest)))
+	if res = receive(); res.Type.Class != ClassSuccessResponse {
+		t.Errorf("request should be handled after release: %s", res)
+	}
+}
+
+func TestServer_AllocationLimit(t *testing.T) {
+	var (
+		addr  = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+		other = &net.UDPAddr{IP: addr.IP, Port: 3479}
+		calls = 0
+		fail  = false
+	)
+	s := newTestServer(t, WithAllocationLimit(1), WithServerHandler(func(res, req *Message, a net.Addr) {
+		calls++
+		if fail {
+			_ = res.Build(req, NewType(req.Type.Method, ClassErrorResponse), CodeInsufficientCapacity)
+			return
+		}
+		_ = res.Build(req, allocateSuccess)
+	}))
+	allocate := func(a net.Addr) *Message {
+		res := new(Message)
+		if !s.process(res, new(Message), MustBuild(TransactionID, allocateRequest).Raw, a) {
+			t.Fatal("no response")
+		}
+		return res
+	}
+	fail = true
+	if res := allocate(addr); res.Type.Class != ClassErrorResponse || s.Allocations(addr) != 0 {
+		t.Errorf("failed allocation should not be counted: %s", res)
+	}
+	fail = false
+	if res := allocate(addr); res.Type != allocateSuccess || s.Allocations(addr) != 1 {
+		t.Errorf("unexpected %s", res)
+	}
+	res := allocate(other)
+	var code ErrorCodeAttribute
+	if err := code.GetFrom(res); err != nil || code.Code != CodeAllocQuotaReached {
+		t.Errorf("unexpected %s, %v", code, err)
+	}
+	if calls != 2 {
+		t.Errorf("handler should not be called beyond limit, calls: %d", calls)
+	}
+	s.ReleaseAllocation(addr)
+	if res := allocate(other); res.Type != allocateSuccess {
+		t.Errorf("unexpected %s", res)
+	}
+}
+
+func TestServer_AllocationLimitRetransmission(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+	s := newTestServer(t, WithAllocationLimit(2), WithServerHandler(func(res, req *Message, a net.Addr) {
+		_ = res.Build(req, allocateSuccess)
+	}))
+	req := MustBuild(TransactionID, allocateRequest)
+	for i := 0; i < 2; i++ {
+		res := new(Message)
+		if !s.process(res, new(Message), req.Raw, addr) || res.Type != allocateSuccess {
+			t.Fatalf("unexpected %s", res)
+		}
+		if n := s.Allocations(addr); n != 1 {
+			t.Fatalf("allocation should be counted once, got %d", n)
+		}
+	}
+	s.ReleaseAllocation(addr)
+	if n := s.Allocations(addr); n != 0 {
+		t.Errorf("allocation should be released, got %d", n)
+	}
+	s.ReleaseAllocation(addr)
+	if n := s.Allocations(addr); n != 0 {
+		t.Errorf("unexpected allocations %d", n)
+	}
+}
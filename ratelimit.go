@@ -0,0 +1,191 @@
+package stun
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Verdict is decision of ServerPolicy about message.
+type Verdict byte
+
+// Possible verdicts.
+const (
+	// VerdictAccept means that message is processed.
+	VerdictAccept Verdict = iota
+	// VerdictDrop means that message is dropped and counted in
+	// Stats as received and dropped.
+	VerdictDrop
+	// VerdictIgnore means that message is silently ignored, e.g. from
+	// blocked sender, and is not counted in Stats.
+	VerdictIgnore
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case VerdictAccept:
+		return "accept"
+	case VerdictDrop:
+		return "drop"
+	case VerdictIgnore:
+		return "ignore"
+	default:
+		return "unknown"
+	}
+}
+
+// ServerPolicy decides what to do with message from addr before it is
+// decoded. The limited is true if rate limit of source IP is exceeded,
+// see WithRateLimit. Default policy drops limited messages and accepts
+// others. Policy is called concurrently by serve methods.
+type ServerPolicy func(addr net.Addr, limited bool) Verdict
+
+func defaultPolicy(addr net.Addr, limited bool) Verdict {
+	if limited {
+		return VerdictDrop
+	}
+	return VerdictAccept
+}
+
+// WithPolicy sets policy that is consulted for every message, e.g. to
+// block abusive senders.
+func WithPolicy(p ServerPolicy) ServerOption {
+	return func(s *Server) {
+		s.policy = p
+	}
+}
+
+// WithRateLimit limits rate of messages from each source IP with token
+// bucket that is refilled by rate tokens per second up to burst.
+// Messages that exceed the limit are dropped unless policy decides
+// otherwise, see WithPolicy. Public servers are target of reflection
+// attacks with spoofed source addresses, so rate limiting protects
+// victims as well as server.
+func WithRateLimit(rate float64, burst int) ServerOption {
+	return func(s *Server) {
+		s.limiter.rate = rate
+		s.limiter.burst = float64(burst)
+	}
+}
+
+// WithMaxAmplification drops responses to UDP messages that are larger
+// than factor times the message, so server cannot be used to amplify
+// traffic to spoofed source. Zero means no limit.
+func WithMaxAmplification(factor int) ServerOption {
+	return func(s *Server) {
+		s.maxAmplification = factor
+	}
+}
+
+// maxRateBuckets is maximum count of tracked source IPs of rate limiter.
+const maxRateBuckets = 1 << 16
+
+// tokenBucket is state of rate limit for single source IP.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is per-source-IP token bucket rate limiter. The zero
+// value does not limit.
+type rateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mux     sync.Mutex
+	buckets map[[net.IPv6len]byte]tokenBucket
+	memory  *memoryBudget
+}
+
+// refill returns b refilled up to burst at now.
+func (l *rateLimiter) refill(b tokenBucket, now time.Time) tokenBucket {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * l.rate
+		b.last = now
+	}
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	return b
+}
+
+// allow takes token of bucket of ip at now, returning false if bucket
+// is empty.
+func (l *rateLimiter) allow(ip net.IP, now time.Time) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	var key [net.IPv6len]byte
+	copy(key[:], ip.To16())
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateBuckets {
+			l.prune(now)
+		}
+		if !l.memory.reserve(rateBucketMemory) {
+			// Treating as limited if memory limit is reached even after
+			// pruning.
+			l.prune(now)
+			if !l.memory.reserve(rateBucketMemory) {
+				return false
+			}
+		}
+		b = tokenBucket{tokens: l.burst, last: now}
+	}
+	b = l.refill(b, now)
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	if l.buckets == nil {
+		l.buckets = make(map[[net.IPv6len]byte]tokenBucket)
+	}
+	l.buckets[key] = b
+	return allowed
+}
+
+// prune removes buckets that are refilled, because they are equivalent
+// to new ones. If all buckets are still in use, e.g. during flood from
+// spoofed addresses, they are reset, so legitimate clients are not
+// rejected.
+func (l *rateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now).tokens >= l.burst {
+			delete(l.buckets, key)
+			l.memory.free(rateBucketMemory)
+		}
+	}
+	if len(l.buckets) >= maxRateBuckets {
+		l.memory.free(rateBucketMemory * int64(len(l.buckets)))
+		l.buckets = nil
+	}
+}
+
+// sourceIP returns IP of addr or nil.
+func sourceIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.TCPAddr:
+		return a.IP
+	default:
+		return nil
+	}
+}
+
+// verdict returns decision about message from addr.
+func (s *Server) verdict(addr net.Addr) Verdict {
+	limited := false
+	if ip := sourceIP(addr); ip != nil {
+		limited = !s.limiter.allow(ip, s.now())
+	}
+	return s.policy(addr, limited)
+}
+
+// amplified returns true if response of size n to message of size
+// received exceeds amplification limit.
+func (s *Server) amplified(n, received int) bool {
+	return s.maxAmplification > 0 && n > received*s.maxAmplification
+}
@@ -0,0 +1,153 @@
+package stun
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var (
+		l   = rateLimiter{rate: 1, burst: 2}
+		now = time.Unix(1000, 0)
+		a   = net.IPv4(127, 0, 0, 1)
+		b   = net.IPv4(127, 0, 0, 2)
+	)
+	for i, allowed := range []bool{true, true, false} {
+		if l.allow(a, now) != allowed {
+			t.Errorf("%d: allowed should be %v", i, allowed)
+		}
+	}
+	if !l.allow(b, now) {
+		t.Error("buckets of IPs should be independent")
+	}
+	if !l.allow(a, now.Add(time.Second)) {
+		t.Error("bucket should be refilled")
+	}
+	if l.allow(a, now.Add(time.Second)) {
+		t.Error("bucket should be empty")
+	}
+	if !(&rateLimiter{}).allow(a, now) {
+		t.Error("zero limiter should not limit")
+	}
+	t.Run("Prune", func(t *testing.T) {
+		fill := func(l *rateLimiter) {
+			for i := 0; i < maxRateBuckets; i++ {
+				l.allow(net.IPv4(10, 0, byte(i>>8), byte(i)), now)
+			}
+		}
+		l := &rateLimiter{rate: 1, burst: 1}
+		fill(l)
+		l.allow(a, now.Add(time.Second))
+		if len(l.buckets) != 1 {
+			t.Errorf("refilled buckets should be removed, %d left", len(l.buckets))
+		}
+		l = &rateLimiter{rate: 1, burst: 1}
+		fill(l)
+		if !l.allow(a, now) || len(l.buckets) != 1 {
+			t.Errorf("buckets should be reset, %d left", len(l.buckets))
+		}
+	})
+}
+
+func TestVerdict_String(t *testing.T) {
+	for v, s := range map[Verdict]string{
+		VerdictAccept: "accept",
+		VerdictDrop:   "drop",
+		VerdictIgnore: "ignore",
+		Verdict(10):   "unknown",
+	} {
+		if v.String() != s {
+			t.Errorf("%q, expected %q", v, s)
+		}
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	var (
+		addr    = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+		blocked = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: 3478}
+		req     = MustBuild(TransactionID, BindingRequest)
+		res     = new(Message)
+		limited []bool
+	)
+	s := newTestServer(t, WithRateLimit(1, 2), WithPolicy(func(a net.Addr, l bool) Verdict {
+		if a == blocked {
+			return VerdictIgnore
+		}
+		limited = append(limited, l)
+		return defaultPolicy(a, l)
+	}))
+	now := time.Unix(1000, 0)
+	s.now = func() time.Time { return now }
+	for i, ok := range []bool{true, true, false} {
+		if s.process(res, new(Message), req.Raw, addr) != ok {
+			t.Errorf("%d: response should be %v", i, ok)
+		}
+	}
+	if s.process(res, new(Message), req.Raw, blocked) {
+		t.Error("blocked sender should be ignored")
+	}
+	now = now.Add(time.Second)
+	if !s.process(res, new(Message), req.Raw, addr) {
+		t.Error("should respond after refill")
+	}
+	if len(limited) != 4 || !limited[2] || limited[3] {
+		t.Errorf("unexpected limited %v", limited)
+	}
+	stats := s.Stats().Snapshot()
+	if stats.Received != 4 || stats.Dropped != 1 {
+		t.Errorf("unexpected stats %+v", stats.StatsCounters)
+	}
+}
+
+func TestServer_MaxAmplification(t *testing.T) {
+	software := NewSoftware("software of server with long name")
+	s := newTestServer(t, WithMaxAmplification(2), WithSoftware(software.String()))
+	if s.amplified(40, 20) {
+		t.Error("40 bytes response to 20 bytes request should be sent")
+	}
+	if !s.amplified(41, 20) {
+		t.Error("41 bytes response to 20 bytes request should be dropped")
+	}
+	if newTestServer(t).amplified(1500, 20) {
+		t.Error("should not be limited by default")
+	}
+	addr, errs := listenServer(t, "udp", s)
+	conn, err := net.Dial("udp4", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// Binding response with SOFTWARE is larger than twice of empty
+	// request, but not of request with SOFTWARE.
+	if _, err = conn.Write(MustBuild(TransactionID, BindingRequest).Raw); err != nil {
+		t.Fatal(err)
+	}
+	req := MustBuild(TransactionID, BindingRequest, software)
+	if _, err = conn.Write(req.Raw); err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(Message)
+	if _, err = m.Write(buf[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != BindingSuccess || m.TransactionID != req.TransactionID {
+		t.Errorf("unexpected %s", m)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	<-errs
+	if dropped := s.Stats().Snapshot().Dropped; dropped != 1 {
+		t.Errorf("unexpected dropped %d", dropped)
+	}
+}
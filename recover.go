@@ -0,0 +1,79 @@
+package stun
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+)
+
+// WithServerErrorLog sets logger of panics that are recovered while
+// processing messages, defaults to standard logger of log package.
+func WithServerErrorLog(l *log.Logger) ServerOption {
+	return func(s *Server) {
+		s.errorLog = l
+	}
+}
+
+// WithErrorLog sets logger of panics that are recovered in read loop of
+// client, e.g. in handlers, defaults to standard logger of log package.
+func WithErrorLog(l *log.Logger) ClientOption {
+	return func(c *Client) {
+		c.errorLog = l
+	}
+}
+
+// redacted returns description of m for logs without attribute values,
+// which can contain credentials.
+func redacted(m *Message) string {
+	types := make([]string, 0, len(m.Attributes))
+	for _, a := range m.Attributes {
+		types = append(types, a.Type.String())
+	}
+	tID := base64.StdEncoding.EncodeToString(m.TransactionID[:])
+	return fmt.Sprintf("%s l=%d attrs=[%s] id=%s", m.Type, m.Length, strings.Join(types, " "), tID)
+}
+
+// logPanic logs recovered panic p that was caused by m with stack.
+func logPanic(l *log.Logger, prefix string, p interface{}, m *Message) {
+	printf := log.Printf
+	if l != nil {
+		printf = l.Printf
+	}
+	printf("%s: panic processing %s: %v\n%s", prefix, redacted(m), p, debug.Stack())
+}
+
+// safeProcess is process that recovers from panic, e.g. in handlers,
+// dropping req, so one message can't stop serving.
+func (s *Server) safeProcess(res, req *Message, data []byte, addr net.Addr) (ok bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.stats.inc(&s.stats.panics)
+			s.stats.inc(&s.stats.dropped)
+			logPanic(s.errorLog, "server", p, req)
+			ok = false
+		}
+	}()
+	return s.process(res, req, data, addr)
+}
+
+// Panics returns count of panics that were recovered in read loop of
+// client, e.g. in handlers of transactions or indications.
+func (c *Client) Panics() uint64 {
+	return atomic.LoadUint64(&c.panics)
+}
+
+// process passes m to agent, recovering from panic, so one message
+// can't stop read loop.
+func (c *Client) process(m *Message) error {
+	defer func() {
+		if p := recover(); p != nil {
+			atomic.AddUint64(&c.panics, 1)
+			logPanic(c.errorLog, "client", p, m)
+		}
+	}()
+	return c.a.Process(m)
+}
@@ -0,0 +1,131 @@
+package stun
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestServer_safeProcess(t *testing.T) {
+	var (
+		addr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+		logs = new(bytes.Buffer)
+	)
+	s := newTestServer(t,
+		WithAllocationLimit(1),
+		WithServerErrorLog(log.New(logs, "", 0)),
+		WithServerHandler(func(res, req *Message, a net.Addr) {
+			panic("handler")
+		}),
+	)
+	for i := 0; i < 2; i++ {
+		req := MustBuild(TransactionID, allocateRequest, NewUsername("secret-user"))
+		if s.safeProcess(new(Message), new(Message), req.Raw, addr) {
+			t.Fatal("response should not be sent")
+		}
+		if s.Allocations(addr) != 0 {
+			t.Fatal("allocation should be released")
+		}
+	}
+	stats := s.Stats().Snapshot()
+	if stats.Panics != 2 || stats.Dropped != 2 {
+		t.Errorf("unexpected stats %+v", stats.StatsCounters)
+	}
+	if !strings.Contains(logs.String(), "server: panic processing Allocate request") {
+		t.Errorf("unexpected log: %s", logs)
+	}
+	if !strings.Contains(logs.String(), "USERNAME") || strings.Contains(logs.String(), "secret-user") {
+		t.Errorf("value of attribute should be redacted: %s", logs)
+	}
+}
+
+func TestServer_recover(t *testing.T) {
+	s := newTestServer(t,
+		WithServerErrorLog(log.New(new(bytes.Buffer), "", 0)),
+		WithServerHandler(func(res, req *Message, a net.Addr) {
+			panic("handler")
+		}),
+	)
+	addr, _ := listenServer(t, "udp", s)
+	defer s.Close()
+	c, err := Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err = c.Indicate(MustBuild(TransactionID, NewType(MethodSend, ClassIndication))); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+		if e.Error != nil {
+			t.Error(e.Error)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if panics := s.Stats().Snapshot().Panics; panics != 1 {
+		t.Errorf("unexpected panics %d", panics)
+	}
+}
+
+func TestClient_recover(t *testing.T) {
+	connL, connR := net.Pipe()
+	defer connL.Close()
+	logs := new(bytes.Buffer)
+	c, err := NewClient(connR,
+		WithErrorLog(log.New(logs, "", 0)),
+		WithIndicationHandler(NewType(MethodData, ClassIndication), func(e Event) {
+			panic("indication")
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, readErr := connL.Read(buf)
+			if readErr != nil {
+				return
+			}
+			req := new(Message)
+			if _, readErr = req.Write(buf[:n]); readErr != nil {
+				t.Error(readErr)
+				return
+			}
+			data := MustBuild(TransactionID, NewType(MethodData, ClassIndication), NewUsername("secret-user"))
+			if _, writeErr := connL.Write(data.Raw); writeErr != nil {
+				return
+			}
+			if _, writeErr := connL.Write(MustBuild(req, BindingSuccess).Raw); writeErr != nil {
+				return
+			}
+		}
+	}()
+	// Waiter of Do is released if handler panics.
+	if err = c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+		panic("transaction")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+		if e.Error != nil {
+			t.Error(e.Error)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Messages are processed in order, so both indications are handled.
+	if panics := c.Panics(); panics != 3 {
+		t.Errorf("unexpected panics %d", panics)
+	}
+	if !strings.Contains(logs.String(), "client: panic processing Data indication") {
+		t.Errorf("unexpected log: %s", logs)
+	}
+	if strings.Contains(logs.String(), "secret-user") {
+		t.Errorf("value of attribute should be redacted: %s", logs)
+	}
+}
@@ -0,0 +1,127 @@
+package stun
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strconv"
+)
+
+const defaultRedirectLimit = 3
+
+// ErrRedirectNoTLSConfig means that redirect of client that is connected
+// over TLS can't be followed by default Dial, because Redirector has no
+// TLSConfig. Redirect is not followed over TCP, which is a downgrade.
+var ErrRedirectNoTLSConfig = errors.New("no TLSConfig to follow redirect over TLS")
+
+// Redirector performs transactions, following 300 (Try Alternate)
+// error responses with ALTERNATE-SERVER attribute by retrying
+// transaction on alternate server.
+//
+// RFC 5389 Section 11
+type Redirector struct {
+	// Limit is maximum count of followed redirects per transaction.
+	// Defaults to 3.
+	Limit int
+
+	// Approve is called before following redirect to s, the redirect
+	// is not followed if false is returned and response is passed to
+	// the transaction callback. Nil Approve approves all redirects.
+	//
+	// Approve is called from client read loop, so it should not block.
+	Approve func(s AlternateServer) bool
+
+	// Dial connects to alternate server. Defaults to DialTLS if
+	// TLSConfig is set, otherwise to Dial with the "udp" network or
+	// "tcp" network for stream clients. Redirects of clients that are
+	// connected over TLS fail with ErrRedirectNoTLSConfig if TLSConfig
+	// is not set.
+	Dial func(c *Client, s AlternateServer) (*Client, error)
+
+	// TLSConfig enables following redirects over TLS with default Dial.
+	// Server certificate is verified against ALTERNATE-DOMAIN if it is
+	// present in response, otherwise against TLSConfig.ServerName, which
+	// should be set to the original domain.
+	TLSConfig *tls.Config
+}
+
+// Do performs transaction m on c like Client.Do, following redirects.
+// Returns the client that performed the last transaction, which is c if
+// no redirects were followed. Otherwise the returned client should be
+// closed by caller; intermediate clients are closed by Do.
+func (r *Redirector) Do(c *Client, m *Message, f func(Event)) (*Client, error) {
+	limit := r.Limit
+	if limit == 0 {
+		limit = defaultRedirectLimit
+	}
+	current := c
+	for redirects := 0; ; redirects++ {
+		var (
+			alt      AlternateServer
+			domain   string
+			redirect bool
+		)
+		err := current.Do(m, func(e Event) {
+			if redirects < limit && r.shouldRedirect(e, &alt, &domain) {
+				redirect = true
+				return
+			}
+			f(e)
+		})
+		if err != nil || !redirect {
+			if err != nil && current != c {
+				_ = current.Close()
+				current = nil
+			}
+			return current, err
+		}
+		next, dialErr := r.dial(current, alt, domain)
+		if current != c {
+			_ = current.Close()
+		}
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		current = next
+	}
+}
+
+func (r *Redirector) shouldRedirect(e Event, alt *AlternateServer, domain *string) bool {
+	if e.Error != nil || e.Message.Type.Class != ClassErrorResponse {
+		return false
+	}
+	var code ErrorCodeAttribute
+	if err := code.GetFrom(e.Message); err != nil || code.Code != CodeTryAlternate {
+		return false
+	}
+	if err := alt.GetFrom(e.Message); err != nil {
+		return false
+	}
+	var d AlternateDomain
+	if err := d.GetFrom(e.Message); err == nil {
+		*domain = d.String()
+	}
+	return r.Approve == nil || r.Approve(*alt)
+}
+
+func (r *Redirector) dial(c *Client, s AlternateServer, domain string) (*Client, error) {
+	if r.Dial != nil {
+		return r.Dial(c, s)
+	}
+	address := net.JoinHostPort(s.IP.String(), strconv.Itoa(s.Port))
+	if r.TLSConfig != nil {
+		config := r.TLSConfig.Clone()
+		if domain != "" {
+			config.ServerName = domain
+		}
+		return DialTLS(address, config)
+	}
+	if _, ok := c.c.(*tls.Conn); ok {
+		return nil, ErrRedirectNoTLSConfig
+	}
+	network := "udp"
+	if c.stream {
+		network = "tcp"
+	}
+	return Dial(network, address)
+}
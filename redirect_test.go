@@ -0,0 +1,245 @@
+package stun
+
+import (
+	"crypto/tls"
+	"net"
+	"testing"
+)
+
+// serveUDP starts UDP server on loopback that responds to requests
+// with messages returned by h.
+func serveUDP(t *testing.T, h func(req *Message) *Message) (*net.UDPAddr, func()) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buf := make([]byte, 1024)
+		req := new(Message)
+		for {
+			n, addr, readErr := conn.ReadFromUDP(buf)
+			if readErr != nil {
+				return
+			}
+			if Decode(buf[:n], req) != nil {
+				continue
+			}
+			_, _ = conn.WriteToUDP(h(req).Raw, addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr), func() {
+		_ = conn.Close()
+	}
+}
+
+func tryAlternate(addr *net.UDPAddr) func(req *Message) *Message {
+	return func(req *Message) *Message {
+		return MustBuild(req, NewType(req.Type.Method, ClassErrorResponse),
+			CodeTryAlternate,
+			&AlternateServer{IP: addr.IP, Port: addr.Port},
+		)
+	}
+}
+
+func TestRedirector_Do(t *testing.T) {
+	final, closeFinal := serveUDP(t, func(req *Message) *Message {
+		return MustBuild(req, BindingSuccess)
+	})
+	defer closeFinal()
+	middle, closeMiddle := serveUDP(t, tryAlternate(final))
+	defer closeMiddle()
+	first, closeFirst := serveUDP(t, tryAlternate(middle))
+	defer closeFirst()
+
+	t.Run("Follow", func(t *testing.T) {
+		c, err := Dial("udp4", first.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		var (
+			r        Redirector
+			approved []int
+		)
+		r.Approve = func(s AlternateServer) bool {
+			approved = append(approved, s.Port)
+			return true
+		}
+		last, err := r.Do(c, MustBuild(TransactionID, BindingRequest), func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+				return
+			}
+			if e.Message.Type != BindingSuccess {
+				t.Error("unexpected type", e.Message.Type)
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last == c {
+			t.Error("last client should be dialed")
+		}
+		if err = last.Close(); err != nil {
+			t.Error(err)
+		}
+		if len(approved) != 2 || approved[0] != middle.Port || approved[1] != final.Port {
+			t.Error("unexpected redirects", approved)
+		}
+	})
+	for _, tc := range []struct {
+		name string
+		r    Redirector
+	}{
+		{"Limit", Redirector{Limit: 1}},
+		{"NotApproved", Redirector{Approve: func(AlternateServer) bool { return false }}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := Dial("udp4", first.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			last, err := tc.r.Do(c, MustBuild(TransactionID, BindingRequest), func(e Event) {
+				if e.Error != nil {
+					t.Error(e.Error)
+					return
+				}
+				var code ErrorCodeAttribute
+				if err := code.GetFrom(e.Message); err != nil || code.Code != CodeTryAlternate {
+					t.Error("should pass not followed redirect to callback")
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if last != c {
+				_ = last.Close()
+			}
+		})
+	}
+}
+
+func TestRedirector_TLS(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfig(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, acceptErr := l.Accept()
+			if acceptErr != nil {
+				return
+			}
+			req := new(Message)
+			if readErr := readStreamMessage(conn, req); readErr == nil && req.Decode() == nil {
+				_, _ = conn.Write(MustBuild(req, BindingSuccess).Raw)
+			}
+			conn.Close()
+		}
+	}()
+	alternate := l.Addr().(*net.TCPAddr)
+	// Certificate is valid only for "localhost", so original domain
+	// fails verification.
+	clientConfig.ServerName = "stun.example.org"
+	for _, tc := range []struct {
+		name     string
+		domain   string
+		verified bool
+	}{
+		{"AlternateDomain", "localhost", true},
+		{"OriginalDomain", "", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			first, closeFirst := serveUDP(t, func(req *Message) *Message {
+				setters := []Setter{
+					req, NewType(req.Type.Method, ClassErrorResponse), CodeTryAlternate,
+					&AlternateServer{IP: alternate.IP, Port: alternate.Port},
+				}
+				if tc.domain != "" {
+					setters = append(setters, NewAlternateDomain(tc.domain))
+				}
+				return MustBuild(setters...)
+			})
+			defer closeFirst()
+			c, err := Dial("udp4", first.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			r := Redirector{TLSConfig: clientConfig}
+			last, err := r.Do(c, MustBuild(TransactionID, BindingRequest), func(e Event) {
+				if e.Error != nil || e.Message.Type != BindingSuccess {
+					t.Error("unexpected event", e.Error, e.Message)
+				}
+			})
+			if !tc.verified {
+				if err == nil {
+					t.Error("should fail certificate verification")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !last.stream {
+				t.Error("should redirect over tls")
+			}
+			_ = last.Close()
+		})
+	}
+}
+
+func TestRedirector_NoTLSConfig(t *testing.T) {
+	serverConfig, clientConfig := newTestTLSConfig(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	alternate, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alternate.Close()
+	accepted := make(chan struct{}, 1)
+	go func() {
+		if conn, acceptErr := alternate.Accept(); acceptErr == nil {
+			accepted <- struct{}{}
+			conn.Close()
+		}
+	}()
+	go func() {
+		conn, acceptErr := l.Accept()
+		if acceptErr != nil {
+			return
+		}
+		defer conn.Close()
+		req := new(Message)
+		for readStreamMessage(conn, req) == nil && req.Decode() == nil {
+			addr := alternate.Addr().(*net.TCPAddr)
+			_, _ = conn.Write(MustBuild(req, NewType(req.Type.Method, ClassErrorResponse),
+				CodeTryAlternate,
+				&AlternateServer{IP: addr.IP, Port: addr.Port},
+			).Raw)
+		}
+	}()
+	c, err := DialTLS(l.Addr().String(), clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var r Redirector
+	if _, err = r.Do(c, MustBuild(TransactionID, BindingRequest), func(e Event) {
+		t.Error("redirect should not be passed to callback")
+	}); err != ErrRedirectNoTLSConfig {
+		t.Errorf("unexpected error %v", err)
+	}
+	select {
+	case <-accepted:
+		t.Error("redirect should not be followed over tcp")
+	default:
+	}
+}
@@ -0,0 +1,80 @@
+package stun
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"sync"
+)
+
+// ListenPacketReusePort opens n UDP sockets that are bound to the same
+// address with SO_REUSEPORT, so kernel balances datagrams between them.
+// If n is not positive, runtime.GOMAXPROCS(0) sockets are opened. If
+// port of address is zero, all sockets are bound to the port of the
+// first one. Supported only on Linux.
+func ListenPacketReusePort(network, address string, n int) ([]net.PacketConn, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	var (
+		lc    = net.ListenConfig{Control: reusePortControl}
+		conns = make([]net.PacketConn, 0, n)
+	)
+	for i := 0; i < n; i++ {
+		conn, err := lc.ListenPacket(context.Background(), network, address)
+		if err != nil {
+			for _, c := range conns {
+				_ = c.Close()
+			}
+			return nil, err
+		}
+		if i == 0 {
+			address = conn.LocalAddr().String()
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// ListenAndServeReusePort listens on n UDP sockets like
+// ListenPacketReusePort and serves each one by its own goroutine with
+// ServePacket, because single reader caps throughput on multi-core
+// hosts. Every reader has its own buffer and messages, so readers do
+// not contend. If one of sockets fails, the others are closed, and
+// the first error is returned after all readers stop.
+func (s *Server) ListenAndServeReusePort(network, address string, n int) error {
+	conns, err := ListenPacketReusePort(network, address, n)
+	if err != nil {
+		return err
+	}
+	return s.servePackets(conns)
+}
+
+// servePackets serves conns concurrently, returning first error.
+func (s *Server) servePackets(conns []net.PacketConn) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn net.PacketConn) {
+			defer wg.Done()
+			serveErr := s.ServePacket(conn)
+			once.Do(func() {
+				firstErr = serveErr
+				for _, c := range conns {
+					_ = c.Close()
+				}
+			})
+		}(conn)
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,22 @@
+// +build linux
+
+package stun
+
+import "syscall"
+
+// soReusePort is SO_REUSEPORT socket option, which is not defined by
+// syscall package on every Linux architecture.
+const soReusePort = 0xf
+
+// reusePortControl is net.ListenConfig control hook that enables
+// SO_REUSEPORT, so kernel balances datagrams between sockets that are
+// bound to the same address.
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var setErr error
+	if err := c.Control(func(fd uintptr) {
+		setErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	}); err != nil {
+		return err
+	}
+	return setErr
+}
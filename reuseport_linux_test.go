@@ -0,0 +1,73 @@
+// +build linux
+
+package stun
+
+import (
+	"net"
+	"testing"
+)
+
+func TestListenPacketReusePort(t *testing.T) {
+	conns, err := ListenPacketReusePort("udp4", "127.0.0.1:0", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 4 {
+		t.Fatalf("unexpected count %d", len(conns))
+	}
+	addr := conns[0].LocalAddr().String()
+	for _, conn := range conns[1:] {
+		if conn.LocalAddr().String() != addr {
+			t.Errorf("%s should be bound to %s", conn.LocalAddr(), addr)
+		}
+	}
+	s := newTestServer(t)
+	errs := make(chan error, 1)
+	go func() { errs <- s.servePackets(conns) }()
+	const clients = 16
+	for i := 0; i < clients; i++ {
+		c, dialErr := Dial("udp4", addr)
+		if dialErr != nil {
+			t.Fatal(dialErr)
+		}
+		if doErr := c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+			if e.Error != nil {
+				t.Fatal(e.Error)
+			}
+			if e.Message.Type != BindingSuccess {
+				t.Errorf("unexpected type %s", e.Message.Type)
+			}
+		}); doErr != nil {
+			t.Fatal(doErr)
+		}
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}
+	if received := s.Stats().Snapshot().Received; received != clients {
+		t.Errorf("received %d, expected %d", received, clients)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-errs; err != ErrServerClosed {
+		t.Errorf("unexpected error %v", err)
+	}
+	t.Run("Busy", func(t *testing.T) {
+		// Socket without SO_REUSEPORT does not share its port.
+		conn, listenErr := net.ListenPacket("udp4", "127.0.0.1:0")
+		if listenErr != nil {
+			t.Fatal(listenErr)
+		}
+		defer conn.Close()
+		if _, listenErr = ListenPacketReusePort("udp4", conn.LocalAddr().String(), 2); listenErr == nil {
+			t.Error("should fail")
+		}
+	})
+}
+
+func TestListenPacketReusePort_Network(t *testing.T) {
+	if _, err := ListenPacketReusePort("tcp", "127.0.0.1:0", 1); err == nil {
+		t.Error("should fail")
+	}
+}
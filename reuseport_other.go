@@ -0,0 +1,16 @@
+// +build !linux
+
+package stun
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ErrReusePortUnsupported means that SO_REUSEPORT is not supported on
+// current platform.
+var ErrReusePortUnsupported = errors.New("SO_REUSEPORT is not supported")
+
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return ErrReusePortUnsupported
+}
@@ -0,0 +1,160 @@
+package stun
+
+import "errors"
+
+// ChangeRequest represents CHANGE-REQUEST attribute, asking server to
+// send response from different IP address or port.
+//
+// RFC 5780 Section 7.2
+type ChangeRequest struct {
+	ChangeIP   bool
+	ChangePort bool
+}
+
+// constants for CHANGE-REQUEST encoding.
+const (
+	changeRequestSize = 4
+	changeIPFlag      = 0x04
+	changePortFlag    = 0x02
+)
+
+// AddTo adds CHANGE-REQUEST attribute to message.
+func (c ChangeRequest) AddTo(m *Message) error {
+	v := make([]byte, changeRequestSize)
+	if c.ChangeIP {
+		v[3] |= changeIPFlag
+	}
+	if c.ChangePort {
+		v[3] |= changePortFlag
+	}
+	m.Add(AttrChangeRequest, v)
+	return nil
+}
+
+// GetFrom decodes CHANGE-REQUEST from message.
+func (c *ChangeRequest) GetFrom(m *Message) error {
+	v, err := m.Get(AttrChangeRequest)
+	if err != nil {
+		return err
+	}
+	if err = CheckSize(AttrChangeRequest, len(v), changeRequestSize); err != nil {
+		return err
+	}
+	c.ChangeIP = v[3]&changeIPFlag != 0
+	c.ChangePort = v[3]&changePortFlag != 0
+	return nil
+}
+
+// ResponseOrigin represents RESPONSE-ORIGIN attribute, the address that
+// response is sent from.
+//
+// RFC 5780 Section 7.3
+type ResponseOrigin MappedAddress
+
+func (o ResponseOrigin) String() string {
+	return MappedAddress(o).String()
+}
+
+// AddTo adds RESPONSE-ORIGIN attribute to message.
+func (o *ResponseOrigin) AddTo(m *Message) error {
+	return (*MappedAddress)(o).addAs(m, AttrResponseOrigin)
+}
+
+// GetFrom decodes RESPONSE-ORIGIN from message.
+func (o *ResponseOrigin) GetFrom(m *Message) error {
+	return (*MappedAddress)(o).getAs(m, AttrResponseOrigin)
+}
+
+// OtherAddress represents OTHER-ADDRESS attribute, the alternate
+// address and port of server that differ from ones the request was
+// received on.
+//
+// RFC 5780 Section 7.4
+type OtherAddress MappedAddress
+
+func (o OtherAddress) String() string {
+	return MappedAddress(o).String()
+}
+
+// AddTo adds OTHER-ADDRESS attribute to message.
+func (o *OtherAddress) AddTo(m *Message) error {
+	return (*MappedAddress)(o).addAs(m, AttrOtherAddress)
+}
+
+// GetFrom decodes OTHER-ADDRESS from message.
+func (o *OtherAddress) GetFrom(m *Message) error {
+	return (*MappedAddress)(o).getAs(m, AttrOtherAddress)
+}
+
+// ResponsePort represents RESPONSE-PORT attribute, the port that
+// response should be sent to.
+//
+// RFC 5780 Section 7.5
+type ResponsePort uint16
+
+// Value is 16-bit port followed by 2 bytes of padding.
+const (
+	responsePortSize      = 4
+	responsePortValueSize = 2
+)
+
+// AddTo adds RESPONSE-PORT attribute to message.
+func (p ResponsePort) AddTo(m *Message) error {
+	v := make([]byte, responsePortSize)
+	bin.PutUint16(v, uint16(p))
+	m.Add(AttrResponsePort, v)
+	return nil
+}
+
+// GetFrom decodes RESPONSE-PORT from message. Value without padding is
+// accepted too.
+func (p *ResponsePort) GetFrom(m *Message) error {
+	v, err := m.Get(AttrResponsePort)
+	if err != nil {
+		return err
+	}
+	if len(v) != responsePortValueSize {
+		if err = CheckSize(AttrResponsePort, len(v), responsePortSize); err != nil {
+			return err
+		}
+	}
+	*p = ResponsePort(bin.Uint16(v))
+	return nil
+}
+
+// Padding represents PADDING attribute of specified length, which is
+// used to test fragmentation behavior of NAT.
+//
+// RFC 5780 Section 7.6
+type Padding int
+
+// ErrBadPaddingSize means that PADDING attribute length is not a
+// multiple of 4 bytes.
+var ErrBadPaddingSize = errors.New("bad PADDING size")
+
+const maxPaddingB = 0xFFFC
+
+// AddTo adds PADDING attribute of p zero bytes to message.
+func (p Padding) AddTo(m *Message) error {
+	if p < 0 || p%padding != 0 {
+		return ErrBadPaddingSize
+	}
+	if err := CheckOverflow(AttrPadding, int(p), maxPaddingB); err != nil {
+		return err
+	}
+	m.Add(AttrPadding, make([]byte, p))
+	return nil
+}
+
+// GetFrom decodes length of PADDING attribute from message.
+func (p *Padding) GetFrom(m *Message) error {
+	v, err := m.Get(AttrPadding)
+	if err != nil {
+		return err
+	}
+	if len(v)%padding != 0 {
+		return ErrBadPaddingSize
+	}
+	*p = Padding(len(v))
+	return nil
+}
@@ -0,0 +1,107 @@
+package stun
+
+import (
+	"net"
+	"testing"
+)
+
+func TestChangeRequest(t *testing.T) {
+	for _, c := range []ChangeRequest{
+		{},
+		{ChangeIP: true},
+		{ChangePort: true},
+		{ChangeIP: true, ChangePort: true},
+	} {
+		m := MustBuild(BindingRequest, c)
+		var got ChangeRequest
+		if err := got.GetFrom(m); err != nil {
+			t.Fatal(err)
+		}
+		if got != c {
+			t.Errorf("%+v != %+v", got, c)
+		}
+	}
+	v, _ := MustBuild(BindingRequest, ChangeRequest{ChangeIP: true, ChangePort: true}).Get(AttrChangeRequest)
+	if len(v) != 4 || v[3] != 0x06 {
+		t.Errorf("unexpected value %x", v)
+	}
+	m := new(Message)
+	m.Add(AttrChangeRequest, []byte{1})
+	var c ChangeRequest
+	if err := c.GetFrom(m); !IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestResponseOrigin_OtherAddress(t *testing.T) {
+	origin := &ResponseOrigin{IP: net.IPv4(192, 0, 2, 1), Port: 3478}
+	other := &OtherAddress{IP: net.ParseIP("2001:db8::1"), Port: 3479}
+	m := MustBuild(BindingSuccess, origin, other)
+	var (
+		gotOrigin ResponseOrigin
+		gotOther  OtherAddress
+	)
+	if err := gotOrigin.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := gotOther.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if gotOrigin.String() != "192.0.2.1:3478" {
+		t.Errorf("unexpected RESPONSE-ORIGIN %s", gotOrigin)
+	}
+	if gotOther.String() != "[2001:db8::1]:3479" {
+		t.Errorf("unexpected OTHER-ADDRESS %s", gotOther)
+	}
+	if err := gotOrigin.GetFrom(new(Message)); err != ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestResponsePort(t *testing.T) {
+	m := MustBuild(BindingRequest, ResponsePort(5000))
+	if v, _ := m.Get(AttrResponsePort); len(v) != 4 {
+		t.Errorf("value should be padded to 4 bytes, got %d", len(v))
+	}
+	var p ResponsePort
+	if err := p.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if p != 5000 {
+		t.Errorf("unexpected port %d", p)
+	}
+	unpadded := new(Message)
+	unpadded.Add(AttrResponsePort, []byte{0x13, 0x89})
+	if err := p.GetFrom(unpadded); err != nil || p != 5001 {
+		t.Error("unexpected result", p, err)
+	}
+	invalid := new(Message)
+	invalid.Add(AttrResponsePort, []byte{1, 2, 3})
+	if err := p.GetFrom(invalid); !IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestPadding_AddTo(t *testing.T) {
+	m := MustBuild(BindingRequest, Padding(64))
+	var p Padding
+	if err := p.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if p != 64 {
+		t.Errorf("unexpected padding %d", p)
+	}
+	for _, invalid := range []Padding{-4, 3} {
+		if err := invalid.AddTo(new(Message)); err != ErrBadPaddingSize {
+			t.Errorf("%d: unexpected error %v", invalid, err)
+		}
+	}
+	if err := Padding(0x10000).AddTo(new(Message)); !IsAttrSizeOverflow(err) {
+		t.Error("unexpected error", err)
+	}
+	invalid := new(Message)
+	invalid.Add(AttrPadding, []byte{1, 2})
+	if err := p.GetFrom(invalid); err != ErrBadPaddingSize {
+		t.Error("unexpected error", err)
+	}
+}
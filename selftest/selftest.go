@@ -0,0 +1,349 @@
+// Package selftest implements diagnostic of local NAT and STUN server
+// reachability with human-readable summary, e.g. for support tickets.
+package selftest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"time"
+
+	"github.com/pion/stun"
+	"github.com/pion/stun/natdiscovery"
+)
+
+// Result is outcome of single check.
+type Result byte
+
+// Possible results.
+const (
+	Pass Result = iota
+	Fail
+	Skip
+)
+
+func (r Result) String() string {
+	switch r {
+	case Pass:
+		return "PASS"
+	case Fail:
+		return "FAIL"
+	default:
+		return "SKIP"
+	}
+}
+
+// Check is result of single check of self-test.
+type Check struct {
+	Name     string
+	Result   Result
+	Detail   string // e.g. mapped address or reason of failure
+	Duration time.Duration
+}
+
+// Report is result of self-test.
+type Report struct {
+	Server string
+	Time   time.Time // start of self-test
+	Checks []Check
+}
+
+// Passed returns true if no check failed.
+func (r *Report) Passed() bool {
+	for _, c := range r.Checks {
+		if c.Result == Fail {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTo writes summary of r to w, implementing io.WriterTo.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	printf := func(format string, a ...interface{}) error {
+		n, err := fmt.Fprintf(w, format, a...)
+		written += int64(n)
+		return err
+	}
+	if err := printf("self-test of %s at %s (%s/%s)\n",
+		r.Server, r.Time.UTC().Format(time.RFC3339), runtime.GOOS, runtime.GOARCH,
+	); err != nil {
+		return written, err
+	}
+	for _, c := range r.Checks {
+		if err := printf("%s %-11s %s (%s)\n",
+			c.Result, c.Name, c.Detail, c.Duration.Round(time.Millisecond),
+		); err != nil {
+			return written, err
+		}
+	}
+	result := Pass
+	if !r.Passed() {
+		result = Fail
+	}
+	err := printf("result: %s\n", result)
+	return written, err
+}
+
+const defaultTimeout = 3 * time.Second
+
+// Tester runs self-test against STUN server: resolution of server
+// address, Binding round trip, FINGERPRINT and MESSAGE-INTEGRITY round
+// trips, RFC 5780 NAT behavior discovery and keepalive timing.
+type Tester struct {
+	// Server is address of STUN server, e.g. "stun.example.org:3478".
+	Server string
+
+	// Username and Password are long-term credentials for integrity
+	// check, which is skipped if Username is blank.
+	Username string
+	Password string
+
+	// KeepaliveInterval is time between two Binding requests on the
+	// same socket, checking that NAT keeps mapping if keepalives are sent
+	// with such interval. Defaults to stun.DefaultKeepaliveInterval,
+	// negative value skips the check.
+	KeepaliveInterval time.Duration
+
+	// Timeout of single transaction, including retransmissions.
+	// Defaults to 3 seconds.
+	Timeout time.Duration
+}
+
+// errNotAuthenticated means that response has no MESSAGE-INTEGRITY.
+var errNotAuthenticated = errors.New("response is not authenticated")
+
+// Run runs checks in order and returns report. Checks that depend on
+// failed ones are skipped.
+func (t *Tester) Run() *Report {
+	r := &Report{
+		Server: t.Server,
+		Time:   time.Now(),
+	}
+	timeout := t.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	interval := t.KeepaliveInterval
+	if interval == 0 {
+		interval = stun.DefaultKeepaliveInterval
+	}
+	skipAll := func(reason string, names ...string) {
+		for _, name := range names {
+			r.Checks = append(r.Checks, Check{Name: name, Result: Skip, Detail: reason})
+		}
+	}
+
+	var server *net.UDPAddr
+	if !r.run("resolve", func() (string, error) {
+		addr, err := net.ResolveUDPAddr("udp", t.Server)
+		if err != nil {
+			return "", err
+		}
+		server = addr
+		return addr.String(), nil
+	}) {
+		skipAll("server is not resolved", "binding", "fingerprint", "integrity", "discovery", "keepalive")
+		return r
+	}
+
+	conn, err := net.DialUDP("udp", nil, server)
+	if err != nil {
+		r.Checks = append(r.Checks, Check{Name: "binding", Result: Fail, Detail: err.Error()})
+		skipAll("server is not reachable", "fingerprint", "integrity", "discovery", "keepalive")
+		return r
+	}
+	// Requests are sent at 0, RTO and 3*RTO, timing out at 7*RTO.
+	c, err := stun.NewClient(conn,
+		stun.WithRTO(timeout/7), stun.WithRc(3), stun.WithRm(4),
+	)
+	if err != nil {
+		_ = conn.Close()
+		r.Checks = append(r.Checks, Check{Name: "binding", Result: Fail, Detail: err.Error()})
+		skipAll("server is not reachable", "fingerprint", "integrity", "discovery", "keepalive")
+		return r
+	}
+	defer c.Close()
+	var credentials *stun.LongTermCredentials
+	if t.Username != "" {
+		credentials = &stun.LongTermCredentials{
+			Username: t.Username,
+			Password: t.Password,
+		}
+	}
+
+	var (
+		mapped        stun.XORMappedAddress
+		authenticated bool
+	)
+	if !r.run("binding", func() (string, error) {
+		start := time.Now()
+		res, err := do(c, credentials, stun.BindingRequest)
+		if err != nil {
+			return "", err
+		}
+		if err = mapped.GetFrom(res); err != nil {
+			return "", err
+		}
+		authenticated = res.Contains(stun.AttrMessageIntegrity)
+		detail := fmt.Sprintf("mapped %s, local %s, rtt %s",
+			mapped, conn.LocalAddr(), time.Since(start).Round(time.Millisecond),
+		)
+		var software stun.Software
+		if software.GetFrom(res) == nil {
+			detail += fmt.Sprintf(", software %q", software)
+		}
+		return detail, nil
+	}) {
+		skipAll("server is not reachable", "fingerprint", "integrity", "discovery", "keepalive")
+		return r
+	}
+
+	r.run("fingerprint", func() (string, error) {
+		res, err := do(c, credentials, stun.BindingRequest, stun.Fingerprint)
+		if err != nil {
+			return "", err
+		}
+		if !res.Contains(stun.AttrFingerprint) {
+			return "request accepted, response without FINGERPRINT", nil
+		}
+		if err = stun.Fingerprint.Check(res); err != nil {
+			return "", err
+		}
+		return "verified", nil
+	})
+
+	r.run("integrity", func() (string, error) {
+		if credentials == nil {
+			return "", errSkip{"no credentials"}
+		}
+		if !authenticated {
+			return "", errSkip{"server does not authenticate Binding requests"}
+		}
+		// Cached nonce and key are used from the start.
+		res, err := do(c, credentials, stun.BindingRequest)
+		if err != nil {
+			return "", err
+		}
+		if !res.Contains(stun.AttrMessageIntegrity) {
+			return "", errNotAuthenticated
+		}
+		return "verified", nil
+	})
+
+	r.run("discovery", func() (string, error) {
+		if authenticated {
+			return "", errSkip{"server requires authentication"}
+		}
+		d := &natdiscovery.Discoverer{
+			Server:  t.Server,
+			Timeout: timeout,
+		}
+		report, err := d.Discover()
+		if err == natdiscovery.ErrNoOtherAddress {
+			return "", errSkip{"server does not support RFC 5780"}
+		}
+		if err != nil {
+			return "", err
+		}
+		if report.NoNAT {
+			return "no NAT", nil
+		}
+		return fmt.Sprintf("mapping %s, filtering %s", report.Mapping, report.Filtering), nil
+	})
+
+	if interval < 0 {
+		skipAll("disabled", "keepalive")
+		return r
+	}
+	r.run("keepalive", func() (string, error) {
+		time.Sleep(interval)
+		res, err := do(c, credentials, stun.BindingRequest)
+		if err != nil {
+			return "", err
+		}
+		var got stun.XORMappedAddress
+		if err = got.GetFrom(res); err != nil {
+			return "", err
+		}
+		if !got.IP.Equal(mapped.IP) || got.Port != mapped.Port {
+			return "", fmt.Errorf("mapping changed from %s to %s after %s, use shorter interval", mapped, got, interval)
+		}
+		return fmt.Sprintf("mapping kept for %s", interval), nil
+	})
+	return r
+}
+
+// errSkip is returned by check that is not applicable.
+type errSkip struct {
+	reason string
+}
+
+func (e errSkip) Error() string {
+	return e.reason
+}
+
+// run runs check f, appending its result to r. Returns false if check
+// failed.
+func (r *Report) run(name string, f func() (detail string, err error)) bool {
+	start := time.Now()
+	detail, err := f()
+	c := Check{
+		Name:     name,
+		Result:   Pass,
+		Detail:   detail,
+		Duration: time.Since(start),
+	}
+	if skip, ok := err.(errSkip); ok {
+		c.Result, c.Detail = Skip, skip.reason
+	} else if err != nil {
+		c.Result, c.Detail = Fail, err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+	return c.Result != Fail
+}
+
+// do performs transaction with request built from setters, using
+// credentials if not nil, and returns copy of success response.
+func do(c *stun.Client, credentials *stun.LongTermCredentials, setters ...stun.Setter) (*stun.Message, error) {
+	var (
+		res    *stun.Message
+		resErr error
+		err    error
+	)
+	f := func(e stun.Event) {
+		if e.Error != nil {
+			resErr = e.Error
+			return
+		}
+		res = new(stun.Message)
+		e.Message.CloneTo(res)
+	}
+	if credentials != nil {
+		err = credentials.Do(c, f, setters...)
+	} else {
+		err = c.Do(stun.MustBuild(append([]stun.Setter{stun.TransactionID}, setters...)...), f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resErr != nil {
+		return nil, resErr
+	}
+	return res, responseError(res)
+}
+
+// responseError returns error for error response.
+func responseError(res *stun.Message) error {
+	if res.Type.Class != stun.ClassErrorResponse {
+		return nil
+	}
+	var code stun.ErrorCodeAttribute
+	if err := code.GetFrom(res); err != nil {
+		return fmt.Errorf("error response %s", res.Type)
+	}
+	return fmt.Errorf("error response: %s", code)
+}
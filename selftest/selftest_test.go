@@ -0,0 +1,165 @@
+package selftest
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+var withAuth = stun.WithAuth("realm", func(username, realm string) ([]byte, bool) {
+	if username != "user" {
+		return nil, false
+	}
+	return stun.NewLongTermIntegrity(username, realm, "secret"), true
+})
+
+func newServer(t *testing.T, options ...stun.ServerOption) (addr string, closer func()) {
+	s, err := stun.NewServer(append([]stun.ServerOption{stun.WithFingerprint}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = s.ServePacket(conn)
+	}()
+	return conn.LocalAddr().String(), func() {
+		_ = conn.Close()
+	}
+}
+
+func results(r *Report) map[string]Result {
+	m := make(map[string]Result)
+	for _, c := range r.Checks {
+		m[c.Name] = c.Result
+	}
+	return m
+}
+
+func TestTester_Run(t *testing.T) {
+	addr, closer := newServer(t)
+	defer closer()
+	authAddr, authCloser := newServer(t, withAuth)
+	defer authCloser()
+	for _, tc := range []struct {
+		name     string
+		server   string
+		tester   Tester
+		expected map[string]Result
+		passed   bool
+	}{
+		{
+			name:   "Pass",
+			server: addr,
+			tester: Tester{
+				KeepaliveInterval: time.Millisecond * 10,
+			},
+			expected: map[string]Result{
+				"resolve":     Pass,
+				"binding":     Pass,
+				"fingerprint": Pass,
+				"integrity":   Skip,
+				"discovery":   Skip,
+				"keepalive":   Pass,
+			},
+			passed: true,
+		},
+		{
+			name:   "NotAuthenticated",
+			server: addr,
+			tester: Tester{
+				Username: "user", Password: "secret",
+				KeepaliveInterval: -1,
+			},
+			expected: map[string]Result{
+				"binding":   Pass,
+				"integrity": Skip,
+				"keepalive": Skip,
+			},
+			passed: true,
+		},
+		{
+			name:   "Auth",
+			server: authAddr,
+			tester: Tester{
+				Username: "user", Password: "secret",
+				KeepaliveInterval: time.Millisecond * 10,
+			},
+			expected: map[string]Result{
+				"binding":     Pass,
+				"fingerprint": Pass,
+				"integrity":   Pass,
+				"discovery":   Skip,
+				"keepalive":   Pass,
+			},
+			passed: true,
+		},
+		{
+			name:   "NoCredentials",
+			server: authAddr,
+			expected: map[string]Result{
+				"binding":   Fail,
+				"integrity": Skip,
+			},
+		},
+		{
+			name:   "WrongPassword",
+			server: authAddr,
+			tester: Tester{
+				Username: "user", Password: "wrong",
+			},
+			expected: map[string]Result{
+				"binding":   Fail,
+				"integrity": Skip,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.tester.Server = tc.server
+			tc.tester.Timeout = time.Second
+			r := tc.tester.Run()
+			got := results(r)
+			if len(got) != 6 {
+				t.Errorf("unexpected checks: %v", r.Checks)
+			}
+			for name, result := range tc.expected {
+				if got[name] != result {
+					t.Errorf("%s: %s (expected %s)", name, got[name], result)
+				}
+			}
+			if r.Passed() != tc.passed {
+				t.Errorf("Passed: %v", r.Passed())
+			}
+		})
+	}
+}
+
+func TestTester_Unreachable(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	tester := &Tester{
+		Server:  conn.LocalAddr().String(),
+		Timeout: time.Millisecond * 70,
+	}
+	r := tester.Run()
+	got := results(r)
+	if got["resolve"] != Pass || got["binding"] != Fail || got["keepalive"] != Skip {
+		t.Errorf("unexpected checks: %v", r.Checks)
+	}
+	buf := new(bytes.Buffer)
+	if _, err := r.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "FAIL binding") || !strings.HasSuffix(buf.String(), "result: FAIL\n") {
+		t.Errorf("unexpected summary:\n%s", buf)
+	}
+}
@@ -0,0 +1,664 @@
+package stun
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrServerClosed is returned by Server serve methods after Close or
+// Shutdown.
+var ErrServerClosed = errors.New("server closed")
+
+// ErrUnsupportedAddr means that address is not UDP or TCP address.
+var ErrUnsupportedAddr = errors.New("unsupported address type")
+
+// ServerHandler handles request req from addr that is not handled by
+// Server itself, building response to res, e.g. with
+// res.Build(req, ...) to copy transaction id. Nothing is sent if res is
+// left empty. The req and res are valid only until handler returns.
+type ServerHandler func(res, req *Message, addr net.Addr)
+
+// ServerOption sets some server option.
+type ServerOption func(s *Server)
+
+// WithSoftware sets SOFTWARE attribute of Binding responses.
+func WithSoftware(software string) ServerOption {
+	return func(s *Server) {
+		s.software = NewSoftware(software)
+	}
+}
+
+// WithFingerprint adds FINGERPRINT to all responses that have none.
+func WithFingerprint(s *Server) {
+	s.fingerprint = true
+}
+
+// WithRequireFingerprint makes server silently drop requests and
+// indications without valid FINGERPRINT.
+//
+// RFC 5389 Section 7.3
+func WithRequireFingerprint(s *Server) {
+	s.requireFingerprint = true
+}
+
+// WithRequireIntegrity makes server silently drop requests and
+// indications without USERNAME or valid MESSAGE-INTEGRITY, e.g. for
+// short-term credentials of ICE. The key of user is returned by h with
+// REALM of message, which is empty if there is no such attribute, and
+// responses are signed with it. Binding indications, which are
+// keepalives, are never checked. Cannot be used with WithAuth.
+func WithRequireIntegrity(h AuthHandler) ServerOption {
+	return func(s *Server) {
+		s.requireIntegrity = h
+	}
+}
+
+// WithServerClock sets Clock of server, the source of current time for
+// nonce expiry and rate limiting, defaults to system clock.
+func WithServerClock(clock Clock) ServerOption {
+	return func(s *Server) {
+		s.now = clock.Now
+	}
+}
+
+// WithKnownAttributes adds types of attributes that are handled by
+// application to types that are defined in this package. Requests with
+// other comprehension-required attributes are rejected with 420
+// (Unknown Attribute) and such indications are dropped.
+//
+// RFC 5389 Section 7.3.1
+func WithKnownAttributes(types ...AttrType) ServerOption {
+	return func(s *Server) {
+		s.known.Add(types...)
+	}
+}
+
+// WithServerSpec pins edition of STUN specification that is used by
+// server: attributes of later editions are not known, and with
+// SpecRFC3489 messages without magic cookie are accepted and Binding
+// responses are built as RFC 3489 ones. Defaults to SpecRFC8489.
+func WithServerSpec(spec Spec) ServerOption {
+	return func(s *Server) {
+		s.spec = spec
+	}
+}
+
+// WithServerHandler sets handler of requests and indications other than
+// Binding. By default, requests of other methods are rejected with 400
+// (Bad Request) and indications are ignored.
+func WithServerHandler(h ServerHandler) ServerOption {
+	return func(s *Server) {
+		s.handler = h
+	}
+}
+
+// WithAllowedMethods restricts methods of messages that are served,
+// e.g. to Binding only on discovery servers, so relay functionality of
+// handler is not exposed accidentally. Requests of other methods are
+// rejected with 403 (Forbidden) before authentication and without
+// calling handler, such indications are dropped. All methods are
+// allowed by default.
+func WithAllowedMethods(methods ...Method) ServerOption {
+	return func(s *Server) {
+		if s.methods == nil {
+			s.methods = make(map[Method]struct{}, len(methods))
+		}
+		for _, m := range methods {
+			s.methods[m] = struct{}{}
+		}
+	}
+}
+
+// allowed returns true if method m is allowed by WithAllowedMethods.
+func (s *Server) allowed(m Method) bool {
+	if s.methods == nil {
+		return true
+	}
+	_, ok := s.methods[m]
+	return ok
+}
+
+// bindingIndication is type of keepalives, which are not authenticated.
+//
+// RFC 5245 Section 10
+var bindingIndication = NewType(MethodBinding, ClassIndication)
+
+// defaultServerBufferSize is size of buffer for UDP datagrams, which is
+// enough for any message that can be sent without fragmentation.
+const defaultServerBufferSize = 1500
+
+// Server is STUN server that responds to Binding requests with
+// XOR-MAPPED-ADDRESS of client, see NewServer.
+//
+// RFC 5389 Section 7.3
+type Server struct {
+	stats Stats // first to be 64-bit aligned
+
+	software    Software
+	fingerprint bool
+	handler     ServerHandler
+	bufferSize  int
+	decoder     Decoder
+	known       AttrRegistry
+	spec        Spec
+	methods     map[Method]struct{} // allowed, nil allows all
+
+	requireFingerprint bool
+	requireIntegrity   AuthHandler
+	healthProbe        HealthProbe
+	audit              AuditHandler
+
+	policy           ServerPolicy
+	limiter          rateLimiter
+	maxAmplification int
+	transactions     ipQuota
+	allocations      allocationQuota
+
+	realm         Realm
+	auth          AuthHandler
+	userhash      UserhashLookup
+	nonceLifetime time.Duration
+	nonceSecret   []byte
+	now           func() time.Time
+	errorLog      *log.Logger
+
+	mux      sync.Mutex // guards fields below
+	closed   bool
+	shutdown bool
+	notReady bool
+	closers  map[io.Closer]struct{} // connections and listeners
+
+	wg sync.WaitGroup // serve methods and connections
+}
+
+// NewServer initializes new Server with options, rejecting invalid
+// option values with OptionErr, see also ServerConfig. Use ServePacket,
+// Serve or ListenAndServe to start serving.
+func NewServer(options ...ServerOption) (*Server, error) {
+	s := &Server{
+		bufferSize:    defaultServerBufferSize,
+		decoder:       Decoder{CheckType: true},
+		known:         NewAttrRegistry(),
+		nonceLifetime: DefaultNonceLifetime,
+		policy:        defaultPolicy,
+		now:           time.Now,
+		closers:       make(map[io.Closer]struct{}),
+	}
+	for _, o := range options {
+		o(s)
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	s.decoder.Classic = s.spec == SpecRFC3489
+	s.limiter.memory = &s.stats.memory
+	s.transactions.memory = &s.stats.memory
+	s.allocations.memory = &s.stats.memory
+	if s.auth != nil {
+		s.initAuth()
+	}
+	return s, nil
+}
+
+// validate returns OptionErr if some option value is invalid.
+func (s *Server) validate() error {
+	switch {
+	case s.nonceLifetime <= 0:
+		return OptionErr{Option: "NonceLifetime", Value: s.nonceLifetime}
+	case s.realm != nil && s.auth == nil:
+		return OptionErr{Option: "Auth", Value: s.auth}
+	case s.requireIntegrity != nil && s.auth != nil:
+		// Unauthenticated requests would be dropped instead of challenged.
+		return OptionErr{Option: "RequireIntegrity", Value: s.requireIntegrity}
+	case s.spec.edition() == 0:
+		return OptionErr{Option: "Spec", Value: s.spec}
+	case s.auth != nil && !s.spec.Defines(AttrNonce):
+		// Long-term credential mechanism needs REALM and NONCE.
+		return OptionErr{Option: "Spec", Value: s.spec}
+	case s.userhash != nil && s.auth == nil:
+		return OptionErr{Option: "UserhashLookup", Value: s.userhash}
+	case s.userhash != nil && !s.spec.Defines(AttrUserhash):
+		return OptionErr{Option: "Spec", Value: s.spec}
+	case s.limiter.rate < 0:
+		return OptionErr{Option: "RateLimit", Value: s.limiter.rate}
+	case s.limiter.rate > 0 && s.limiter.burst < 1:
+		return OptionErr{Option: "RateBurst", Value: s.limiter.burst}
+	case s.maxAmplification < 0:
+		return OptionErr{Option: "MaxAmplification", Value: s.maxAmplification}
+	case s.policy == nil:
+		return OptionErr{Option: "Policy", Value: s.policy}
+	case s.transactions.limit < 0:
+		return OptionErr{Option: "TransactionLimit", Value: s.transactions.limit}
+	case s.allocations.limit < 0:
+		return OptionErr{Option: "AllocationLimit", Value: s.allocations.limit}
+	case s.stats.memory.limit < 0:
+		return OptionErr{Option: "MemoryLimit", Value: s.stats.memory.limit}
+	}
+	return nil
+}
+
+// ListenAndServe listens on network and address, serving until Close or
+// Shutdown.
+// Network is "udp", "udp4", "udp6", "tcp", "tcp4" or "tcp6".
+func (s *Server) ListenAndServe(network, address string) error {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		l, err := net.Listen(network, address)
+		if err != nil {
+			return err
+		}
+		return s.Serve(l)
+	default:
+		conn, err := net.ListenPacket(network, address)
+		if err != nil {
+			return err
+		}
+		return s.ServePacket(conn)
+	}
+}
+
+// track adds c to resources that are closed on Close, closing c and
+// returning false if server is already stopped. Every successful track
+// should be followed by untrack.
+func (s *Server) track(c io.Closer) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.closed || s.shutdown {
+		_ = c.Close()
+		return false
+	}
+	s.closers[c] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+// untrack removes c from resources and closes it.
+func (s *Server) untrack(c io.Closer) {
+	s.mux.Lock()
+	delete(s.closers, c)
+	s.mux.Unlock()
+	_ = c.Close()
+	s.wg.Done()
+}
+
+// stopped returns true if Close or Shutdown was called.
+func (s *Server) stopped() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.closed || s.shutdown
+}
+
+// temporary returns true if err is temporary and serving should
+// continue.
+func (s *Server) temporary(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Temporary() && !s.stopped()
+}
+
+// ServePacket serves messages from datagram conn until Close or
+// Shutdown, returning ErrServerClosed. Conn is closed on return.
+func (s *Server) ServePacket(conn net.PacketConn) error {
+	if !s.track(conn) {
+		return ErrServerClosed
+	}
+	defer s.untrack(conn)
+	return s.servePacket(conn)
+}
+
+// servePacket is ServePacket for conn that is already tracked.
+func (s *Server) servePacket(conn net.PacketConn) error {
+	memory := connMemory(s.bufferSize)
+	s.stats.memory.add(memory)
+	defer s.stats.memory.free(memory)
+	var (
+		buf = make([]byte, s.bufferSize)
+		req = new(Message)
+		res = new(Message)
+	)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if s.temporary(err) {
+				continue
+			}
+			if s.stopped() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.safeProcess(res, req, buf[:n], addr) {
+			continue
+		}
+		if s.amplified(len(res.Raw), n) {
+			s.stats.inc(&s.stats.dropped)
+			continue
+		}
+		// Write errors are only counted, client will retransmit request.
+		if _, err = conn.WriteTo(res.Raw, addr); err != nil {
+			s.stats.inc(&s.stats.writeErrors)
+			continue
+		}
+		s.stats.inc(&s.stats.responses)
+	}
+}
+
+// Serve accepts connections from l and serves messages that are framed
+// on them until Close or Shutdown, returning ErrServerClosed. Listener
+// is closed on return, connections are closed on Close or Shutdown.
+func (s *Server) Serve(l net.Listener) error {
+	if !s.track(l) {
+		return ErrServerClosed
+	}
+	defer s.untrack(l)
+	return s.serve(l)
+}
+
+// serve is Serve for l that is already tracked.
+func (s *Server) serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.temporary(err) {
+				continue
+			}
+			if s.stopped() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.track(conn) {
+			return ErrServerClosed
+		}
+		go s.serveStream(conn)
+	}
+}
+
+// ServePacketContext is ServePacket that also stops serving conn when
+// ctx is done, returning ctx.Err().
+func (s *Server) ServePacketContext(ctx context.Context, conn net.PacketConn) error {
+	return serveContext(ctx, conn, func() error { return s.ServePacket(conn) })
+}
+
+// ServeContext is Serve that also stops accepting connections from l
+// when ctx is done, returning ctx.Err(). Connections that are already
+// accepted are served until Close or Shutdown.
+func (s *Server) ServeContext(ctx context.Context, l net.Listener) error {
+	return serveContext(ctx, l, func() error { return s.Serve(l) })
+}
+
+// serveContext calls serve, closing c when ctx is done.
+func serveContext(ctx context.Context, c io.Closer, serve func() error) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = c.Close()
+		case <-done:
+		}
+	}()
+	err := serve()
+	if ctxErr := ctx.Err(); ctxErr != nil && err != ErrServerClosed {
+		return ctxErr
+	}
+	return err
+}
+
+func (s *Server) serveStream(conn net.Conn) {
+	defer s.untrack(conn)
+	memory := connMemory(defaultServerBufferSize)
+	if !s.stats.memory.reserve(memory) {
+		return
+	}
+	defer s.stats.memory.free(memory)
+	var (
+		raw = new(Message)
+		req = new(Message)
+		res = new(Message)
+	)
+	for {
+		if err := readStreamMessage(conn, raw); err != nil {
+			// Framing is lost, no further messages can be read.
+			return
+		}
+		if !s.safeProcess(res, req, raw.Raw, conn.RemoteAddr()) {
+			continue
+		}
+		if _, err := conn.Write(res.Raw); err != nil {
+			s.stats.inc(&s.stats.writeErrors)
+			return
+		}
+		s.stats.inc(&s.stats.responses)
+	}
+}
+
+// process decodes request from data and builds response to res,
+// returning false if there is nothing to send. Messages with invalid
+// type or that fail required checks or policy are dropped.
+func (s *Server) process(res, req *Message, data []byte, addr net.Addr) bool {
+	verdict := s.verdict(addr)
+	if verdict == VerdictIgnore {
+		return false
+	}
+	s.stats.inc(&s.stats.received)
+	if verdict != VerdictAccept {
+		s.stats.inc(&s.stats.dropped)
+		return false
+	}
+	if err := s.decoder.Decode(data, req); err != nil {
+		s.stats.inc(&s.stats.dropped)
+		return false
+	}
+	if s.healthProbe != nil && s.healthProbe(req, addr) {
+		return s.healthCheck(res, req, addr)
+	}
+	res.Reset()
+	var username string // authenticated identity of request
+	if s.audit != nil && req.Type.Class == ClassRequest {
+		defer s.auditRequest(res, req, addr, s.now(), &username)
+	}
+	if s.requireFingerprint && Fingerprint.Check(req) != nil {
+		s.stats.inc(&s.stats.dropped)
+		return false
+	}
+	if !s.allowed(req.Type.Method) {
+		if req.Type.Class != ClassRequest {
+			s.stats.inc(&s.stats.dropped)
+			return false
+		}
+		if err := res.Build(req, NewType(req.Type.Method, ClassErrorResponse), CodeForbidden); err != nil {
+			return false
+		}
+		return s.finish(res, nil)
+	}
+	if req.Type.Class == ClassRequest {
+		ip := sourceIP(addr)
+		if !s.transactions.acquire(ip) {
+			if err := res.Build(req, NewType(req.Type.Method, ClassErrorResponse), CodeServerError); err != nil {
+				return false
+			}
+			return s.finish(res, nil)
+		}
+		defer s.transactions.release(ip)
+	}
+	var (
+		integrity MessageIntegrity
+		ok        bool
+	)
+	switch {
+	case s.requireIntegrity != nil && req.Type != bindingIndication:
+		if integrity, username, ok = s.checkIntegrity(req); !ok {
+			s.stats.inc(&s.stats.dropped)
+			return false
+		}
+	case s.auth != nil && req.Type.Class == ClassRequest:
+		if integrity, username, ok = s.authenticate(res, req, addr); !ok {
+			return s.finish(res, nil)
+		}
+	}
+	if required, _ := req.Unknown(s.knownAttr); len(required) > 0 {
+		if req.Type.Class != ClassRequest {
+			return false
+		}
+		if err := res.Build(req, NewType(req.Type.Method, ClassErrorResponse), &UnknownAttrsErr{Required: required}); err != nil {
+			return false
+		}
+		return s.finish(res, integrity)
+	}
+	switch {
+	case req.Type == BindingRequest:
+		if err := s.bindingResponse(res, req, addr); err != nil {
+			return false
+		}
+	case req.Type.Method == MethodBinding:
+		// Binding indications are keepalives, nothing to respond.
+		return false
+	case s.handler != nil:
+		s.handle(res, req, addr)
+	case req.Type.Class == ClassRequest:
+		if err := res.Build(req, NewType(req.Type.Method, ClassErrorResponse), CodeBadRequest); err != nil {
+			return false
+		}
+	default:
+		return false
+	}
+	return s.finish(res, integrity)
+}
+
+// knownAttr returns true if t is known to server and is defined by its
+// edition of specification.
+func (s *Server) knownAttr(t AttrType) bool {
+	return s.known.Known(t) && s.spec.Defines(t)
+}
+
+// finish adds MESSAGE-INTEGRITY with integrity if not nil and
+// FINGERPRINT if enabled to res, returning false if res is empty.
+func (s *Server) finish(res *Message, integrity MessageIntegrity) bool {
+	if len(res.Raw) == 0 {
+		return false
+	}
+	fingerprint := (s.fingerprint || res.Contains(AttrFingerprint)) && s.spec.Defines(AttrFingerprint)
+	if integrity != nil && !res.Contains(AttrMessageIntegrity) {
+		res.Remove(AttrFingerprint)
+		if err := integrity.AddTo(res); err != nil {
+			return false
+		}
+	}
+	if fingerprint && !res.Contains(AttrFingerprint) {
+		if err := Fingerprint.AddTo(res); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) bindingResponse(res, req *Message, addr net.Addr) error {
+	var mapped XORMappedAddress
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		mapped.IP, mapped.Port = a.IP, a.Port
+	case *net.TCPAddr:
+		mapped.IP, mapped.Port = a.IP, a.Port
+	default:
+		return ErrUnsupportedAddr
+	}
+	if !s.spec.Defines(AttrXORMappedAddress) {
+		return classicBindingResponse(res, req, MappedAddress(mapped))
+	}
+	if err := res.Build(req, BindingSuccess, &mapped); err != nil {
+		return err
+	}
+	if len(s.software) > 0 {
+		return s.software.AddTo(res)
+	}
+	return nil
+}
+
+// classicBindingResponse builds RFC 3489 Binding response with
+// MAPPED-ADDRESS, echoing all 128 bits of transaction id of req, which
+// has no magic cookie if req is RFC 3489 request.
+//
+// RFC 3489 Section 8.2
+func classicBindingResponse(res, req *Message, mapped MappedAddress) error {
+	if err := res.Build(req, BindingSuccess, &mapped); err != nil {
+		return err
+	}
+	copy(res.Raw[4:8], req.Raw[4:8])
+	return nil
+}
+
+// Stats returns counters of server, use Stats.Snapshot to read them.
+func (s *Server) Stats() *Stats {
+	return &s.stats
+}
+
+// Close stops serving immediately, closing all connections and
+// listeners, and waits until serve methods return.
+func (s *Server) Close() error {
+	if !s.closeAll() {
+		return ErrServerClosed
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// closeAll closes all connections and listeners, returning false if
+// server is already closed.
+func (s *Server) closeAll() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.closed {
+		return false
+	}
+	s.closed = true
+	for c := range s.closers {
+		_ = c.Close()
+	}
+	return true
+}
+
+// aLongTimeAgo is deadline that interrupts pending reads.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// Shutdown stops serving gracefully: listeners are closed, so no new
+// connections are accepted, and reads of connections are interrupted,
+// while messages that are being handled are completed and responded.
+// Shutdown waits until serve methods return or ctx is done, in which
+// case all connections are closed like by Close and ctx.Err() is
+// returned without waiting for handlers that are still running.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mux.Lock()
+	if s.closed || s.shutdown {
+		s.mux.Unlock()
+		return ErrServerClosed
+	}
+	s.shutdown = true
+	for c := range s.closers {
+		if d, ok := c.(interface {
+			SetReadDeadline(t time.Time) error
+		}); ok {
+			if err := d.SetReadDeadline(aLongTimeAgo); err == nil {
+				continue
+			}
+		}
+		_ = c.Close()
+	}
+	s.mux.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.closeAll()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,459 @@
+package stun
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, options ...ServerOption) *Server {
+	t.Helper()
+	s, err := NewServer(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func listenServer(t *testing.T, network string, s *Server) (string, chan error) {
+	t.Helper()
+	errs := make(chan error, 1)
+	switch network {
+	case "tcp":
+		l, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() { errs <- s.Serve(l) }()
+		return l.Addr().String(), errs
+	default:
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() { errs <- s.ServePacket(conn) }()
+		return conn.LocalAddr().String(), errs
+	}
+}
+
+func TestServer_Binding(t *testing.T) {
+	for _, network := range []string{"udp", "tcp"} {
+		t.Run(network, func(t *testing.T) {
+			s := newTestServer(t, WithSoftware("test"), WithFingerprint)
+			addr, errs := listenServer(t, network, s)
+			c, err := Dial(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if doErr := c.Do(MustBuild(TransactionID, BindingRequest), func(e Event) {
+				if e.Error != nil {
+					t.Fatal(e.Error)
+				}
+				if e.Message.Type != BindingSuccess {
+					t.Errorf("unexpected type %s", e.Message.Type)
+				}
+				var mapped XORMappedAddress
+				if getErr := mapped.GetFrom(e.Message); getErr != nil {
+					t.Fatal(getErr)
+				}
+				if !mapped.IP.IsLoopback() || mapped.Port == 0 {
+					t.Errorf("unexpected mapped address %s", mapped)
+				}
+				var software Software
+				if getErr := software.GetFrom(e.Message); getErr != nil {
+					t.Fatal(getErr)
+				}
+				if software.String() != "test" {
+					t.Errorf("unexpected software %q", software)
+				}
+				if checkErr := Fingerprint.Check(e.Message); checkErr != nil {
+					t.Error(checkErr)
+				}
+			}); doErr != nil {
+				t.Fatal(doErr)
+			}
+			if closeErr := s.Close(); closeErr != nil {
+				t.Fatal(closeErr)
+			}
+			if serveErr := <-errs; serveErr != ErrServerClosed {
+				t.Errorf("unexpected serve error %v", serveErr)
+			}
+			if closeErr := s.Close(); closeErr != ErrServerClosed {
+				t.Errorf("unexpected second close error %v", closeErr)
+			}
+		})
+	}
+}
+
+func TestServer_Handler(t *testing.T) {
+	t.Run("BadRequest", func(t *testing.T) {
+		s := newTestServer(t)
+		addr, _ := listenServer(t, "udp", s)
+		defer s.Close()
+		c, err := Dial("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if doErr := c.Do(MustBuild(TransactionID, NewType(MethodAllocate, ClassRequest)), func(e Event) {
+			if e.Error != nil {
+				t.Fatal(e.Error)
+			}
+			var code ErrorCodeAttribute
+			if getErr := code.GetFrom(e.Message); getErr != nil {
+				t.Fatal(getErr)
+			}
+			if code.Code != CodeBadRequest {
+				t.Errorf("unexpected code %d", code.Code)
+			}
+		}); doErr != nil {
+			t.Fatal(doErr)
+		}
+	})
+	t.Run("Custom", func(t *testing.T) {
+		indications := make(chan MessageType, 1)
+		s := newTestServer(t, WithFingerprint, WithServerHandler(func(res, req *Message, addr net.Addr) {
+			if req.Type.Class == ClassIndication {
+				indications <- req.Type
+				return
+			}
+			if err := res.Build(req, NewType(req.Type.Method, ClassSuccessResponse)); err != nil {
+				t.Error(err)
+			}
+		}))
+		addr, _ := listenServer(t, "udp", s)
+		defer s.Close()
+		c, err := Dial("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if doErr := c.Do(MustBuild(TransactionID, NewType(MethodAllocate, ClassRequest)), func(e Event) {
+			if e.Error != nil {
+				t.Fatal(e.Error)
+			}
+			if e.Message.Type != NewType(MethodAllocate, ClassSuccessResponse) {
+				t.Errorf("unexpected type %s", e.Message.Type)
+			}
+			if !e.Message.Contains(AttrFingerprint) {
+				t.Error("no fingerprint")
+			}
+		}); doErr != nil {
+			t.Fatal(doErr)
+		}
+		indication := NewType(MethodSend, ClassIndication)
+		if doErr := c.Indicate(MustBuild(TransactionID, indication)); doErr != nil {
+			t.Fatal(doErr)
+		}
+		if got := <-indications; got != indication {
+			t.Errorf("unexpected indication %s", got)
+		}
+	})
+}
+
+func TestServer_AllowedMethods(t *testing.T) {
+	var (
+		addr  = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+		res   = new(Message)
+		calls = 0
+	)
+	s := newTestServer(t,
+		WithAllowedMethods(MethodBinding),
+		WithAuth("realm", func(username, realm string) ([]byte, bool) {
+			return nil, false
+		}),
+		WithServerHandler(func(res, req *Message, addr net.Addr) {
+			calls++
+		}),
+	)
+	allocate := MustBuild(TransactionID, NewType(MethodAllocate, ClassRequest))
+	if !s.process(res, new(Message), allocate.Raw, addr) {
+		t.Fatal("no response")
+	}
+	var code ErrorCodeAttribute
+	if err := code.GetFrom(res); err != nil || code.Code != CodeForbidden {
+		t.Errorf("unexpected %s, %v", code, err)
+	}
+	if res.Contains(AttrNonce) {
+		t.Error("forbidden request should not be challenged")
+	}
+	send := MustBuild(TransactionID, NewType(MethodSend, ClassIndication))
+	if s.process(res, new(Message), send.Raw, addr) {
+		t.Error("indication should be dropped")
+	}
+	binding := MustBuild(TransactionID, BindingRequest)
+	if !s.process(res, new(Message), binding.Raw, addr) {
+		t.Fatal("no response")
+	}
+	if err := code.GetFrom(res); err != nil || code.Code != CodeUnauthorized {
+		t.Errorf("allowed request should be challenged: %s, %v", code, err)
+	}
+	if calls != 0 {
+		t.Errorf("handler should not be called, got %d calls", calls)
+	}
+	if stats := s.Stats().Snapshot(); stats.Dropped != 1 {
+		t.Errorf("unexpected stats %+v", stats.StatsCounters)
+	}
+}
+
+func TestServer_Closed(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.ServePacket(conn); err != ErrServerClosed {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err = conn.WriteTo([]byte{1}, conn.LocalAddr()); err == nil {
+		t.Error("conn should be closed")
+	}
+}
+
+func TestServer_Strict(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+	t.Run("Fingerprint", func(t *testing.T) {
+		s := newTestServer(t, WithRequireFingerprint)
+		for _, tc := range []struct {
+			name string
+			req  *Message
+			ok   bool
+		}{
+			{"Missing", MustBuild(TransactionID, BindingRequest), false},
+			{"Valid", MustBuild(TransactionID, BindingRequest, Fingerprint), true},
+		} {
+			if got := s.process(new(Message), new(Message), tc.req.Raw, addr); got != tc.ok {
+				t.Errorf("%s: process() = %v, want %v", tc.name, got, tc.ok)
+			}
+		}
+		req := MustBuild(TransactionID, BindingRequest, Fingerprint)
+		req.Raw[len(req.Raw)-1]++
+		if s.process(new(Message), new(Message), req.Raw, addr) {
+			t.Error("request with invalid fingerprint should be dropped")
+		}
+	})
+	t.Run("Integrity", func(t *testing.T) {
+		key := NewShortTermIntegrity("password")
+		s := newTestServer(t, WithRequireIntegrity(func(username, realm string) ([]byte, bool) {
+			return key, username == "user" && realm == ""
+		}))
+		for _, tc := range []struct {
+			name string
+			req  *Message
+			ok   bool
+		}{
+			{"Missing", MustBuild(TransactionID, BindingRequest), false},
+			{"NoUsername", MustBuild(TransactionID, BindingRequest, key), false},
+			{"UnknownUser", MustBuild(TransactionID, BindingRequest, NewUsername("other"), key), false},
+			{"Mismatch", MustBuild(TransactionID, BindingRequest, NewUsername("user"), NewShortTermIntegrity("x")), false},
+			{"Valid", MustBuild(TransactionID, BindingRequest, NewUsername("user"), key, Fingerprint), true},
+		} {
+			res := new(Message)
+			if got := s.process(res, new(Message), tc.req.Raw, addr); got != tc.ok {
+				t.Errorf("%s: process() = %v, want %v", tc.name, got, tc.ok)
+			}
+			if !tc.ok {
+				continue
+			}
+			if err := key.Check(res); err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+		}
+	})
+	t.Run("Conflict", func(t *testing.T) {
+		h := func(username, realm string) ([]byte, bool) { return nil, false }
+		if _, err := NewServer(WithAuth("realm", h), WithRequireIntegrity(h)); err == nil {
+			t.Error("should error")
+		}
+	})
+}
+
+func TestServer_UnknownAttributes(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+	s := newTestServer(t, WithKnownAttributes(0x7F02))
+	var (
+		unknown  = RawAttribute{Type: 0x7F01, Value: []byte{1}}
+		handled  = RawAttribute{Type: 0x7F02, Value: []byte{2}}
+		optional = RawAttribute{Type: 0xFF01, Value: []byte{3}}
+	)
+	res := new(Message)
+	if !s.process(res, new(Message), MustBuild(TransactionID, BindingRequest, unknown, handled, optional).Raw, addr) {
+		t.Fatal("no response")
+	}
+	var (
+		code  ErrorCodeAttribute
+		types UnknownAttributes
+	)
+	if err := res.Parse(&code, &types); err != nil {
+		t.Fatal(err)
+	}
+	if code.Code != CodeUnknownAttribute || types.String() != "0x7f01" {
+		t.Errorf("unexpected %s; %s", code, types)
+	}
+	if !s.process(res, new(Message), MustBuild(TransactionID, BindingRequest, handled, optional).Raw, addr) {
+		t.Fatal("no response")
+	}
+	if res.Type != BindingSuccess {
+		t.Errorf("unexpected type %s", res.Type)
+	}
+	indication := MustBuild(TransactionID, NewType(MethodSend, ClassIndication), unknown)
+	if s.process(res, new(Message), indication.Raw, addr) {
+		t.Error("indication with unknown attributes should be dropped")
+	}
+}
+
+func TestServer_Spec(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+	t.Run("RFC5389", func(t *testing.T) {
+		req := MustBuild(TransactionID, BindingRequest, NewUserhash("user", "realm"))
+		res := new(Message)
+		if !newTestServer(t).process(res, new(Message), req.Raw, addr) || res.Type != BindingSuccess {
+			t.Fatalf("unexpected response %s", res)
+		}
+		if !newTestServer(t, WithServerSpec(SpecRFC5389)).process(res, new(Message), req.Raw, addr) {
+			t.Fatal("no response")
+		}
+		var types UnknownAttributes
+		if err := types.GetFrom(res); err != nil {
+			t.Fatal(err)
+		}
+		if len(types) != 1 || types[0] != AttrUserhash {
+			t.Errorf("unexpected %s", types)
+		}
+	})
+	t.Run("RFC3489", func(t *testing.T) {
+		s := newTestServer(t, WithServerSpec(SpecRFC3489), WithSoftware("test"), WithFingerprint)
+		req := MustBuild(TransactionID, BindingRequest)
+		copy(req.Raw[4:8], []byte{1, 2, 3, 4})
+		res := new(Message)
+		if !s.process(res, new(Message), req.Raw, addr) {
+			t.Fatal("no response")
+		}
+		if !bytes.Equal(res.Raw[4:messageHeaderSize], req.Raw[4:messageHeaderSize]) {
+			t.Error("transaction id should be echoed")
+		}
+		var mapped MappedAddress
+		if err := mapped.GetFrom(res); err != nil {
+			t.Fatal(err)
+		}
+		if !mapped.IP.Equal(addr.IP) || mapped.Port != addr.Port {
+			t.Errorf("unexpected %s", mapped)
+		}
+		for _, attr := range []AttrType{AttrXORMappedAddress, AttrSoftware, AttrFingerprint} {
+			if res.Contains(attr) {
+				t.Errorf("%s should not be added", attr)
+			}
+		}
+	})
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	method := Method(0x100)
+	for _, network := range []string{"udp", "tcp"} {
+		t.Run(network, func(t *testing.T) {
+			var (
+				started = make(chan struct{})
+				release = make(chan struct{})
+			)
+			s := newTestServer(t, WithServerHandler(func(res, req *Message, addr net.Addr) {
+				close(started)
+				<-release
+				if err := res.Build(req, NewType(method, ClassSuccessResponse)); err != nil {
+					t.Error(err)
+				}
+			}))
+			addr, errs := listenServer(t, network, s)
+			c, err := Dial(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			responses := make(chan error, 1)
+			go func() {
+				responses <- c.Do(MustBuild(TransactionID, NewType(method, ClassRequest)), func(e Event) {
+					if e.Error != nil {
+						t.Error(e.Error)
+					}
+				})
+			}()
+			<-started
+			shutdown := make(chan error, 1)
+			go func() {
+				shutdown <- s.Shutdown(context.Background())
+			}()
+			select {
+			case err = <-shutdown:
+				t.Fatalf("shutdown should wait for handler, got %v", err)
+			case <-time.After(time.Millisecond * 50):
+			}
+			close(release)
+			if err = <-responses; err != nil {
+				t.Error(err)
+			}
+			if err = <-shutdown; err != nil {
+				t.Error(err)
+			}
+			if err = <-errs; err != ErrServerClosed {
+				t.Errorf("unexpected serve error %v", err)
+			}
+			if err = s.Shutdown(context.Background()); err != ErrServerClosed {
+				t.Errorf("unexpected second shutdown error %v", err)
+			}
+		})
+	}
+	t.Run("Deadline", func(t *testing.T) {
+		var (
+			started = make(chan struct{})
+			release = make(chan struct{})
+		)
+		defer close(release)
+		s := newTestServer(t, WithServerHandler(func(res, req *Message, addr net.Addr) {
+			close(started)
+			<-release
+		}))
+		addr, _ := listenServer(t, "udp", s)
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err = conn.Write(MustBuild(TransactionID, NewType(method, ClassRequest)).Raw); err != nil {
+			t.Fatal(err)
+		}
+		<-started
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		if err = s.Shutdown(ctx); err != context.DeadlineExceeded {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestServer_ServeContext(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- s.ServePacketContext(ctx, conn) }()
+	cancel()
+	if err = <-errs; err != context.Canceled {
+		t.Errorf("unexpected error %v", err)
+	}
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.ServeContext(ctx, l); err != context.Canceled {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package stun
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"time"
+)
+
+// AuthHandler returns long-term credentials key of user in realm, e.g.
+// NewLongTermIntegrity(username, realm, password), or false if there is
+// no such user.
+type AuthHandler func(username, realm string) (key []byte, ok bool)
+
+// DefaultNonceLifetime is default time after which nonce issued by
+// Server becomes stale.
+const DefaultNonceLifetime = time.Minute * 10
+
+// WithAuth enables long-term credential mechanism for requests to
+// server: unauthenticated requests are challenged by 401 (Unauthorized)
+// with realm and nonce, stale nonces are rotated by 438 (Stale Nonce)
+// and responses to authenticated requests are signed with the key from
+// h. Indications are not authenticated.
+//
+// RFC 5389 Section 10.2.2
+func WithAuth(realm string, h AuthHandler) ServerOption {
+	return func(s *Server) {
+		s.realm = NewRealm(realm)
+		s.auth = h
+	}
+}
+
+// UserhashLookup returns username of user in realm for USERHASH, or
+// false if there is no such user. Servers usually keep table of
+// NewUserhash(username, realm) for all users.
+type UserhashLookup func(userhash Userhash, realm string) (username string, ok bool)
+
+// WithUserhashLookup enables username anonymity for WithAuth: nonces
+// signal FeatureUsernameAnonymity, and requests with USERHASH instead of
+// USERNAME are authenticated as user that is returned by h.
+//
+// RFC 8489 Section 9.2.4
+func WithUserhashLookup(h UserhashLookup) ServerOption {
+	return func(s *Server) {
+		s.userhash = h
+	}
+}
+
+// WithNonceLifetime sets time after which nonce becomes stale,
+// defaults to DefaultNonceLifetime.
+func WithNonceLifetime(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.nonceLifetime = d
+	}
+}
+
+// nonce sizes: hex of 8-byte timestamp and of truncated HMAC.
+const (
+	nonceTimestampSize = 8
+	nonceTagSize       = 8
+	nonceSize          = (nonceTimestampSize + nonceTagSize) * 2
+)
+
+// initAuth initializes secret that is used to sign nonces, so they
+// can be validated without keeping state.
+func (s *Server) initAuth() {
+	s.nonceSecret = make([]byte, 16)
+	readFullOrPanic(rand.Reader, s.nonceSecret)
+}
+
+func (s *Server) nonceTag(timestamp []byte, addr net.Addr) []byte {
+	b := append(append([]byte(nil), timestamp...), addr.String()...)
+	return newHMAC(s.nonceSecret, b, nil)[:nonceTagSize]
+}
+
+// newNonce returns nonce for addr that is issued at t.
+func (s *Server) newNonce(addr net.Addr, t time.Time) Nonce {
+	var timestamp [nonceTimestampSize]byte
+	bin.PutUint64(timestamp[:], uint64(t.UnixNano()))
+	nonce := hex.EncodeToString(timestamp[:]) + hex.EncodeToString(s.nonceTag(timestamp[:], addr))
+	if s.userhash != nil {
+		return NewSecureNonce(FeatureUsernameAnonymity, nonce)
+	}
+	return Nonce(nonce)
+}
+
+// validNonce returns true if n was issued by server for addr and is not
+// stale at t.
+func (s *Server) validNonce(n Nonce, addr net.Addr, t time.Time) bool {
+	if s.userhash != nil {
+		features, ok := n.Features()
+		if !ok || features != FeatureUsernameAnonymity {
+			return false
+		}
+		n = n[nonceCookieLen:]
+	}
+	if len(n) != nonceSize {
+		return false
+	}
+	v := make([]byte, nonceSize/2)
+	if _, err := hex.Decode(v, n); err != nil {
+		return false
+	}
+	timestamp, tag := v[:nonceTimestampSize], v[nonceTimestampSize:]
+	if !hmac.Equal(tag, s.nonceTag(timestamp, addr)) {
+		return false
+	}
+	issued := time.Unix(0, int64(bin.Uint64(timestamp)))
+	return t.Sub(issued) < s.nonceLifetime
+}
+
+// authenticate checks long-term credentials of req, returning the key
+// and name of user. If req is not authenticated, challenge or error response is
+// built to res and false is returned.
+func (s *Server) authenticate(res, req *Message, addr net.Addr) (MessageIntegrity, string, bool) {
+	var (
+		errorType = NewType(req.Type.Method, ClassErrorResponse)
+		now       = s.now()
+		challenge = func(code ErrorCode) {
+			_ = res.Build(req, errorType, code, s.realm, s.newNonce(addr, now))
+		}
+	)
+	if !req.Contains(AttrMessageIntegrity) {
+		challenge(CodeUnauthorized)
+		return nil, "", false
+	}
+	var (
+		username Username
+		userhash Userhash
+		realm    Realm
+		nonce    Nonce
+	)
+	// USERHASH is used only if there is no USERNAME.
+	anonymous := s.userhash != nil && !req.Contains(AttrUsername)
+	user := username.GetFrom
+	if anonymous {
+		user = userhash.GetFrom
+	}
+	if user(req) != nil || realm.GetFrom(req) != nil || nonce.GetFrom(req) != nil {
+		_ = res.Build(req, errorType, CodeBadRequest)
+		return nil, "", false
+	}
+	if !bytes.Equal(realm, s.realm) {
+		// Realm of request must be the one from challenge.
+		challenge(CodeUnauthorized)
+		return nil, "", false
+	}
+	if !s.validNonce(nonce, addr, now) {
+		challenge(CodeStaleNonce)
+		return nil, "", false
+	}
+	if anonymous {
+		name, found := s.userhash(userhash, realm.String())
+		if !found {
+			challenge(CodeUnauthorized)
+			return nil, "", false
+		}
+		username = NewUsername(name)
+	}
+	key, ok := s.auth(username.String(), realm.String())
+	if !ok {
+		challenge(CodeUnauthorized)
+		return nil, "", false
+	}
+	integrity := MessageIntegrity(key)
+	if err := integrity.Check(req); err != nil {
+		challenge(CodeUnauthorized)
+		return nil, "", false
+	}
+	return integrity, username.String(), true
+}
+
+// checkIntegrity returns key and name of user that signed m, or false if
+// m is not signed with valid MESSAGE-INTEGRITY.
+func (s *Server) checkIntegrity(m *Message) (MessageIntegrity, string, bool) {
+	var (
+		username Username
+		realm    Realm
+	)
+	if !m.Contains(AttrMessageIntegrity) || username.GetFrom(m) != nil {
+		return nil, "", false
+	}
+	if m.Contains(AttrRealm) && realm.GetFrom(m) != nil {
+		return nil, "", false
+	}
+	key, ok := s.requireIntegrity(username.String(), realm.String())
+	if !ok {
+		return nil, "", false
+	}
+	integrity := MessageIntegrity(key)
+	if err := integrity.Check(m); err != nil {
+		return nil, "", false
+	}
+	return integrity, username.String(), true
+}
@@ -0,0 +1,210 @@
+package stun
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newAuthServer(t *testing.T) (*Server, func(d time.Duration)) {
+	t.Helper()
+	clock := &manualClock{current: time.Unix(1000, 0)}
+	s := newTestServer(t, WithFingerprint, WithServerClock(clock), WithAuth("realm", func(username, realm string) ([]byte, bool) {
+		if username != "user" {
+			return nil, false
+		}
+		return NewLongTermIntegrity(username, realm, "secret"), true
+	}))
+	return s, func(d time.Duration) {
+		clock.Add(d)
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	s, advance := newAuthServer(t)
+	addr, _ := listenServer(t, "udp", s)
+	defer s.Close()
+	c, err := Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	credentials := &LongTermCredentials{Username: "user", Password: "secret"}
+	do := func() {
+		t.Helper()
+		if doErr := credentials.Do(c, func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+				return
+			}
+			if e.Message.Type != BindingSuccess {
+				t.Errorf("unexpected type %s", e.Message.Type)
+			}
+			if !e.Message.Contains(AttrMessageIntegrity) {
+				t.Error("response is not signed")
+			}
+			if checkErr := Fingerprint.Check(e.Message); checkErr != nil {
+				t.Error(checkErr)
+			}
+		}, BindingRequest, Fingerprint); doErr != nil {
+			t.Fatal(doErr)
+		}
+	}
+	do()
+	t.Run("Stale", func(t *testing.T) {
+		advance(DefaultNonceLifetime)
+		do()
+	})
+	t.Run("Unauthorized", func(t *testing.T) {
+		wrong := &LongTermCredentials{Username: "user", Password: "wrong"}
+		if doErr := wrong.Do(c, func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+				return
+			}
+			var code ErrorCodeAttribute
+			if getErr := code.GetFrom(e.Message); getErr != nil {
+				t.Error(getErr)
+				return
+			}
+			if code.Code != CodeUnauthorized {
+				t.Errorf("unexpected code %d", code.Code)
+			}
+		}, BindingRequest); doErr != nil {
+			t.Fatal(doErr)
+		}
+	})
+}
+
+func TestServer_Userhash(t *testing.T) {
+	var (
+		usernames = map[string]string{
+			NewUserhash("user", "realm").String(): "user",
+		}
+		lookups int32
+	)
+	s := newTestServer(t,
+		WithAuth("realm", func(username, realm string) ([]byte, bool) {
+			if username != "user" {
+				return nil, false
+			}
+			return NewLongTermIntegrity(username, realm, "secret"), true
+		}),
+		WithUserhashLookup(func(userhash Userhash, realm string) (string, bool) {
+			atomic.AddInt32(&lookups, 1)
+			username, ok := usernames[userhash.String()]
+			return username, ok
+		}),
+	)
+	addr, _ := listenServer(t, "udp", s)
+	defer s.Close()
+	c, err := Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for _, tc := range []struct {
+		name        string
+		credentials *LongTermCredentials
+		code        ErrorCode
+	}{
+		{"Userhash", &LongTermCredentials{Username: "user", Password: "secret", Userhash: true}, 0},
+		{"Username", &LongTermCredentials{Username: "user", Password: "secret"}, 0},
+		{"UnknownUser", &LongTermCredentials{Username: "other", Password: "secret", Userhash: true}, CodeUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if doErr := tc.credentials.Do(c, func(e Event) {
+				if e.Error != nil {
+					t.Error(e.Error)
+					return
+				}
+				var code ErrorCodeAttribute
+				if tc.code == 0 && e.Message.Type != BindingSuccess {
+					_ = code.GetFrom(e.Message)
+					t.Errorf("unexpected response %s %s", e.Message.Type, code)
+				}
+				if tc.code != 0 && (code.GetFrom(e.Message) != nil || code.Code != tc.code) {
+					t.Errorf("unexpected response %s %s", e.Message.Type, code)
+				}
+			}, BindingRequest); doErr != nil {
+				t.Fatal(doErr)
+			}
+		})
+	}
+	if n := atomic.LoadInt32(&lookups); n != 2 {
+		t.Errorf("unexpected lookups %d", n)
+	}
+}
+
+func TestServer_authenticate(t *testing.T) {
+	s, advance := newAuthServer(t)
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3478}
+	key := NewLongTermIntegrity("user", "realm", "secret")
+	nonce := s.newNonce(addr, s.now())
+	for _, tc := range []struct {
+		name    string
+		setters []Setter
+		code    ErrorCode
+	}{
+		{"NoIntegrity", nil, CodeUnauthorized},
+		{"NoNonce", []Setter{NewUsername("user"), NewRealm("realm"), key}, CodeBadRequest},
+		{"BadNonce", []Setter{NewUsername("user"), NewRealm("realm"), NewNonce("nonce"), key}, CodeStaleNonce},
+		{"UnknownUser", []Setter{NewUsername("other"), NewRealm("realm"), nonce, key}, CodeUnauthorized},
+		{"OtherRealm", []Setter{NewUsername("user"), NewRealm("other"), nonce, NewLongTermIntegrity("user", "other", "secret")}, CodeUnauthorized},
+		{"Mismatch", []Setter{NewUsername("user"), NewRealm("realm"), nonce, NewShortTermIntegrity("x")}, CodeUnauthorized},
+		{"OK", []Setter{NewUsername("user"), NewRealm("realm"), nonce, key}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := MustBuild(append([]Setter{TransactionID, BindingRequest}, tc.setters...)...)
+			res := new(Message)
+			if !s.process(res, new(Message), req.Raw, addr) {
+				t.Fatal("no response")
+			}
+			if tc.code == 0 {
+				if res.Type != BindingSuccess {
+					t.Fatalf("unexpected type %s", res.Type)
+				}
+				if err := key.Check(res); err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			var code ErrorCodeAttribute
+			if err := code.GetFrom(res); err != nil {
+				t.Fatal(err)
+			}
+			if code.Code != tc.code {
+				t.Errorf("unexpected code %d", code.Code)
+			}
+			if res.Contains(AttrMessageIntegrity) {
+				t.Error("error response should not be signed")
+			}
+			if tc.code != CodeBadRequest && !res.Contains(AttrNonce) {
+				t.Error("no nonce in challenge")
+			}
+			var realm Realm
+			if tc.code != CodeBadRequest && (realm.GetFrom(res) != nil || realm.String() != "realm") {
+				t.Errorf("unexpected realm %q in challenge", realm)
+			}
+		})
+	}
+	t.Run("StaleNonce", func(t *testing.T) {
+		if !s.validNonce(nonce, addr, s.now()) {
+			t.Fatal("nonce should be valid")
+		}
+		if s.validNonce(nonce, &net.UDPAddr{IP: addr.IP, Port: 1}, s.now()) {
+			t.Error("nonce should be bound to address")
+		}
+		advance(DefaultNonceLifetime)
+		if s.validNonce(nonce, addr, s.now()) {
+			t.Error("nonce should be stale")
+		}
+	})
+	t.Run("Indication", func(t *testing.T) {
+		req := MustBuild(TransactionID, NewType(MethodBinding, ClassIndication))
+		if s.process(new(Message), new(Message), req.Raw, addr) {
+			t.Error("unexpected response to indication")
+		}
+	})
+}
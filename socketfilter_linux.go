@@ -0,0 +1,38 @@
+// +build linux
+
+package stun
+
+import "syscall"
+
+// socketFilter is classic BPF program that accepts only UDP datagrams
+// that look like STUN messages: first two bits of the message are zero
+// and magic cookie is at its place. Offsets include 8 bytes of UDP
+// header.
+var socketFilter = []syscall.SockFilter{
+	{Code: syscall.BPF_LD | syscall.BPF_W | syscall.BPF_ABS, K: 8 + 4},
+	{Code: syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, Jt: 0, Jf: 3, K: magicCookie},
+	{Code: syscall.BPF_LD | syscall.BPF_B | syscall.BPF_ABS, K: 8},
+	{Code: syscall.BPF_JMP | syscall.BPF_JSET | syscall.BPF_K, Jt: 1, Jf: 0, K: 0xc0},
+	{Code: syscall.BPF_RET | syscall.BPF_K, K: 0xffffffff}, // accept
+	{Code: syscall.BPF_RET | syscall.BPF_K, K: 0},          // drop
+}
+
+// AttachSocketFilter attaches BPF filter to UDP socket of conn, so
+// datagrams that are not STUN messages are dropped by kernel before
+// being read. Useful for high-traffic public servers.
+//
+// Do not use on sockets that are multiplexed with other protocols,
+// e.g. DTLS or RTP, because their datagrams will be dropped too.
+func AttachSocketFilter(conn syscall.Conn) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var attachErr error
+	if err = raw.Control(func(fd uintptr) {
+		attachErr = syscall.AttachLsf(int(fd), socketFilter)
+	}); err != nil {
+		return err
+	}
+	return attachErr
+}
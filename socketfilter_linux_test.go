@@ -0,0 +1,53 @@
+// +build linux
+
+package stun
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAttachSocketFilter(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err = AttachSocketFilter(conn); err != nil {
+		t.Fatal(err)
+	}
+	sender, err := net.DialUDP("udp4", nil, conn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	m := MustBuild(TransactionID, BindingRequest)
+	badType := MustBuild(TransactionID, BindingRequest)
+	badType.Raw[0] |= 0x80
+	for _, b := range [][]byte{
+		make([]byte, 100),
+		[]byte("not a stun message"),
+		badType.Raw,
+		m.Raw,
+	} {
+		if _, err = sender.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := new(Message)
+	if err = Decode(buf[:n], got); err != nil {
+		t.Fatal(err)
+	}
+	if got.TransactionID != m.TransactionID {
+		t.Error("only valid message should be received")
+	}
+}
@@ -0,0 +1,18 @@
+// +build !linux
+
+package stun
+
+import (
+	"errors"
+	"syscall"
+)
+
+// ErrSocketFilterUnsupported means that socket filters are not supported
+// on current platform.
+var ErrSocketFilterUnsupported = errors.New("socket filter is not supported")
+
+// AttachSocketFilter is supported only on Linux and returns
+// ErrSocketFilterUnsupported.
+func AttachSocketFilter(conn syscall.Conn) error {
+	return ErrSocketFilterUnsupported
+}
@@ -0,0 +1,103 @@
+package stun
+
+import "fmt"
+
+// Spec is edition of STUN specification that controls validation
+// rules, attributes and algorithms, so behavior can be pinned when
+// interoperating with a peer that implements known old edition. The
+// zero value is SpecRFC8489, the latest edition.
+type Spec byte
+
+// Supported editions.
+const (
+	// SpecRFC8489 enables all features, including SHA-256 password
+	// algorithm and USERHASH. Default.
+	SpecRFC8489 Spec = iota
+	// SpecRFC5389 disables features of RFC 8489: their attributes are
+	// not known and MD5 key derivation is used for credentials.
+	SpecRFC5389
+	// SpecRFC3489 also disables features of RFC 5389: messages without
+	// magic cookie are accepted, Binding responses have MAPPED-ADDRESS
+	// instead of XOR-MAPPED-ADDRESS, and FINGERPRINT and SOFTWARE are
+	// not added.
+	SpecRFC3489
+)
+
+// edition returns number of RFC of s, or zero if s is unknown.
+func (s Spec) edition() int {
+	switch s {
+	case SpecRFC8489:
+		return 8489
+	case SpecRFC5389:
+		return 5389
+	case SpecRFC3489:
+		return 3489
+	default:
+		return 0
+	}
+}
+
+func (s Spec) String() string {
+	if e := s.edition(); e != 0 {
+		return fmt.Sprintf("RFC %d", e)
+	}
+	return fmt.Sprintf("0x%x", byte(s))
+}
+
+// MarshalText implements encoding.TextMarshaler, e.g. for ClientConfig
+// and ServerConfig.
+func (s Spec) MarshalText() ([]byte, error) {
+	if e := s.edition(); e != 0 {
+		return []byte(fmt.Sprintf("RFC%d", e)), nil
+	}
+	return nil, OptionErr{Option: "Spec", Value: s}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting
+// "RFC3489", "RFC5389" and "RFC8489".
+func (s *Spec) UnmarshalText(text []byte) error {
+	for _, spec := range []Spec{SpecRFC8489, SpecRFC5389, SpecRFC3489} {
+		if string(text) == fmt.Sprintf("RFC%d", spec.edition()) {
+			*s = spec
+			return nil
+		}
+	}
+	return OptionErr{Option: "Spec", Value: string(text)}
+}
+
+// specAttrs are editions that introduced attributes of STUN itself.
+var specAttrs = map[AttrType]Spec{
+	AttrRealm:            SpecRFC5389,
+	AttrNonce:            SpecRFC5389,
+	AttrXORMappedAddress: SpecRFC5389,
+	AttrSoftware:         SpecRFC5389,
+	AttrAlternateServer:  SpecRFC5389,
+	AttrFingerprint:      SpecRFC5389,
+
+	AttrMessageIntegritySHA256: SpecRFC8489,
+	AttrPasswordAlgorithm:      SpecRFC8489,
+	AttrUserhash:               SpecRFC8489,
+	AttrPasswordAlgorithms:     SpecRFC8489,
+	AttrAlternateDomain:        SpecRFC8489,
+}
+
+// Defines returns true if attribute t is defined by s or by earlier
+// edition. Attributes of other specifications, e.g. ICE or TURN, are
+// not restricted.
+func (s Spec) Defines(t AttrType) bool {
+	introduced, ok := specAttrs[t]
+	return !ok || introduced.edition() <= s.edition()
+}
+
+// quirks returns workarounds that disable features of later editions
+// for long-term credentials.
+func (s Spec) quirks() Quirks {
+	switch s {
+	case SpecRFC5389:
+		return QuirkNoSHA256 | QuirkNoUserhash
+	case SpecRFC3489:
+		return QuirkNoSHA256 | QuirkNoUserhash | QuirkNoFingerprint
+	default:
+		return 0
+	}
+}
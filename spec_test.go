@@ -0,0 +1,76 @@
+package stun
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSpec_Defines(t *testing.T) {
+	for _, tc := range []struct {
+		spec    Spec
+		t       AttrType
+		defines bool
+	}{
+		{SpecRFC8489, AttrUserhash, true},
+		{SpecRFC8489, AttrXORMappedAddress, true},
+		{SpecRFC5389, AttrUserhash, false},
+		{SpecRFC5389, AttrMessageIntegritySHA256, false},
+		{SpecRFC5389, AttrFingerprint, true},
+		{SpecRFC3489, AttrFingerprint, false},
+		{SpecRFC3489, AttrXORMappedAddress, false},
+		{SpecRFC3489, AttrMappedAddress, true},
+		{SpecRFC3489, AttrPriority, true},
+	} {
+		if got := tc.spec.Defines(tc.t); got != tc.defines {
+			t.Errorf("%s defines %s: %v, expected %v", tc.spec, tc.t, got, tc.defines)
+		}
+	}
+}
+
+func TestSpec_String(t *testing.T) {
+	for spec, s := range map[Spec]string{
+		SpecRFC8489: "RFC 8489",
+		SpecRFC5389: "RFC 5389",
+		SpecRFC3489: "RFC 3489",
+		Spec(10):    "0xa",
+	} {
+		if spec.String() != s {
+			t.Errorf("%q, expected %q", spec, s)
+		}
+	}
+}
+
+func TestSpec_Text(t *testing.T) {
+	for _, spec := range []Spec{SpecRFC8489, SpecRFC5389, SpecRFC3489} {
+		b, err := json.Marshal(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Spec
+		if err = json.Unmarshal(b, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded != spec {
+			t.Errorf("%s decoded as %s", spec, decoded)
+		}
+	}
+	if _, err := Spec(10).MarshalText(); err == nil {
+		t.Error("should error")
+	}
+	var spec Spec
+	if err := spec.UnmarshalText([]byte("RFC1234")); err == nil {
+		t.Error("should error")
+	}
+}
+
+func TestSpec_quirks(t *testing.T) {
+	if SpecRFC8489.quirks() != 0 {
+		t.Error("RFC 8489 should have no quirks")
+	}
+	if !SpecRFC5389.quirks().Has(QuirkNoSHA256 | QuirkNoUserhash) {
+		t.Error("RFC 5389 should disable SHA-256 and USERHASH")
+	}
+	if !SpecRFC3489.quirks().Has(QuirkNoFingerprint) {
+		t.Error("RFC 3489 should disable FINGERPRINT")
+	}
+}
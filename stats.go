@@ -0,0 +1,81 @@
+package stun
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// StatsCounters are message counters of Server.
+type StatsCounters struct {
+	Received     uint64 // messages that were read
+	Dropped      uint64 // messages that failed policy, decoding or required checks
+	Responses    uint64 // responses that were sent
+	WriteErrors  uint64 // responses that failed to be sent
+	Panics       uint64 // messages that caused recovered panic, also dropped
+	HealthChecks uint64 // health check probes, also received, see WithHealthCheck
+}
+
+// StatsSnapshot is copy of counters at Time.
+type StatsSnapshot struct {
+	Time   time.Time
+	Memory int64 // approximate bytes that are used, see WithMemoryLimit
+	StatsCounters
+}
+
+// StatsDelta is difference between two snapshots.
+type StatsDelta struct {
+	Interval time.Duration
+	StatsCounters
+}
+
+// Sub returns delta of s since prev, which should be earlier snapshot of
+// the same Stats.
+func (s StatsSnapshot) Sub(prev StatsSnapshot) StatsDelta {
+	return StatsDelta{
+		Interval: s.Time.Sub(prev.Time),
+		StatsCounters: StatsCounters{
+			Received:     s.Received - prev.Received,
+			Dropped:      s.Dropped - prev.Dropped,
+			Responses:    s.Responses - prev.Responses,
+			WriteErrors:  s.WriteErrors - prev.WriteErrors,
+			Panics:       s.Panics - prev.Panics,
+			HealthChecks: s.HealthChecks - prev.HealthChecks,
+		},
+	}
+}
+
+// Stats is set of counters that are updated atomically by Server, see
+// Server.Stats. Use Snapshot to read them. Stats should be 64-bit
+// aligned, i.e. first field of struct, for atomic operations.
+type Stats struct {
+	received     uint64
+	dropped      uint64
+	responses    uint64
+	writeErrors  uint64
+	panics       uint64
+	healthChecks uint64
+	memory       memoryBudget
+}
+
+// Snapshot returns copy of counters. Each counter is read atomically,
+// and outcomes of message are loaded before Received, so Dropped and
+// Responses never exceed Received in snapshot.
+func (s *Stats) Snapshot() StatsSnapshot {
+	snapshot := StatsSnapshot{
+		StatsCounters: StatsCounters{
+			Dropped:      atomic.LoadUint64(&s.dropped),
+			Responses:    atomic.LoadUint64(&s.responses),
+			WriteErrors:  atomic.LoadUint64(&s.writeErrors),
+			Panics:       atomic.LoadUint64(&s.panics),
+			HealthChecks: atomic.LoadUint64(&s.healthChecks),
+		},
+	}
+	snapshot.Received = atomic.LoadUint64(&s.received)
+	snapshot.Memory = atomic.LoadInt64(&s.memory.used)
+	snapshot.Time = time.Now()
+	return snapshot
+}
+
+func (s *Stats) inc(counter *uint64) {
+	atomic.AddUint64(counter, 1)
+}
@@ -0,0 +1,56 @@
+package stun
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsSnapshot_Sub(t *testing.T) {
+	start := time.Unix(100, 0)
+	prev := StatsSnapshot{Time: start, StatsCounters: StatsCounters{Received: 10, Dropped: 1, Responses: 8}}
+	s := StatsSnapshot{Time: start.Add(time.Second), StatsCounters: StatsCounters{Received: 15, Dropped: 2, Responses: 12, WriteErrors: 1, Panics: 1}}
+	delta := s.Sub(prev)
+	expected := StatsDelta{Interval: time.Second, StatsCounters: StatsCounters{Received: 5, Dropped: 1, Responses: 4, WriteErrors: 1, Panics: 1}}
+	if delta != expected {
+		t.Errorf("Sub() = %+v, want %+v", delta, expected)
+	}
+}
+
+func TestServer_Stats(t *testing.T) {
+	s := newTestServer(t, WithRequireFingerprint)
+	addr, _ := listenServer(t, "udp", s)
+	defer s.Close()
+	c, err := Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	prev := s.Stats().Snapshot()
+	for i := 0; i < 3; i++ {
+		if doErr := c.Do(MustBuild(TransactionID, BindingRequest, Fingerprint), func(e Event) {
+			if e.Error != nil {
+				t.Error(e.Error)
+			}
+		}); doErr != nil {
+			t.Fatal(doErr)
+		}
+	}
+	// Response counter is updated after write, so client can be faster.
+	delta := s.Stats().Snapshot().Sub(prev)
+	for i := 0; i < 100 && delta.Responses < 3; i++ {
+		time.Sleep(time.Millisecond)
+		delta = s.Stats().Snapshot().Sub(prev)
+	}
+	if delta.Received != 3 || delta.Responses != 3 || delta.Dropped != 0 {
+		t.Errorf("unexpected delta %+v", delta)
+	}
+	if delta.Interval <= 0 {
+		t.Errorf("unexpected interval %s", delta.Interval)
+	}
+	if s.process(new(Message), new(Message), MustBuild(TransactionID, BindingRequest).Raw, nil) {
+		t.Error("request without fingerprint should be dropped")
+	}
+	if dropped := s.Stats().Snapshot().Dropped; dropped != 1 {
+		t.Errorf("unexpected dropped %d", dropped)
+	}
+}
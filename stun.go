@@ -9,6 +9,15 @@
 //
 // See examples for Message for basic usage, or https://github.com/gortc/turn
 // package for example of stun extension implementation.
+//
+// Client and Server with their options stay in this package and are
+// not excluded by build tags, because moving them would break its API
+// and tags would change the API depending on build. Binaries that only
+// encode and decode messages rely on the linker to drop unused code of
+// Client and Server, but package initialization of their imports, like
+// crypto/tls, is kept. TURN client, NAT behavior discovery and
+// self-test are in subpackages turn, natdiscovery and selftest, which
+// are not imported by this package.
 package stun
 
 import (
@@ -0,0 +1,31 @@
+package stuntest
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/pion/stun"
+)
+
+// DumpTimeFormat is time layout of records written by Dump.
+const DumpTimeFormat = "15:04:05.000000"
+
+// Dump writes human-readable representation of record r with decoded
+// message m to w: one line with time, direction and message, followed
+// by indented line for every attribute. If m is nil, length of raw
+// message is written instead.
+func Dump(w io.Writer, r Record, m *stun.Message) error {
+	if m == nil {
+		_, err := fmt.Fprintf(w, "%s %-3s <%d bytes>\n", r.Time.Format(DumpTimeFormat), r.Direction, len(r.Raw))
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s %-3s %s\n", r.Time.Format(DumpTimeFormat), r.Direction, m); err != nil {
+		return err
+	}
+	for _, a := range m.Attributes {
+		if _, err := fmt.Fprintf(w, "\t%s\n", a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package stuntest
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+func TestDump(t *testing.T) {
+	m := stun.MustBuild(stun.NewTransactionIDSetter([stun.TransactionIDSize]byte{}),
+		stun.BindingRequest, stun.NewSoftware("a"),
+	)
+	r := Record{
+		Time:      time.Date(2019, 1, 1, 10, 20, 30, 1000, time.UTC),
+		Direction: DirectionIn,
+		Raw:       m.Raw,
+	}
+	var buf bytes.Buffer
+	if err := Dump(&buf, r, m); err != nil {
+		t.Fatal(err)
+	}
+	expected := "10:20:30.000001 in  Binding request l=8 attrs=1 id=AAAAAAAAAAAAAAAA\n" +
+		"\tSOFTWARE: 0x61\n"
+	if buf.String() != expected {
+		t.Errorf("%q != %q", buf.String(), expected)
+	}
+	buf.Reset()
+	r.Direction = DirectionOut
+	if err := Dump(&buf, r, nil); err != nil {
+		t.Fatal(err)
+	}
+	if expected = "10:20:30.000001 out <28 bytes>\n"; buf.String() != expected {
+		t.Errorf("%q != %q", buf.String(), expected)
+	}
+}
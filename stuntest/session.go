@@ -0,0 +1,180 @@
+package stuntest
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+// Direction of recorded message relative to the recording side.
+type Direction byte
+
+// Possible directions of recorded message.
+const (
+	DirectionOut Direction = 0x00 // written to connection
+	DirectionIn  Direction = 0x01 // read from connection
+)
+
+func (d Direction) String() string {
+	switch d {
+	case DirectionOut:
+		return "out"
+	case DirectionIn:
+		return "in"
+	default:
+		return "unknown"
+	}
+}
+
+// Record is single message of recorded session.
+type Record struct {
+	Time      time.Time
+	Direction Direction
+	Raw       []byte
+}
+
+// Record is encoded as header followed by raw message:
+//
+//	[0:8]   time, unix nanoseconds
+//	[8:9]   direction
+//	[9:11]  length of raw message
+//	[11:]   raw message
+const recordHeaderSize = 8 + 1 + 2
+
+// ErrRecordTooBig means that message can't be recorded because of its size.
+var ErrRecordTooBig = errors.New("record is too big")
+
+// WriteRecord encodes r to w.
+func WriteRecord(w io.Writer, r Record) error {
+	if len(r.Raw) > 0xffff {
+		return ErrRecordTooBig
+	}
+	buf := make([]byte, recordHeaderSize+len(r.Raw))
+	binary.BigEndian.PutUint64(buf[0:8], uint64(r.Time.UnixNano()))
+	buf[8] = byte(r.Direction)
+	binary.BigEndian.PutUint16(buf[9:11], uint16(len(r.Raw)))
+	copy(buf[recordHeaderSize:], r.Raw)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadRecord decodes next record from reader to r, reusing r.Raw.
+// Returns io.EOF if there are no records left.
+func ReadRecord(reader io.Reader, r *Record) error {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return err
+	}
+	r.Time = time.Unix(0, int64(binary.BigEndian.Uint64(header[0:8])))
+	r.Direction = Direction(header[8])
+	n := int(binary.BigEndian.Uint16(header[9:11]))
+	if cap(r.Raw) < n {
+		r.Raw = make([]byte, n)
+	}
+	r.Raw = r.Raw[:n]
+	if _, err := io.ReadFull(reader, r.Raw); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// Recorder wraps connection and records every message that is read
+// from or written to it, so session can be replayed later via Replay.
+//
+// Recorder implements stun.Connection and can be passed to stun.NewClient.
+type Recorder struct {
+	Conn   io.ReadWriteCloser
+	Output io.Writer
+	Now    func() time.Time // defaults to time.Now
+
+	mux sync.Mutex // guards Output
+	err error      // first recording error
+}
+
+// NewRecorder returns Recorder that records messages of conn to w.
+func NewRecorder(conn io.ReadWriteCloser, w io.Writer) *Recorder {
+	return &Recorder{
+		Conn:   conn,
+		Output: w,
+	}
+}
+
+func (r *Recorder) record(d Direction, b []byte) {
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = WriteRecord(r.Output, Record{
+		Time:      now(),
+		Direction: d,
+		Raw:       b,
+	})
+}
+
+// Read reads from underlying connection, recording result.
+func (r *Recorder) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	if n > 0 {
+		r.record(DirectionIn, b[:n])
+	}
+	return n, err
+}
+
+// Write writes to underlying connection, recording b.
+func (r *Recorder) Write(b []byte) (int, error) {
+	n, err := r.Conn.Write(b)
+	if n > 0 {
+		r.record(DirectionOut, b[:n])
+	}
+	return n, err
+}
+
+// Close closes underlying connection.
+func (r *Recorder) Close() error {
+	return r.Conn.Close()
+}
+
+// Err returns first error that occurred while writing to Output.
+func (r *Recorder) Err() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.err
+}
+
+// Replay decodes session recorded by Recorder from reader, calling f for
+// every record with decoded message. Replay stops on first error returned
+// by f or while decoding.
+//
+// Both r and m are valid only during f call.
+func Replay(reader io.Reader, f func(r Record, m *stun.Message) error) error {
+	var (
+		r Record
+		m = stun.New()
+	)
+	for {
+		if err := ReadRecord(reader, &r); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := stun.Decode(r.Raw, m); err != nil {
+			return err
+		}
+		if err := f(r, m); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package stuntest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+type bufferConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (bufferConn) Close() error { return nil }
+
+func TestRecorder(t *testing.T) {
+	req := stun.MustBuild(stun.TransactionID, stun.BindingRequest, stun.Fingerprint)
+	res := stun.MustBuild(req, stun.BindingSuccess, stun.NewSoftware("test"), stun.Fingerprint)
+	var (
+		session bytes.Buffer
+		start   = time.Unix(1, 0)
+		calls   = 0
+	)
+	r := NewRecorder(bufferConn{
+		Reader: bytes.NewReader(res.Raw),
+		Writer: ioutil.Discard,
+	}, &session)
+	r.Now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Second)
+	}
+	if _, err := req.WriteTo(r); err != nil {
+		t.Fatal(err)
+	}
+	got := stun.New()
+	if _, err := got.ReadFrom(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Error(err)
+	}
+	var replayed []stun.MessageType
+	if err := Replay(&session, func(rec Record, m *stun.Message) error {
+		replayed = append(replayed, m.Type)
+		if m.TransactionID != req.TransactionID {
+			t.Error("unexpected transaction id")
+		}
+		if err := stun.Fingerprint.Check(m); err != nil {
+			t.Error(err)
+		}
+		expected := start.Add(time.Duration(len(replayed)) * time.Second)
+		if !rec.Time.Equal(expected) {
+			t.Errorf("bad time %s, expected %s", rec.Time, expected)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(replayed))
+	}
+	if replayed[0] != stun.BindingRequest || replayed[1] != stun.BindingSuccess {
+		t.Error("unexpected types:", replayed)
+	}
+}
+
+func TestReadRecord(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteRecord(&buf, Record{Raw: make([]byte, 0xffff+1)}); err != ErrRecordTooBig {
+		t.Error("should be ErrRecordTooBig, got", err)
+	}
+	if err := WriteRecord(&buf, Record{
+		Direction: DirectionIn,
+		Raw:       []byte{1, 2, 3, 4},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()-1])
+	var r Record
+	if err := ReadRecord(truncated, &r); err != io.ErrUnexpectedEOF {
+		t.Error("should be ErrUnexpectedEOF, got", err)
+	}
+	if err := ReadRecord(&buf, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Direction != DirectionIn || r.Direction.String() != "in" {
+		t.Error("bad direction", r.Direction)
+	}
+	if !bytes.Equal(r.Raw, []byte{1, 2, 3, 4}) {
+		t.Error("bad raw", r.Raw)
+	}
+	if err := ReadRecord(&buf, &r); err != io.EOF {
+		t.Error("should be EOF, got", err)
+	}
+}
@@ -0,0 +1,54 @@
+package stun
+
+// SetterFunc is function that implements Setter, so value of attribute
+// can be computed at the moment when message is built, e.g. timestamp
+// or sequence number.
+type SetterFunc func(m *Message) error
+
+// AddTo implements Setter by calling f(m).
+func (f SetterFunc) AddTo(m *Message) error {
+	return f(m)
+}
+
+// Template is message with fixed part that is encoded once and
+// late-binding setters that are applied on each Build, so volatile
+// attributes like timestamps, sequence numbers, MESSAGE-INTEGRITY and
+// FINGERPRINT are refreshed without rebuilding whole message.
+//
+// Template is immutable and can be used concurrently if late setters
+// are safe for concurrent use.
+type Template struct {
+	raw  []byte
+	late []Setter
+}
+
+// NewTemplate builds fixed part of template from fixed setters, which
+// should not depend on transaction id, so MESSAGE-INTEGRITY and
+// FINGERPRINT should be passed as late setters, in order.
+func NewTemplate(fixed []Setter, late ...Setter) (*Template, error) {
+	m := new(Message)
+	if err := m.Build(fixed...); err != nil {
+		return nil, err
+	}
+	return &Template{
+		raw:  m.Raw,
+		late: late,
+	}, nil
+}
+
+// Build builds message to m from fixed part with transaction id,
+// applying late setters after it.
+func (t *Template) Build(m *Message, id [TransactionIDSize]byte) error {
+	m.Raw = append(m.Raw[:0], t.raw...)
+	if err := m.Decode(); err != nil {
+		return err
+	}
+	m.TransactionID = id
+	m.WriteTransactionID()
+	for _, s := range t.late {
+		if err := s.AddTo(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package stun
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTemplate_Build(t *testing.T) {
+	var counter Priority
+	tmpl, err := NewTemplate([]Setter{BindingRequest, NewSoftware("software")},
+		SetterFunc(func(m *Message) error {
+			counter++
+			return counter.AddTo(m)
+		}),
+		Fingerprint,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := NewTransactionID()
+	for i := 1; i <= 2; i++ {
+		m := new(Message)
+		if err := tmpl.Build(m, id); err != nil {
+			t.Fatal(err)
+		}
+		decoded := new(Message)
+		if _, err := decoded.Write(m.Raw); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Type != BindingRequest {
+			t.Errorf("unexpected type %s", decoded.Type)
+		}
+		if decoded.TransactionID != id {
+			t.Error("unexpected transaction id")
+		}
+		var (
+			software Software
+			p        Priority
+		)
+		if err := software.GetFrom(decoded); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.GetFrom(decoded); err != nil {
+			t.Fatal(err)
+		}
+		if p != Priority(i) {
+			t.Errorf("priority %d, expected %d", p, i)
+		}
+		if err := Fingerprint.Check(decoded); err != nil {
+			t.Error(err)
+		}
+	}
+	t.Run("Error", func(t *testing.T) {
+		errLate := errors.New("late")
+		tmpl, err := NewTemplate([]Setter{BindingRequest}, SetterFunc(func(m *Message) error {
+			return errLate
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tmpl.Build(new(Message), NewTransactionID()); err != errLate {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+	t.Run("FixedError", func(t *testing.T) {
+		errFixed := errors.New("fixed")
+		if _, err := NewTemplate([]Setter{SetterFunc(func(m *Message) error {
+			return errFixed
+		})}); err != errFixed {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestClient_DoTemplate(t *testing.T) {
+	response := MustBuild(TransactionID, BindingSuccess)
+	response.Encode()
+	connL, connR := net.Pipe()
+	defer connL.Close()
+	agent := &manualAgent{}
+	attempt := 0
+	agent.start = func(id [TransactionIDSize]byte, deadline time.Time) error {
+		if attempt == 0 {
+			attempt++
+			go agent.h(Event{
+				TransactionID: id,
+				Error:         ErrTransactionTimeOut,
+			})
+		} else {
+			go agent.h(Event{
+				TransactionID: id,
+				Message:       response,
+			})
+		}
+		return nil
+	}
+	c, err := NewClient(connR,
+		WithAgent(agent),
+		WithClock(&manualClock{current: time.Now()}),
+		WithCollector(new(manualCollector)),
+		WithRTO(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if closeErr := c.Close(); closeErr != nil {
+			t.Error(closeErr)
+		}
+	}()
+	var counter Priority
+	tmpl, err := NewTemplate([]Setter{BindingRequest}, SetterFunc(func(m *Message) error {
+		counter++
+		return counter.AddTo(m)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotReads := make(chan [2]*Message)
+	go func() {
+		var requests [2]*Message
+		for i := range requests {
+			buf := make([]byte, 1500)
+			readN, readErr := connL.Read(buf)
+			if readErr != nil {
+				t.Error(readErr)
+			}
+			requests[i] = new(Message)
+			if _, writeErr := requests[i].Write(buf[:readN]); writeErr != nil {
+				t.Error(writeErr)
+			}
+		}
+		gotReads <- requests
+	}()
+	if doErr := c.DoTemplate(tmpl, func(event Event) {
+		if event.Error != nil {
+			t.Error(event.Error)
+		}
+	}); doErr != nil {
+		t.Fatal(doErr)
+	}
+	requests := <-gotReads
+	if requests[0].TransactionID != requests[1].TransactionID {
+		t.Error("retransmission should have the same transaction id")
+	}
+	for i, m := range requests {
+		var p Priority
+		if getErr := p.GetFrom(m); getErr != nil {
+			t.Fatal(getErr)
+		}
+		if p != Priority(i+1) {
+			t.Errorf("request %d: priority %d, expected %d", i, p, i+1)
+		}
+	}
+}
@@ -105,6 +105,34 @@ func (n *Nonce) GetFrom(m *Message) error {
 	return (*TextAttribute)(n).GetFromAs(m, AttrNonce)
 }
 
+// AlternateDomain represents ALTERNATE-DOMAIN attribute, the domain
+// name of alternate server that is used for certificate verification
+// when redirected over TLS or DTLS.
+//
+// RFC 8489 Section 14.16
+type AlternateDomain []byte
+
+// NewAlternateDomain returns new AlternateDomain from string.
+func NewAlternateDomain(domain string) AlternateDomain {
+	return AlternateDomain(domain)
+}
+
+func (d AlternateDomain) String() string {
+	return string(d)
+}
+
+const maxAlternateDomainB = 255
+
+// AddTo adds ALTERNATE-DOMAIN to message.
+func (d AlternateDomain) AddTo(m *Message) error {
+	return TextAttribute(d).AddToAs(m, AttrAlternateDomain, maxAlternateDomainB)
+}
+
+// GetFrom gets ALTERNATE-DOMAIN from message.
+func (d *AlternateDomain) GetFrom(m *Message) error {
+	return (*TextAttribute)(d).GetFromAs(m, AttrAlternateDomain)
+}
+
 // TextAttribute is helper for adding and getting text attributes.
 type TextAttribute []byte
 
@@ -261,3 +261,20 @@ func BenchmarkNonce_GetFrom(b *testing.B) {
 		n.GetFrom(m)
 	}
 }
+
+func TestAlternateDomain(t *testing.T) {
+	m := MustBuild(BindingRequest, NewAlternateDomain("stun.example.org"))
+	var d AlternateDomain
+	if err := d.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if d.String() != "stun.example.org" {
+		t.Errorf("unexpected domain %q", d)
+	}
+	if err := make(AlternateDomain, 256).AddTo(new(Message)); !IsAttrSizeOverflow(err) {
+		t.Error("unexpected error", err)
+	}
+	if err := d.GetFrom(new(Message)); err != ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+}
@@ -0,0 +1,36 @@
+package turn
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pion/stun"
+)
+
+func TestXORPeerAddress_XORRelayedAddress(t *testing.T) {
+	peer := XORPeerAddress{IP: net.IPv4(192, 0, 2, 1), Port: 3478}
+	relayed := XORRelayedAddress{IP: net.ParseIP("2001:db8::1"), Port: 49152}
+	m := stun.MustBuild(stun.TransactionID, stun.NewType(stun.MethodAllocate, stun.ClassSuccessResponse),
+		peer, relayed,
+	)
+	var (
+		gotPeer    XORPeerAddress
+		gotRelayed XORRelayedAddress
+		mapped     stun.XORMappedAddress
+	)
+	if err := gotPeer.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := gotRelayed.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if gotPeer.String() != "192.0.2.1:3478" {
+		t.Errorf("unexpected XOR-PEER-ADDRESS %s", gotPeer)
+	}
+	if gotRelayed.String() != "[2001:db8::1]:49152" {
+		t.Errorf("unexpected XOR-RELAYED-ADDRESS %s", gotRelayed)
+	}
+	if err := mapped.GetFrom(m); err != stun.ErrAttributeNotFound {
+		t.Error("XOR-MAPPED-ADDRESS should not be added", err)
+	}
+}
@@ -0,0 +1,147 @@
+package turn
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pion/stun"
+)
+
+// AddressFamily is address family of relayed transport address.
+//
+// RFC 6156 Section 4.1.1
+type AddressFamily byte
+
+// Possible address families.
+const (
+	AddressFamilyIPv4 AddressFamily = 0x01
+	AddressFamilyIPv6 AddressFamily = 0x02
+)
+
+func (f AddressFamily) String() string {
+	switch f {
+	case AddressFamilyIPv4:
+		return "IPv4"
+	case AddressFamilyIPv6:
+		return "IPv6"
+	default:
+		return fmt.Sprintf("0x%x", byte(f))
+	}
+}
+
+// ErrUnknownAddressFamily means that address family is not IPv4 or
+// IPv6.
+var ErrUnknownAddressFamily = errors.New("unknown address family")
+
+// RequestedAddressFamily represents REQUESTED-ADDRESS-FAMILY attribute.
+//
+// This attribute is used by the client to request the allocation of a
+// specific address type from a server.
+//
+// RFC 6156 Section 4.1.1
+type RequestedAddressFamily AddressFamily
+
+func (f RequestedAddressFamily) String() string {
+	return AddressFamily(f).String()
+}
+
+const requestedAddressFamilySize = 4
+
+// AddTo adds REQUESTED-ADDRESS-FAMILY to message.
+func (f RequestedAddressFamily) AddTo(m *stun.Message) error {
+	switch AddressFamily(f) {
+	case AddressFamilyIPv4, AddressFamilyIPv6:
+	default:
+		return ErrUnknownAddressFamily
+	}
+	v := make([]byte, requestedAddressFamilySize)
+	v[0] = byte(f)
+	// v[1:4] is RFFU = 0.
+	m.Add(stun.AttrRequestedAddressFamily, v)
+	return nil
+}
+
+// GetFrom decodes REQUESTED-ADDRESS-FAMILY from message. Unknown family
+// is decoded with ErrUnknownAddressFamily, to which server should
+// respond with 440 (Address Family not Supported).
+func (f *RequestedAddressFamily) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrRequestedAddressFamily)
+	if err != nil {
+		return err
+	}
+	if err = stun.CheckSize(stun.AttrRequestedAddressFamily, len(v), requestedAddressFamilySize); err != nil {
+		return err
+	}
+	*f = RequestedAddressFamily(v[0])
+	switch AddressFamily(*f) {
+	case AddressFamilyIPv4, AddressFamilyIPv6:
+		return nil
+	default:
+		return ErrUnknownAddressFamily
+	}
+}
+
+// AddressErrorCode represents ADDRESS-ERROR-CODE attribute.
+//
+// This attribute is used by servers to signal the reason for not
+// allocating the requested address family, e.g. in success response
+// to Allocate request that allocated only one of requested families.
+//
+// RFC 8656 Section 18.13
+type AddressErrorCode struct {
+	Family AddressFamily
+	Code   stun.ErrorCode
+	Reason []byte
+}
+
+func (c AddressErrorCode) String() string {
+	return fmt.Sprintf("%s: %d: %s", c.Family, c.Code, c.Reason)
+}
+
+// constants for ADDRESS-ERROR-CODE encoding.
+const (
+	addressErrorCodeReasonStart = 4
+	addressErrorCodeClassByte   = 2
+	addressErrorCodeNumberByte  = 3
+	addressErrorCodeReasonMaxB  = 763
+	addressErrorCodeModulo      = 100
+)
+
+// AddTo adds ADDRESS-ERROR-CODE to message.
+func (c AddressErrorCode) AddTo(m *stun.Message) error {
+	if err := stun.CheckOverflow(stun.AttrAddressErrorCode,
+		len(c.Reason)+addressErrorCodeReasonStart,
+		addressErrorCodeReasonMaxB+addressErrorCodeReasonStart,
+	); err != nil {
+		return err
+	}
+	v := make([]byte, addressErrorCodeReasonStart+len(c.Reason))
+	v[0] = byte(c.Family)
+	// v[1] is reserved.
+	v[addressErrorCodeClassByte] = byte(c.Code / addressErrorCodeModulo)
+	v[addressErrorCodeNumberByte] = byte(c.Code % addressErrorCodeModulo)
+	copy(v[addressErrorCodeReasonStart:], c.Reason)
+	m.Add(stun.AttrAddressErrorCode, v)
+	return nil
+}
+
+// GetFrom decodes ADDRESS-ERROR-CODE from message. Reason is valid
+// until m.Raw is valid.
+func (c *AddressErrorCode) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrAddressErrorCode)
+	if err != nil {
+		return err
+	}
+	if len(v) < addressErrorCodeReasonStart {
+		return io.ErrUnexpectedEOF
+	}
+	var (
+		class  = int(v[addressErrorCodeClassByte])
+		number = int(v[addressErrorCodeNumberByte])
+	)
+	c.Family = AddressFamily(v[0])
+	c.Code = stun.ErrorCode(class*addressErrorCodeModulo + number)
+	c.Reason = v[addressErrorCodeReasonStart:]
+	return nil
+}
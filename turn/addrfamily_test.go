@@ -0,0 +1,62 @@
+package turn
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pion/stun"
+)
+
+func TestRequestedAddressFamily(t *testing.T) {
+	for _, f := range []AddressFamily{AddressFamilyIPv4, AddressFamilyIPv6} {
+		m := stun.MustBuild(stun.TransactionID, AllocateRequest, RequestedAddressFamily(f))
+		var got RequestedAddressFamily
+		if err := got.GetFrom(m); err != nil {
+			t.Fatal(err)
+		}
+		if AddressFamily(got) != f {
+			t.Errorf("%s != %s", got, f)
+		}
+	}
+	if AddressFamily(3).String() != "0x3" {
+		t.Error("unexpected string of unknown family")
+	}
+	if err := RequestedAddressFamily(3).AddTo(new(stun.Message)); err != ErrUnknownAddressFamily {
+		t.Error("unexpected error", err)
+	}
+	var f RequestedAddressFamily
+	unknown := new(stun.Message)
+	unknown.Add(stun.AttrRequestedAddressFamily, []byte{3, 0, 0, 0})
+	if err := f.GetFrom(unknown); err != ErrUnknownAddressFamily {
+		t.Error("unexpected error", err)
+	}
+	invalid := new(stun.Message)
+	invalid.Add(stun.AttrRequestedAddressFamily, []byte{1})
+	if err := f.GetFrom(invalid); !stun.IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestAddressErrorCode(t *testing.T) {
+	c := AddressErrorCode{
+		Family: AddressFamilyIPv6,
+		Code:   stun.CodeAddrFamilyNotSupported,
+		Reason: []byte("Address Family not Supported"),
+	}
+	m := stun.MustBuild(stun.TransactionID, AllocateSuccess, c)
+	var got AddressErrorCode
+	if err := got.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != "IPv6: 440: Address Family not Supported" {
+		t.Errorf("unexpected %s", got)
+	}
+	if err := (AddressErrorCode{Reason: make([]byte, 1024)}).AddTo(new(stun.Message)); !stun.IsAttrSizeOverflow(err) {
+		t.Error("unexpected error", err)
+	}
+	short := new(stun.Message)
+	short.Add(stun.AttrAddressErrorCode, []byte{1, 0, 4})
+	if err := got.GetFrom(short); err != io.ErrUnexpectedEOF {
+		t.Error("unexpected error", err)
+	}
+}
@@ -0,0 +1,591 @@
+package turn
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+// Lifetimes of permissions and channel bindings, which are not
+// negotiated.
+const (
+	// PermissionLifetime is lifetime of permission.
+	//
+	// RFC 5766 Section 8
+	PermissionLifetime = time.Minute * 5
+	// ChannelLifetime is lifetime of channel binding.
+	//
+	// RFC 5766 Section 11
+	ChannelLifetime = time.Minute * 10
+)
+
+const (
+	// refreshMargin is how long before expiry entries are refreshed.
+	refreshMargin = time.Minute
+	// refreshTick is interval of expiry checks.
+	refreshTick = time.Second * 5
+)
+
+// ResponseErr is error response to TURN request.
+type ResponseErr struct {
+	Method stun.Method
+	Code   stun.ErrorCodeAttribute
+}
+
+func (e ResponseErr) Error() string {
+	return fmt.Sprintf("%s error response: %s", e.Method, e.Code)
+}
+
+// FamilyNotSupported returns true if server does not support requested
+// address family, so allocation can be retried with another family.
+//
+// RFC 6156 Section 4.2
+func (e ResponseErr) FamilyNotSupported() bool {
+	return e.Code.Code == stun.CodeAddrFamilyNotSupported
+}
+
+// PeerFamilyMismatch returns true if peer address family differs from
+// family of relayed address.
+//
+// RFC 6156 Section 6.2
+func (e ResponseErr) PeerFamilyMismatch() bool {
+	return e.Code.Code == stun.CodePeerAddrFamilyMismatch
+}
+
+// InsufficientCapacity returns true if server has no resources for
+// allocation at the moment, e.g. of requested address family.
+//
+// RFC 5766 Section 6.2
+func (e ResponseErr) InsufficientCapacity() bool {
+	return e.Code.Code == stun.CodeInsufficientCapacity
+}
+
+// ErrAllocationClosed means that allocation is closed.
+var ErrAllocationClosed = errors.New("allocation closed")
+
+// ErrNoFreeChannel means that all channel numbers are bound.
+var ErrNoFreeChannel = errors.New("no free channel number")
+
+// AllocationOption sets some option of Allocation.
+type AllocationOption func(a *Allocation)
+
+// WithCredentials sets long-term credentials that are used for all
+// requests of allocation.
+func WithCredentials(l *stun.LongTermCredentials) AllocationOption {
+	return func(a *Allocation) {
+		a.credentials = l
+	}
+}
+
+// WithTransport sets protocol of REQUESTED-TRANSPORT, ProtoUDP by
+// default.
+func WithTransport(p Protocol) AllocationOption {
+	return func(a *Allocation) {
+		a.transport = p
+	}
+}
+
+// WithAddressFamily sets REQUESTED-ADDRESS-FAMILY of Allocate request.
+// By default, family is not requested and server allocates IPv4
+// address.
+//
+// RFC 6156 Section 4.1
+func WithAddressFamily(f AddressFamily) AllocationOption {
+	return func(a *Allocation) {
+		a.family = f
+	}
+}
+
+// WithLifetime sets lifetime that is requested on allocation and
+// refreshes. Zero means default lifetime of server.
+func WithLifetime(d time.Duration) AllocationOption {
+	return func(a *Allocation) {
+		a.requested = d
+	}
+}
+
+// WithRefreshErrorHandler sets function that is called with errors of
+// automatic refreshes.
+func WithRefreshErrorHandler(f func(err error)) AllocationOption {
+	return func(a *Allocation) {
+		a.onRefreshError = f
+	}
+}
+
+// WithPermissionExpiredHandler sets function that is called when
+// permission expires, e.g. because refresh failed. Expired
+// permission is removed from allocation.
+func WithPermissionExpiredHandler(f func(p Permission)) AllocationOption {
+	return func(a *Allocation) {
+		a.onPermissionExpired = f
+	}
+}
+
+// WithChannelExpiredHandler sets function that is called when channel
+// binding expires, e.g. because refresh failed. Expired channel is
+// removed from allocation and its number can be reused.
+func WithChannelExpiredHandler(f func(c Channel)) AllocationOption {
+	return func(a *Allocation) {
+		a.onChannelExpired = f
+	}
+}
+
+// Permission is permission that is installed on allocation.
+type Permission struct {
+	IP      net.IP
+	Expires time.Time
+}
+
+// Channel is channel binding of allocation.
+type Channel struct {
+	Number  ChannelNumber
+	Peer    XORPeerAddress
+	Expires time.Time
+}
+
+// Allocation is client side of TURN allocation.
+//
+// Allocation, its permissions and channel bindings are refreshed before
+// they expire until Close is called. Permissions and channels that are
+// not refreshed in time are removed, see WithPermissionExpiredHandler
+// and WithChannelExpiredHandler. Allocation does not read data
+// from peers, use stun.WithIndicationHandler or Client.HandleIndication
+// for Data indications.
+type Allocation struct {
+	c              *stun.Client
+	credentials    *stun.LongTermCredentials
+	transport      Protocol
+	family         AddressFamily
+	requested      time.Duration
+	onRefreshError func(err error)
+
+	onPermissionExpired func(p Permission)
+	onChannelExpired    func(c Channel)
+
+	// Lifetimes and tick are fields to be shortened in tests.
+	permissionLifetime time.Duration
+	channelLifetime    time.Duration
+	tick               time.Duration
+
+	mux         sync.Mutex // guards fields below
+	relayed     XORRelayedAddress
+	mapped      stun.XORMappedAddress
+	lifetime    time.Duration
+	expires     time.Time
+	permissions map[string]*Permission
+	channels    map[ChannelNumber]*Channel
+	closed      bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Allocate requests new allocation on server of c. The c should not be
+// used for other allocations. Invalid option values are rejected with
+// stun.OptionErr, see also AllocationConfig.
+//
+// RFC 5766 Section 6
+func Allocate(c *stun.Client, options ...AllocationOption) (*Allocation, error) {
+	a := &Allocation{
+		c:                  c,
+		transport:          ProtoUDP,
+		permissionLifetime: PermissionLifetime,
+		channelLifetime:    ChannelLifetime,
+		tick:               refreshTick,
+		permissions:        make(map[string]*Permission),
+		channels:           make(map[ChannelNumber]*Channel),
+		done:               make(chan struct{}),
+	}
+	for _, o := range options {
+		o(a)
+	}
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+	setters := []stun.Setter{RequestedTransport{Protocol: a.transport}}
+	if a.family != 0 {
+		setters = append(setters, RequestedAddressFamily(a.family))
+	}
+	if a.requested > 0 {
+		setters = append(setters, Lifetime{Duration: a.requested})
+	}
+	res, err := a.do(AllocateRequest, setters...)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		relayed  XORRelayedAddress
+		mapped   stun.XORMappedAddress
+		lifetime Lifetime
+	)
+	for _, g := range []stun.Getter{&relayed, &mapped, &lifetime} {
+		if err = g.GetFrom(res); err != nil {
+			return nil, err
+		}
+	}
+	a.relayed, a.mapped = relayed, mapped
+	a.setLifetime(lifetime.Duration)
+	a.wg.Add(1)
+	go a.refreshLoop()
+	return a, nil
+}
+
+// validate returns stun.OptionErr if some option value is invalid.
+func (a *Allocation) validate() error {
+	switch {
+	case a.transport != ProtoUDP && a.transport != ProtoTCP:
+		return stun.OptionErr{Option: "Transport", Value: a.transport}
+	case a.family != 0 && a.family != AddressFamilyIPv4 && a.family != AddressFamilyIPv6:
+		return stun.OptionErr{Option: "AddressFamily", Value: a.family}
+	case a.requested < 0:
+		return stun.OptionErr{Option: "Lifetime", Value: a.requested}
+	}
+	return nil
+}
+
+// Relayed returns relayed transport address of allocation.
+func (a *Allocation) Relayed() XORRelayedAddress {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.relayed
+}
+
+// Mapped returns server-reflexive address of client.
+func (a *Allocation) Mapped() stun.XORMappedAddress {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.mapped
+}
+
+// Lifetime returns lifetime that is granted by server on last
+// allocation or refresh.
+func (a *Allocation) Lifetime() time.Duration {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.lifetime
+}
+
+func (a *Allocation) setLifetime(d time.Duration) {
+	a.mux.Lock()
+	a.lifetime = d
+	a.expires = time.Now().Add(d)
+	a.mux.Unlock()
+}
+
+func (a *Allocation) isClosed() bool {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	return a.closed
+}
+
+// Refresh refreshes allocation with requested lifetime.
+//
+// RFC 5766 Section 7
+func (a *Allocation) Refresh() error {
+	if a.isClosed() {
+		return ErrAllocationClosed
+	}
+	return a.refresh(a.requested)
+}
+
+func (a *Allocation) refresh(d time.Duration) error {
+	var setters []stun.Setter
+	if d > 0 || a.isClosed() {
+		setters = append(setters, Lifetime{Duration: d})
+	}
+	res, err := a.do(RefreshRequest, setters...)
+	if err != nil {
+		return err
+	}
+	var lifetime Lifetime
+	if err = lifetime.GetFrom(res); err != nil {
+		return err
+	}
+	a.setLifetime(lifetime.Duration)
+	return nil
+}
+
+// CreatePermission installs or refreshes permissions for peers in one
+// request. Port of peer addresses is ignored.
+//
+// RFC 5766 Section 9
+func (a *Allocation) CreatePermission(peers ...net.IP) error {
+	if a.isClosed() {
+		return ErrAllocationClosed
+	}
+	setters := make([]stun.Setter, 0, len(peers))
+	for _, ip := range peers {
+		setters = append(setters, XORPeerAddress{IP: ip})
+	}
+	if _, err := a.do(CreatePermissionRequest, setters...); err != nil {
+		return err
+	}
+	a.mux.Lock()
+	for _, ip := range peers {
+		a.permit(ip)
+	}
+	a.mux.Unlock()
+	return nil
+}
+
+// permit installs or refreshes permission for ip, a.mux should be held.
+func (a *Allocation) permit(ip net.IP) {
+	key := ip.String()
+	p, ok := a.permissions[key]
+	if !ok {
+		p = &Permission{IP: append(net.IP(nil), ip...)}
+		a.permissions[key] = p
+	}
+	p.Expires = time.Now().Add(a.permissionLifetime)
+}
+
+// BindChannel binds channel to peer, also installing permission for
+// peer address. If peer is already bound, the binding is refreshed and
+// its channel number is returned.
+//
+// RFC 5766 Section 11
+func (a *Allocation) BindChannel(peer XORPeerAddress) (ChannelNumber, error) {
+	if a.isClosed() {
+		return 0, ErrAllocationClosed
+	}
+	a.mux.Lock()
+	n, err := a.channelFor(peer)
+	_, bound := a.channels[n]
+	if err == nil && !bound {
+		// Reserving number, so concurrent calls can't use it for
+		// another peer. Pending channel has zero expiration time.
+		a.channels[n] = &Channel{Number: n, Peer: XORPeerAddress{
+			IP:   append(net.IP(nil), peer.IP...),
+			Port: peer.Port,
+		}}
+	}
+	a.mux.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if err = a.bind(n, peer); err != nil {
+		if !bound {
+			a.mux.Lock()
+			if ch, ok := a.channels[n]; ok && ch.Expires.IsZero() {
+				delete(a.channels, n)
+			}
+			a.mux.Unlock()
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// channelFor returns channel that is bound to peer or free one, a.mux
+// should be held.
+func (a *Allocation) channelFor(peer XORPeerAddress) (ChannelNumber, error) {
+	for n, ch := range a.channels {
+		if ch.Peer.IP.Equal(peer.IP) && ch.Peer.Port == peer.Port {
+			return n, nil
+		}
+	}
+	for n := MinChannelNumber; n <= MaxChannelNumber; n++ {
+		if _, ok := a.channels[n]; !ok {
+			return n, nil
+		}
+	}
+	return 0, ErrNoFreeChannel
+}
+
+func (a *Allocation) bind(n ChannelNumber, peer XORPeerAddress) error {
+	if _, err := a.do(ChannelBindRequest, n, peer); err != nil {
+		return err
+	}
+	a.mux.Lock()
+	ch, ok := a.channels[n]
+	if !ok {
+		ch = &Channel{Number: n, Peer: XORPeerAddress{
+			IP:   append(net.IP(nil), peer.IP...),
+			Port: peer.Port,
+		}}
+		a.channels[n] = ch
+	}
+	ch.Expires = time.Now().Add(a.channelLifetime)
+	a.permit(peer.IP)
+	a.mux.Unlock()
+	return nil
+}
+
+// Permissions returns permissions of allocation sorted by IP.
+func (a *Allocation) Permissions() []Permission {
+	a.mux.Lock()
+	permissions := make([]Permission, 0, len(a.permissions))
+	for _, p := range a.permissions {
+		permissions = append(permissions, Permission{
+			IP:      append(net.IP(nil), p.IP...),
+			Expires: p.Expires,
+		})
+	}
+	a.mux.Unlock()
+	sort.Slice(permissions, func(i, j int) bool {
+		return bytes.Compare(permissions[i].IP.To16(), permissions[j].IP.To16()) < 0
+	})
+	return permissions
+}
+
+// Channels returns channel bindings of allocation sorted by number.
+func (a *Allocation) Channels() []Channel {
+	a.mux.Lock()
+	channels := make([]Channel, 0, len(a.channels))
+	for _, ch := range a.channels {
+		if ch.Expires.IsZero() {
+			continue // pending, see BindChannel
+		}
+		c := *ch
+		c.Peer.IP = append(net.IP(nil), ch.Peer.IP...)
+		channels = append(channels, c)
+	}
+	a.mux.Unlock()
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Number < channels[j].Number
+	})
+	return channels
+}
+
+// Close stops refreshing and deletes allocation on server. Client is
+// not closed.
+func (a *Allocation) Close() error {
+	a.mux.Lock()
+	if a.closed {
+		a.mux.Unlock()
+		return ErrAllocationClosed
+	}
+	a.closed = true
+	a.mux.Unlock()
+	close(a.done)
+	a.wg.Wait()
+	return a.refresh(0)
+}
+
+func (a *Allocation) refreshLoop() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(a.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.done:
+			return
+		case now := <-ticker.C:
+			a.refreshExpiring(now)
+		}
+	}
+}
+
+// expiring returns true if entry with lifetime should be refreshed.
+func expiring(now, expires time.Time, lifetime time.Duration) bool {
+	margin := refreshMargin
+	if lifetime/2 < margin {
+		margin = lifetime / 2
+	}
+	return expires.Sub(now) < margin
+}
+
+func (a *Allocation) refreshExpiring(now time.Time) {
+	var (
+		peers    []net.IP
+		channels []Channel
+
+		expiredPermissions []Permission
+		expiredChannels    []Channel
+	)
+	a.mux.Lock()
+	allocation := expiring(now, a.expires, a.lifetime)
+	for n, ch := range a.channels {
+		switch {
+		case ch.Expires.IsZero():
+			// Pending, see BindChannel.
+		case !now.Before(ch.Expires):
+			expiredChannels = append(expiredChannels, *ch)
+			delete(a.channels, n)
+		case expiring(now, ch.Expires, a.channelLifetime):
+			channels = append(channels, *ch)
+		}
+	}
+	for key, p := range a.permissions {
+		switch {
+		case !now.Before(p.Expires):
+			expiredPermissions = append(expiredPermissions, *p)
+			delete(a.permissions, key)
+		case expiring(now, p.Expires, a.permissionLifetime):
+			peers = append(peers, p.IP)
+		}
+	}
+	a.mux.Unlock()
+	for _, ch := range expiredChannels {
+		if a.onChannelExpired != nil {
+			a.onChannelExpired(ch)
+		}
+	}
+	for _, p := range expiredPermissions {
+		if a.onPermissionExpired != nil {
+			a.onPermissionExpired(p)
+		}
+	}
+	if allocation {
+		a.refreshFailed(a.Refresh())
+	}
+	for _, ch := range channels {
+		a.refreshFailed(a.bind(ch.Number, ch.Peer))
+	}
+	if len(peers) > 0 {
+		a.refreshFailed(a.CreatePermission(peers...))
+	}
+}
+
+func (a *Allocation) refreshFailed(err error) {
+	if err != nil && a.onRefreshError != nil && err != ErrAllocationClosed {
+		a.onRefreshError(err)
+	}
+}
+
+// do performs transaction with request of type t, returning success
+// response or error.
+func (a *Allocation) do(t stun.MessageType, setters ...stun.Setter) (*stun.Message, error) {
+	var (
+		res    = new(stun.Message)
+		resErr error
+	)
+	f := func(e stun.Event) {
+		if e.Error != nil {
+			resErr = e.Error
+			return
+		}
+		if e.Message.Type.Class == stun.ClassErrorResponse {
+			var code stun.ErrorCodeAttribute
+			if resErr = code.GetFrom(e.Message); resErr == nil {
+				resErr = ResponseErr{Method: t.Method, Code: code}
+			}
+			return
+		}
+		resErr = e.Message.CloneTo(res)
+	}
+	setters = append([]stun.Setter{t}, setters...)
+	if a.credentials != nil {
+		if err := a.credentials.Do(a.c, f, setters...); err != nil {
+			return nil, err
+		}
+	} else {
+		m, err := stun.Build(append([]stun.Setter{a.c.TransactionID()}, setters...)...)
+		if err != nil {
+			return nil, err
+		}
+		if err = a.c.Do(m, f); err != nil {
+			return nil, err
+		}
+	}
+	if resErr != nil {
+		return nil, resErr
+	}
+	return res, nil
+}
@@ -0,0 +1,477 @@
+package turn
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+// testServer is minimal TURN server that records requests.
+type testServer struct {
+	t        *testing.T
+	conn     *net.UDPConn
+	lifetime time.Duration
+
+	mux      sync.Mutex
+	requests map[stun.Method]int
+	reject   map[stun.Method]bool
+	peers    []string
+	channels map[ChannelNumber]string
+}
+
+func newTestServer(t *testing.T, lifetime time.Duration) *testServer {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{
+		t:        t,
+		conn:     conn,
+		lifetime: lifetime,
+		requests: make(map[stun.Method]int),
+		reject:   make(map[stun.Method]bool),
+		channels: make(map[ChannelNumber]string),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) serve() {
+	buf := make([]byte, 1024)
+	req := new(stun.Message)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if stun.Decode(buf[:n], req) != nil {
+			continue
+		}
+		if res := s.handle(req, addr); res != nil {
+			_, _ = s.conn.WriteToUDP(res.Raw, addr)
+		}
+	}
+}
+
+func (s *testServer) handle(req *stun.Message, addr *net.UDPAddr) *stun.Message {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.requests[req.Type.Method]++
+	success := stun.NewType(req.Type.Method, stun.ClassSuccessResponse)
+	if s.reject[req.Type.Method] {
+		return stun.MustBuild(req, stun.NewType(req.Type.Method, stun.ClassErrorResponse), stun.CodeForbidden)
+	}
+	switch req.Type {
+	case AllocateRequest:
+		var transport RequestedTransport
+		if err := transport.GetFrom(req); err != nil || transport.Protocol != ProtoUDP {
+			return stun.MustBuild(req, AllocateError, stun.CodeUnsupportedTransProto)
+		}
+		var family RequestedAddressFamily
+		if err := family.GetFrom(req); err == nil && AddressFamily(family) == AddressFamilyIPv6 {
+			return stun.MustBuild(req, AllocateError, stun.CodeAddrFamilyNotSupported)
+		}
+		return stun.MustBuild(req, success,
+			XORRelayedAddress{IP: net.IPv4(192, 0, 2, 1), Port: 49152},
+			&stun.XORMappedAddress{IP: addr.IP, Port: addr.Port},
+			Lifetime{Duration: s.lifetime},
+		)
+	case RefreshRequest:
+		var lifetime Lifetime
+		if err := lifetime.GetFrom(req); err != nil {
+			lifetime.Duration = s.lifetime
+		}
+		return stun.MustBuild(req, success, lifetime)
+	case CreatePermissionRequest:
+		for _, a := range req.Attributes {
+			if a.Type != stun.AttrXORPeerAddress {
+				continue
+			}
+			m := new(stun.Message)
+			m.TransactionID = req.TransactionID
+			m.Add(stun.AttrXORPeerAddress, a.Value)
+			var peer XORPeerAddress
+			if err := peer.GetFrom(m); err != nil {
+				s.t.Error(err)
+			}
+			s.peers = append(s.peers, peer.IP.String())
+		}
+		return stun.MustBuild(req, success)
+	case ChannelBindRequest:
+		var (
+			n    ChannelNumber
+			peer XORPeerAddress
+		)
+		if err := n.GetFrom(req); err != nil {
+			return stun.MustBuild(req, ChannelBindError, stun.CodeBadRequest)
+		}
+		if err := peer.GetFrom(req); err != nil {
+			return stun.MustBuild(req, ChannelBindError, stun.CodeBadRequest)
+		}
+		s.channels[n] = peer.String()
+		return stun.MustBuild(req, success)
+	}
+	return stun.MustBuild(req, stun.NewType(req.Type.Method, stun.ClassErrorResponse), stun.CodeBadRequest)
+}
+
+func (s *testServer) count(m stun.Method) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.requests[m]
+}
+
+func (s *testServer) dial(t *testing.T) *stun.Client {
+	c, err := stun.Dial("udp4", s.conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// withTestLifetimes sets lifetimes of permissions and channels and
+// interval of expiry checks.
+func withTestLifetimes(permission, channel, tick time.Duration) AllocationOption {
+	return func(a *Allocation) {
+		a.permissionLifetime = permission
+		a.channelLifetime = channel
+		a.tick = tick
+	}
+}
+
+func waitFor(t *testing.T, f func() bool) {
+	deadline := time.Now().Add(time.Second * 5)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	s := newTestServer(t, DefaultLifetime)
+	defer s.conn.Close()
+	c := s.dial(t)
+	defer c.Close()
+	a, err := Allocate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Relayed().String() != "192.0.2.1:49152" {
+		t.Errorf("unexpected relayed address %s", a.Relayed())
+	}
+	if !a.Mapped().IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("unexpected mapped address %s", a.Mapped())
+	}
+	if a.Lifetime() != DefaultLifetime {
+		t.Errorf("unexpected lifetime %s", a.Lifetime())
+	}
+	if err = a.CreatePermission(net.IPv4(192, 0, 2, 10), net.IPv4(192, 0, 2, 11)); err != nil {
+		t.Fatal(err)
+	}
+	peer := XORPeerAddress{IP: net.IPv4(192, 0, 2, 12), Port: 5000}
+	n, err := a.BindChannel(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != MinChannelNumber {
+		t.Errorf("unexpected channel %s", n)
+	}
+	if again, bindErr := a.BindChannel(peer); bindErr != nil || again != n {
+		t.Errorf("rebinding should return %s, got %s (%v)", n, again, bindErr)
+	}
+	other, err := a.BindChannel(XORPeerAddress{IP: net.IPv4(192, 0, 2, 12), Port: 5001})
+	if err != nil || other != n+1 {
+		t.Errorf("unexpected channel %s (%v)", other, err)
+	}
+	s.mux.Lock()
+	if len(s.peers) != 2 || s.channels[n] != peer.String() {
+		t.Errorf("unexpected server state %v %v", s.peers, s.channels)
+	}
+	s.mux.Unlock()
+	a.mux.Lock()
+	if len(a.permissions) != 3 {
+		t.Errorf("channel bind should install permission, got %d", len(a.permissions))
+	}
+	a.mux.Unlock()
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Lifetime() != 0 {
+		t.Error("allocation should be deleted")
+	}
+	if err = a.Close(); err != ErrAllocationClosed {
+		t.Error("unexpected error", err)
+	}
+	if err = a.Refresh(); err != ErrAllocationClosed {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestAllocation_BindChannelConcurrent(t *testing.T) {
+	s := newTestServer(t, DefaultLifetime)
+	defer s.conn.Close()
+	c := s.dial(t)
+	defer c.Close()
+	a, err := Allocate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	s.mux.Lock()
+	s.reject[stun.MethodChannelBind] = true
+	s.mux.Unlock()
+	if _, err = a.BindChannel(XORPeerAddress{IP: net.IPv4(192, 0, 2, 12), Port: 4999}); err == nil {
+		t.Fatal("should fail")
+	}
+	if len(a.Channels()) != 0 {
+		t.Fatal("failed binding should be rolled back")
+	}
+	s.mux.Lock()
+	s.reject[stun.MethodChannelBind] = false
+	s.mux.Unlock()
+	const peers = 10
+	var (
+		wg     sync.WaitGroup
+		mux    sync.Mutex
+		bound  = make(map[ChannelNumber]string)
+		issued = make(chan struct{})
+	)
+	for i := 0; i < peers; i++ {
+		wg.Add(1)
+		go func(peer XORPeerAddress) {
+			defer wg.Done()
+			<-issued
+			n, bindErr := a.BindChannel(peer)
+			if bindErr != nil {
+				t.Error(bindErr)
+				return
+			}
+			mux.Lock()
+			if other, ok := bound[n]; ok {
+				t.Errorf("channel %s is bound to %s and %s", n, other, peer)
+			}
+			bound[n] = peer.String()
+			mux.Unlock()
+		}(XORPeerAddress{IP: net.IPv4(192, 0, 2, 12), Port: 5000 + i})
+	}
+	close(issued)
+	wg.Wait()
+	channels := a.Channels()
+	if len(channels) != peers {
+		t.Fatalf("unexpected channels %v", channels)
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, ch := range channels {
+		if ch.Number < MinChannelNumber || ch.Number >= MinChannelNumber+peers {
+			t.Errorf("unexpected channel %s", ch.Number)
+		}
+		if s.channels[ch.Number] != ch.Peer.String() {
+			t.Errorf("channel %s is bound on server to %s, not %s", ch.Number, s.channels[ch.Number], ch.Peer)
+		}
+	}
+}
+
+func TestAllocate_Error(t *testing.T) {
+	s := newTestServer(t, DefaultLifetime)
+	defer s.conn.Close()
+	c := s.dial(t)
+	defer c.Close()
+	_, err := Allocate(c, WithTransport(ProtoTCP))
+	resErr, ok := err.(ResponseErr)
+	if !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if resErr.Method != stun.MethodAllocate || resErr.Code.Code != stun.CodeUnsupportedTransProto {
+		t.Errorf("unexpected error %s", resErr)
+	}
+}
+
+func TestAllocate_AddressFamily(t *testing.T) {
+	s := newTestServer(t, DefaultLifetime)
+	defer s.conn.Close()
+	c := s.dial(t)
+	defer c.Close()
+	_, err := Allocate(c, WithAddressFamily(AddressFamilyIPv6))
+	resErr, ok := err.(ResponseErr)
+	if !ok || !resErr.FamilyNotSupported() || resErr.PeerFamilyMismatch() || resErr.InsufficientCapacity() {
+		t.Fatalf("unexpected error %v", err)
+	}
+	a, err := Allocate(c, WithAddressFamily(AddressFamilyIPv4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAllocation_AutoRefresh(t *testing.T) {
+	s := newTestServer(t, time.Millisecond*400)
+	defer s.conn.Close()
+	c := s.dial(t)
+	defer c.Close()
+	a, err := Allocate(c,
+		withTestLifetimes(time.Millisecond*400, time.Millisecond*400, time.Millisecond*20),
+		WithRefreshErrorHandler(func(err error) {
+			t.Error("refresh failed:", err)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.CreatePermission(net.IPv4(192, 0, 2, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.BindChannel(XORPeerAddress{IP: net.IPv4(192, 0, 2, 12), Port: 5000}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		return s.count(stun.MethodRefresh) >= 2 &&
+			s.count(stun.MethodCreatePermission) >= 2 &&
+			s.count(stun.MethodChannelBind) >= 2
+	})
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAllocate_Credentials(t *testing.T) {
+	const (
+		username = "user"
+		realm    = "realm"
+		password = "secret"
+	)
+	integrity := stun.NewLongTermIntegrity(username, realm, password)
+	s := newTestServer(t, DefaultLifetime)
+	defer s.conn.Close()
+	// Wrapping handler to challenge unauthenticated requests.
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		req := new(stun.Message)
+		for {
+			n, addr, readErr := conn.ReadFromUDP(buf)
+			if readErr != nil {
+				return
+			}
+			if stun.Decode(buf[:n], req) != nil {
+				continue
+			}
+			var res *stun.Message
+			if integrity.Check(req) != nil {
+				res = stun.MustBuild(req, stun.NewType(req.Type.Method, stun.ClassErrorResponse),
+					stun.CodeUnauthorized, stun.NewRealm(realm), stun.NewNonce("nonce"),
+				)
+			} else {
+				res = s.handle(req, addr)
+			}
+			_, _ = conn.WriteToUDP(res.Raw, addr)
+		}
+	}()
+	c, err := stun.Dial("udp4", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	a, err := Allocate(c, WithCredentials(&stun.LongTermCredentials{
+		Username: username,
+		Password: password,
+	}), WithLifetime(time.Minute*20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Lifetime() != DefaultLifetime {
+		t.Errorf("unexpected lifetime %s", a.Lifetime())
+	}
+	if err = a.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Lifetime() != time.Minute*20 {
+		t.Errorf("requested lifetime should be used, got %s", a.Lifetime())
+	}
+	if err = a.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAllocation_Expired(t *testing.T) {
+	s := newTestServer(t, DefaultLifetime)
+	defer s.conn.Close()
+	c := s.dial(t)
+	defer c.Close()
+	var (
+		mux                sync.Mutex
+		expiredPermissions []Permission
+		expiredChannels    []Channel
+		refreshErrors      int
+	)
+	a, err := Allocate(c,
+		withTestLifetimes(time.Millisecond*200, time.Millisecond*200, time.Millisecond*20),
+		WithRefreshErrorHandler(func(err error) {
+			mux.Lock()
+			refreshErrors++
+			mux.Unlock()
+		}),
+		WithPermissionExpiredHandler(func(p Permission) {
+			mux.Lock()
+			expiredPermissions = append(expiredPermissions, p)
+			mux.Unlock()
+		}),
+		WithChannelExpiredHandler(func(c Channel) {
+			mux.Lock()
+			expiredChannels = append(expiredChannels, c)
+			mux.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	peer := XORPeerAddress{IP: net.IPv4(192, 0, 2, 12), Port: 5000}
+	if err = a.CreatePermission(net.IPv4(192, 0, 2, 11), net.IPv4(192, 0, 2, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.BindChannel(peer); err != nil {
+		t.Fatal(err)
+	}
+	permissions := a.Permissions()
+	if len(permissions) != 3 || !permissions[0].IP.Equal(net.IPv4(192, 0, 2, 10)) {
+		t.Fatalf("unexpected permissions %v", permissions)
+	}
+	if permissions[0].Expires.Before(time.Now()) {
+		t.Error("permission should not be expired")
+	}
+	channels := a.Channels()
+	if len(channels) != 1 || channels[0].Number != MinChannelNumber || channels[0].Peer.String() != peer.String() {
+		t.Fatalf("unexpected channels %v", channels)
+	}
+	s.mux.Lock()
+	s.reject[stun.MethodCreatePermission] = true
+	s.reject[stun.MethodChannelBind] = true
+	s.mux.Unlock()
+	waitFor(t, func() bool {
+		mux.Lock()
+		defer mux.Unlock()
+		return len(expiredPermissions) == 3 && len(expiredChannels) == 1
+	})
+	if len(a.Permissions()) != 0 || len(a.Channels()) != 0 {
+		t.Error("expired entries should be removed")
+	}
+	mux.Lock()
+	if refreshErrors == 0 {
+		t.Error("refresh errors should be reported")
+	}
+	if expiredChannels[0].Peer.String() != peer.String() {
+		t.Errorf("unexpected expired channel %v", expiredChannels[0])
+	}
+	mux.Unlock()
+}
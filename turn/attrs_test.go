@@ -0,0 +1,107 @@
+package turn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pion/stun"
+)
+
+func TestData(t *testing.T) {
+	m := stun.MustBuild(stun.TransactionID, stun.NewType(stun.MethodSend, stun.ClassIndication),
+		Data{1, 2, 3, 4, 5},
+	)
+	var d Data
+	if err := d.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(d, []byte{1, 2, 3, 4, 5}) {
+		t.Errorf("unexpected data %x", d)
+	}
+	if err := d.GetFrom(new(stun.Message)); err != stun.ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestEvenPort(t *testing.T) {
+	for _, p := range []EvenPort{{}, {ReservePort: true}} {
+		m := stun.MustBuild(stun.TransactionID, stun.BindingRequest, p)
+		var got EvenPort
+		if err := got.GetFrom(m); err != nil {
+			t.Fatal(err)
+		}
+		if got != p {
+			t.Errorf("%s != %s", got, p)
+		}
+	}
+	m := new(stun.Message)
+	m.Add(stun.AttrEvenPort, []byte{0x80, 0})
+	var p EvenPort
+	if err := p.GetFrom(m); !stun.IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestRequestedTransport(t *testing.T) {
+	for _, r := range []RequestedTransport{{Protocol: ProtoUDP}, {Protocol: ProtoTCP}} {
+		m := stun.MustBuild(stun.TransactionID, stun.BindingRequest, r)
+		var got RequestedTransport
+		if err := got.GetFrom(m); err != nil {
+			t.Fatal(err)
+		}
+		if got != r {
+			t.Errorf("%s != %s", got, r)
+		}
+	}
+	if s := (RequestedTransport{Protocol: 99}).String(); s != "protocol: 99" {
+		t.Errorf("unexpected string %q", s)
+	}
+	m := new(stun.Message)
+	m.Add(stun.AttrRequestedTransport, []byte{17})
+	var r RequestedTransport
+	if err := r.GetFrom(m); !stun.IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestDontFragment(t *testing.T) {
+	m := new(stun.Message)
+	if DontFragment.IsSet(m) {
+		t.Error("should not be set")
+	}
+	if err := DontFragment.GetFrom(m); err != stun.ErrAttributeNotFound {
+		t.Error("unexpected error", err)
+	}
+	m = stun.MustBuild(stun.TransactionID, stun.BindingRequest, DontFragment)
+	if !DontFragment.IsSet(m) {
+		t.Error("should be set")
+	}
+	if err := DontFragment.GetFrom(m); err != nil {
+		t.Error(err)
+	}
+	invalid := new(stun.Message)
+	invalid.Add(stun.AttrDontFragment, []byte{1})
+	if err := DontFragment.GetFrom(invalid); !stun.IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestReservationToken(t *testing.T) {
+	token := ReservationToken{1, 2, 3, 4, 5, 6, 7, 8}
+	m := stun.MustBuild(stun.TransactionID, stun.BindingRequest, token)
+	var got ReservationToken
+	if err := got.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, token) {
+		t.Errorf("unexpected token %x", got)
+	}
+	if err := (ReservationToken{1, 2}).AddTo(new(stun.Message)); !stun.IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+	invalid := new(stun.Message)
+	invalid.Add(stun.AttrReservationToken, []byte{1, 2, 3})
+	if err := got.GetFrom(invalid); !stun.IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package turn
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/pion/stun"
+)
+
+// ChannelNumber represents CHANNEL-NUMBER attribute.
+//
+// The CHANNEL-NUMBER attribute contains the number of the channel.
+//
+// RFC 5766 Section 14.1
+type ChannelNumber uint16 // encoded as uint16
+
+func (n ChannelNumber) String() string { return strconv.Itoa(int(n)) }
+
+// 16 bits of uint + 16 bits of RFFU = 0.
+const channelNumberSize = 4
+
+// Channel numbers are in range 0x4000 through 0x7FFF.
+//
+// RFC 5766 Section 11
+const (
+	MinChannelNumber ChannelNumber = 0x4000
+	MaxChannelNumber ChannelNumber = 0x7FFF
+)
+
+// ErrInvalidChannelNumber means that channel number is not valid as by
+// RFC 5766 Section 11.
+var ErrInvalidChannelNumber = errors.New("channel number not in [0x4000, 0x7FFF]")
+
+// Valid returns true if channel number is in valid range.
+func (n ChannelNumber) Valid() bool {
+	return n >= MinChannelNumber && n <= MaxChannelNumber
+}
+
+// AddTo adds CHANNEL-NUMBER to message.
+func (n ChannelNumber) AddTo(m *stun.Message) error {
+	if !n.Valid() {
+		return ErrInvalidChannelNumber
+	}
+	v := make([]byte, channelNumberSize)
+	bin.PutUint16(v[:2], uint16(n))
+	// v[2:4] are zeroes (RFFU = 0)
+	m.Add(stun.AttrChannelNumber, v)
+	return nil
+}
+
+// GetFrom decodes CHANNEL-NUMBER from message.
+func (n *ChannelNumber) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrChannelNumber)
+	if err != nil {
+		return err
+	}
+	if err = stun.CheckSize(stun.AttrChannelNumber, len(v), channelNumberSize); err != nil {
+		return err
+	}
+	*n = ChannelNumber(bin.Uint16(v[:2]))
+	if !n.Valid() {
+		return ErrInvalidChannelNumber
+	}
+	return nil
+}
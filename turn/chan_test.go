@@ -0,0 +1,61 @@
+package turn
+
+import (
+	"testing"
+
+	"github.com/pion/stun"
+)
+
+func TestChannelNumber(t *testing.T) {
+	m := stun.MustBuild(stun.TransactionID, stun.BindingRequest, ChannelNumber(0x4001))
+	var n ChannelNumber
+	if err := n.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0x4001 {
+		t.Errorf("unexpected number %s", n)
+	}
+	v, _ := m.Get(stun.AttrChannelNumber)
+	if len(v) != channelNumberSize || v[2] != 0 || v[3] != 0 {
+		t.Errorf("unexpected value %x", v)
+	}
+	t.Run("Range", func(t *testing.T) {
+		for _, c := range []struct {
+			n     ChannelNumber
+			valid bool
+		}{
+			{0x3FFF, false},
+			{MinChannelNumber, true},
+			{MaxChannelNumber, true},
+			{0x8000, false},
+		} {
+			if c.n.Valid() != c.valid {
+				t.Errorf("%x: valid should be %v", uint16(c.n), c.valid)
+			}
+			err := c.n.AddTo(new(stun.Message))
+			if c.valid && err != nil {
+				t.Error(err)
+			}
+			if !c.valid && err != ErrInvalidChannelNumber {
+				t.Errorf("%x: unexpected error %v", uint16(c.n), err)
+			}
+		}
+		m := new(stun.Message)
+		m.Add(stun.AttrChannelNumber, []byte{0x80, 0, 0, 0})
+		if err := n.GetFrom(m); err != ErrInvalidChannelNumber {
+			t.Error("unexpected error", err)
+		}
+	})
+	t.Run("InvalidSize", func(t *testing.T) {
+		m := new(stun.Message)
+		m.Add(stun.AttrChannelNumber, []byte{0x40, 0x01})
+		if err := n.GetFrom(m); !stun.IsAttrSizeInvalid(err) {
+			t.Error("unexpected error", err)
+		}
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		if err := n.GetFrom(new(stun.Message)); err != stun.ErrAttributeNotFound {
+			t.Error("unexpected error", err)
+		}
+	})
+}
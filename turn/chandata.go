@@ -0,0 +1,150 @@
+package turn
+
+import (
+	"errors"
+	"io"
+)
+
+// ChannelData represents The ChannelData Message.
+//
+// See RFC 5766 Section 11.4
+type ChannelData struct {
+	Data   []byte // can be subslice of Raw
+	Number ChannelNumber
+	Raw    []byte
+}
+
+const (
+	channelDataHeaderSize   = 4
+	channelDataLengthSize   = 2
+	channelDataNumberSize   = channelDataLengthSize
+	channelDataPadding      = 4
+	channelDataLengthOffset = channelDataNumberSize
+)
+
+// ErrBadChannelDataLength means that channel data length is not equal
+// to actual data length.
+var ErrBadChannelDataLength = errors.New("channelData length != len(Data)")
+
+// nearestPaddedValueLength returns l rounded up to multiple of 4.
+func nearestPaddedValueLength(l int) int {
+	n := channelDataPadding * (l / channelDataPadding)
+	if n < l {
+		n += channelDataPadding
+	}
+	return n
+}
+
+// Equal returns true if b == c.
+func (c *ChannelData) Equal(b *ChannelData) bool {
+	if c == nil && b == nil {
+		return true
+	}
+	if c == nil || b == nil {
+		return false
+	}
+	return c.Number == b.Number && string(c.Data) == string(b.Data)
+}
+
+// Reset resets Number, Data and Raw length.
+func (c *ChannelData) Reset() {
+	c.Raw = c.Raw[:0]
+	c.Data = c.Data[:0]
+	c.Number = 0
+}
+
+func (c *ChannelData) grow(v int) {
+	n := len(c.Raw) + v
+	for cap(c.Raw) < n {
+		c.Raw = append(c.Raw, 0)
+	}
+	c.Raw = c.Raw[:n]
+}
+
+// Encode encodes Number and Data to Raw without padding, as allowed
+// for UDP. Data is copied, so it should not be subslice of Raw.
+func (c *ChannelData) Encode() {
+	c.encode(false)
+}
+
+// EncodePadded encodes Number and Data to Raw, padding it to multiple
+// of 4 bytes as required for TCP and TLS-over-TCP. Data is copied, so
+// it should not be subslice of Raw.
+func (c *ChannelData) EncodePadded() {
+	c.encode(true)
+}
+
+func (c *ChannelData) encode(pad bool) {
+	c.Raw = c.Raw[:0]
+	size := channelDataHeaderSize + len(c.Data)
+	if pad {
+		size = channelDataHeaderSize + nearestPaddedValueLength(len(c.Data))
+	}
+	c.grow(size)
+	bin.PutUint16(c.Raw[:channelDataNumberSize], uint16(c.Number))
+	bin.PutUint16(c.Raw[channelDataLengthOffset:channelDataHeaderSize], uint16(len(c.Data)))
+	copy(c.Raw[channelDataHeaderSize:], c.Data)
+	for i := channelDataHeaderSize + len(c.Data); i < size; i++ {
+		c.Raw[i] = 0
+	}
+}
+
+// Decode decodes Raw to Number and Data, which refers to Raw. Padding
+// after data is allowed and ignored.
+func (c *ChannelData) Decode() error {
+	buf := c.Raw
+	if len(buf) < channelDataHeaderSize {
+		return io.ErrUnexpectedEOF
+	}
+	num := bin.Uint16(buf[:channelDataNumberSize])
+	c.Number = ChannelNumber(num)
+	if !c.Number.Valid() {
+		return ErrInvalidChannelNumber
+	}
+	l := int(bin.Uint16(buf[channelDataLengthOffset:channelDataHeaderSize]))
+	if l > len(buf[channelDataHeaderSize:]) {
+		return ErrBadChannelDataLength
+	}
+	if len(buf[channelDataHeaderSize:]) > nearestPaddedValueLength(l) {
+		return ErrBadChannelDataLength
+	}
+	c.Data = buf[channelDataHeaderSize : channelDataHeaderSize+l]
+	return nil
+}
+
+// ReadChannelData reads single padded ChannelData message from stream
+// r to c.Raw and decodes it.
+//
+// RFC 5766 Section 11.5
+func ReadChannelData(r io.Reader, c *ChannelData) error {
+	if cap(c.Raw) < channelDataHeaderSize {
+		c.Raw = make([]byte, channelDataHeaderSize, 1024)
+	}
+	c.Raw = c.Raw[:channelDataHeaderSize]
+	if _, err := io.ReadFull(r, c.Raw); err != nil {
+		return err
+	}
+	l := int(bin.Uint16(c.Raw[channelDataLengthOffset:channelDataHeaderSize]))
+	c.grow(nearestPaddedValueLength(l))
+	if _, err := io.ReadFull(r, c.Raw[channelDataHeaderSize:]); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return c.Decode()
+}
+
+// IsChannelData returns true if buf looks like the ChannelData Message:
+// first two bits are 0b01 and length fits in buf. Useful for
+// multiplexing with STUN messages, see stun.IsMessage.
+func IsChannelData(buf []byte) bool {
+	if len(buf) < channelDataHeaderSize {
+		return false
+	}
+	if !ChannelNumber(bin.Uint16(buf[:channelDataNumberSize])).Valid() {
+		return false
+	}
+	l := int(bin.Uint16(buf[channelDataLengthOffset:channelDataHeaderSize]))
+	return l <= len(buf[channelDataHeaderSize:])
+}
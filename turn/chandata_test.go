@@ -0,0 +1,103 @@
+package turn
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pion/stun"
+)
+
+func TestChannelData_Encode(t *testing.T) {
+	d := &ChannelData{
+		Data:   []byte{1, 2, 3, 4, 5},
+		Number: MinChannelNumber + 1,
+	}
+	d.Encode()
+	if !bytes.Equal(d.Raw, []byte{0x40, 0x01, 0x00, 0x05, 1, 2, 3, 4, 5}) {
+		t.Errorf("unexpected raw %x", d.Raw)
+	}
+	b := &ChannelData{Raw: append([]byte(nil), d.Raw...)}
+	if err := b.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Equal(d) {
+		t.Error("not equal")
+	}
+	if !IsChannelData(b.Raw) || !IsChannelData(d.Raw) {
+		t.Error("should be channel data")
+	}
+	d.EncodePadded()
+	if len(d.Raw) != 12 || !bytes.Equal(d.Raw[9:], []byte{0, 0, 0}) {
+		t.Errorf("unexpected padded raw %x", d.Raw)
+	}
+	b.Raw = append(b.Raw[:0], d.Raw...)
+	if err := b.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	if !b.Equal(d) {
+		t.Error("padding should be ignored")
+	}
+}
+
+func TestChannelData_Decode(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		buf  []byte
+		err  error
+	}{
+		{"Short", []byte{0x40, 0x00, 0x00}, io.ErrUnexpectedEOF},
+		{"Number", []byte{0x80, 0x00, 0x00, 0x00}, ErrInvalidChannelNumber},
+		{"Length", []byte{0x40, 0x00, 0x00, 0x02, 1}, ErrBadChannelDataLength},
+		{"Padding", []byte{0x40, 0x00, 0x00, 0x01, 1, 0, 0, 0, 0}, ErrBadChannelDataLength},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &ChannelData{Raw: tc.buf}
+			if err := d.Decode(); err != tc.err {
+				t.Errorf("unexpected error %v, expected %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestReadChannelData(t *testing.T) {
+	var stream bytes.Buffer
+	for _, data := range [][]byte{{1}, {1, 2, 3, 4}, {}} {
+		d := &ChannelData{Data: data, Number: MinChannelNumber}
+		d.EncodePadded()
+		stream.Write(d.Raw)
+	}
+	d := new(ChannelData)
+	for _, expected := range []int{1, 4, 0} {
+		if err := ReadChannelData(&stream, d); err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Data) != expected {
+			t.Errorf("unexpected data %x", d.Data)
+		}
+	}
+	if err := ReadChannelData(&stream, d); err != io.EOF {
+		t.Error("unexpected error", err)
+	}
+	stream.Write([]byte{0x40, 0x00, 0x00, 0x04, 1})
+	if err := ReadChannelData(&stream, d); err != io.ErrUnexpectedEOF {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestIsChannelData(t *testing.T) {
+	m := stun.MustBuild(stun.TransactionID, stun.BindingRequest)
+	if IsChannelData(m.Raw) {
+		t.Error("STUN message should not be channel data")
+	}
+	for _, buf := range [][]byte{
+		nil,
+		{0x40, 0x00, 0x00},
+		{0x40, 0x00, 0x00, 0x02, 1},
+		{0x80, 0x00, 0x00, 0x00},
+	} {
+		if IsChannelData(buf) {
+			t.Errorf("%x should not be channel data", buf)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package turn
+
+import "time"
+
+// AllocationConfig is serializable alternative to allocation options.
+// Zero values mean defaults; credentials and handlers are not
+// serializable and should be passed as options.
+type AllocationConfig struct {
+	Transport     Protocol      `json:"transport,omitempty"`
+	AddressFamily AddressFamily `json:"address_family,omitempty"`
+	Lifetime      time.Duration `json:"lifetime,omitempty"`
+}
+
+// Options returns allocation options that are equivalent to c, for
+// Allocate(client, c.Options()...).
+func (c AllocationConfig) Options() []AllocationOption {
+	var options []AllocationOption
+	if c.Transport != 0 {
+		options = append(options, WithTransport(c.Transport))
+	}
+	if c.AddressFamily != 0 {
+		options = append(options, WithAddressFamily(c.AddressFamily))
+	}
+	if c.Lifetime != 0 {
+		options = append(options, WithLifetime(c.Lifetime))
+	}
+	return options
+}
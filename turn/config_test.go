@@ -0,0 +1,40 @@
+package turn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+func TestAllocationConfig_Options(t *testing.T) {
+	config := AllocationConfig{Transport: ProtoTCP, AddressFamily: AddressFamilyIPv6, Lifetime: time.Minute}
+	a := &Allocation{}
+	for _, o := range config.Options() {
+		o(a)
+	}
+	if a.transport != ProtoTCP || a.family != AddressFamilyIPv6 || a.requested != time.Minute {
+		t.Errorf("options are not applied: %+v", a)
+	}
+	if len((AllocationConfig{}).Options()) != 0 {
+		t.Error("zero config should have no options")
+	}
+}
+
+func TestAllocate_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		option AllocationOption
+	}{
+		{"Transport", WithTransport(1)},
+		{"AddressFamily", WithAddressFamily(3)},
+		{"Lifetime", WithLifetime(-time.Second)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Allocate(nil, tc.option)
+			if optionErr, ok := err.(stun.OptionErr); !ok || optionErr.Option != tc.name {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
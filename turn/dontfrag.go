@@ -0,0 +1,39 @@
+package turn
+
+import "github.com/pion/stun"
+
+// DontFragmentAttr represents DONT-FRAGMENT attribute.
+//
+// This attribute is used by the client to request that the server set
+// the DF (Don't Fragment) bit in the IP header when relaying the
+// application data onward to the peer. This attribute has no value
+// part and thus the attribute length field is 0.
+//
+// RFC 5766 Section 14.8
+type DontFragmentAttr struct{}
+
+// DontFragment is shorthand for DontFragmentAttr.
+var DontFragment DontFragmentAttr
+
+const dontFragmentSize = 0
+
+// AddTo adds DONT-FRAGMENT attribute to message.
+func (DontFragmentAttr) AddTo(m *stun.Message) error {
+	m.Add(stun.AttrDontFragment, nil)
+	return nil
+}
+
+// GetFrom checks that DONT-FRAGMENT attribute is present in message
+// and has no value.
+func (DontFragmentAttr) GetFrom(m *stun.Message) error {
+	v, err := m.Get(stun.AttrDontFragment)
+	if err != nil {
+		return err
+	}
+	return stun.CheckSize(stun.AttrDontFragment, len(v), dontFragmentSize)
+}
+
+// IsSet returns true if DONT-FRAGMENT attribute is set.
+func (DontFragmentAttr) IsSet(m *stun.Message) bool {
+	return m.Contains(stun.AttrDontFragment)
+}
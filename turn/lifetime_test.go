@@ -0,0 +1,33 @@
+package turn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+func TestLifetime(t *testing.T) {
+	m := stun.MustBuild(stun.TransactionID, stun.BindingRequest,
+		Lifetime{Duration: time.Second*10 + time.Millisecond*900},
+	)
+	var l Lifetime
+	if err := l.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if l.Duration != time.Second*10 {
+		t.Errorf("duration should be truncated to seconds, got %s", l)
+	}
+	t.Run("InvalidSize", func(t *testing.T) {
+		m := new(stun.Message)
+		m.Add(stun.AttrLifetime, []byte{1, 2, 3})
+		if err := l.GetFrom(m); !stun.IsAttrSizeInvalid(err) {
+			t.Error("unexpected error", err)
+		}
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		if err := l.GetFrom(new(stun.Message)); err != stun.ErrAttributeNotFound {
+			t.Error("unexpected error", err)
+		}
+	})
+}
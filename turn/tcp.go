@@ -0,0 +1,109 @@
+package turn
+
+import (
+	"errors"
+	"io"
+	"net"
+
+	"github.com/pion/stun"
+)
+
+// ErrUnexpectedResponse means that response does not match request.
+var ErrUnexpectedResponse = errors.New("unexpected response")
+
+// ConnectionAttempt is incoming connection from peer that is signaled
+// by ConnectionAttempt indication, see stun.WithHandler.
+//
+// RFC 6062 Section 4.4
+type ConnectionAttempt struct {
+	ID   ConnectionID
+	Peer XORPeerAddress
+}
+
+// GetFrom decodes ConnectionAttempt indication.
+func (c *ConnectionAttempt) GetFrom(m *stun.Message) error {
+	if m.Type != ConnectionAttemptIndication {
+		return ErrUnexpectedResponse
+	}
+	if err := c.ID.GetFrom(m); err != nil {
+		return err
+	}
+	return c.Peer.GetFrom(m)
+}
+
+// Connect requests server of TCP allocation to connect to peer,
+// returning id of connection that should be bound by BindConnection.
+//
+// RFC 6062 Section 4.3
+func (a *Allocation) Connect(peer XORPeerAddress) (ConnectionID, error) {
+	if a.isClosed() {
+		return 0, ErrAllocationClosed
+	}
+	res, err := a.do(ConnectRequest, peer)
+	if err != nil {
+		return 0, err
+	}
+	var id ConnectionID
+	if err = id.GetFrom(res); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// BindConnection sends ConnectionBind request for connection id on
+// new data connection conn to server and reads response. On success,
+// conn is relayed to peer. Request is authenticated with credentials
+// of allocation, which are cached on Allocate.
+//
+// Nothing is read from conn beyond the response.
+//
+// RFC 6062 Section 4.3
+func (a *Allocation) BindConnection(conn net.Conn, id ConnectionID) error {
+	setters := []stun.Setter{stun.TransactionID, ConnectionBindRequest, id}
+	if a.credentials != nil {
+		setters = append(setters, a.credentials)
+	}
+	req, err := stun.Build(setters...)
+	if err != nil {
+		return err
+	}
+	if _, err = conn.Write(req.Raw); err != nil {
+		return err
+	}
+	res := new(stun.Message)
+	if err = readMessage(conn, res); err != nil {
+		return err
+	}
+	if res.TransactionID != req.TransactionID || res.Type.Method != stun.MethodConnectionBind {
+		return ErrUnexpectedResponse
+	}
+	if res.Type.Class == stun.ClassErrorResponse {
+		var code stun.ErrorCodeAttribute
+		if err = code.GetFrom(res); err != nil {
+			return err
+		}
+		return ResponseErr{Method: stun.MethodConnectionBind, Code: code}
+	}
+	return nil
+}
+
+// messageHeaderSize is size of STUN message header.
+const messageHeaderSize = 20
+
+// readMessage reads and decodes single STUN message from stream r,
+// reading nothing after it.
+func readMessage(r io.Reader, m *stun.Message) error {
+	buf := make([]byte, messageHeaderSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	size := messageHeaderSize + int(bin.Uint16(buf[2:4]))
+	buf = append(buf, make([]byte, size-messageHeaderSize)...)
+	if _, err := io.ReadFull(r, buf[messageHeaderSize:]); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return stun.Decode(buf, m)
+}
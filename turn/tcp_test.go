@@ -0,0 +1,154 @@
+package turn
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pion/stun"
+)
+
+func TestConnectionID(t *testing.T) {
+	m := stun.MustBuild(stun.TransactionID, ConnectRequest, ConnectionID(42))
+	var id ConnectionID
+	if err := id.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if id.String() != "42" {
+		t.Errorf("unexpected id %s", id)
+	}
+	invalid := new(stun.Message)
+	invalid.Add(stun.AttrConnectionID, []byte{1})
+	if err := id.GetFrom(invalid); !stun.IsAttrSizeInvalid(err) {
+		t.Error("unexpected error", err)
+	}
+}
+
+func TestConnectionAttempt(t *testing.T) {
+	peer := XORPeerAddress{IP: net.IPv4(192, 0, 2, 1), Port: 5000}
+	m := stun.MustBuild(stun.TransactionID, ConnectionAttemptIndication, ConnectionID(7), peer)
+	var c ConnectionAttempt
+	if err := c.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != 7 || c.Peer.String() != peer.String() {
+		t.Errorf("unexpected attempt %+v", c)
+	}
+	if err := c.GetFrom(stun.MustBuild(stun.TransactionID, DataIndication)); err != ErrUnexpectedResponse {
+		t.Error("unexpected error", err)
+	}
+}
+
+const testConnectionID ConnectionID = 42
+
+// serveTCP serves control and data connections of TCP allocation,
+// echoing data of bound connections.
+func serveTCP(t *testing.T, l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			req := new(stun.Message)
+			for {
+				if err := readMessage(conn, req); err != nil {
+					return
+				}
+				var res *stun.Message
+				switch req.Type {
+				case AllocateRequest:
+					var transport RequestedTransport
+					if err := transport.GetFrom(req); err != nil || transport.Protocol != ProtoTCP {
+						t.Error("TCP transport should be requested")
+					}
+					res = stun.MustBuild(req, AllocateSuccess,
+						XORRelayedAddress{IP: net.IPv4(192, 0, 2, 1), Port: 49152},
+						&stun.XORMappedAddress{IP: net.IPv4(127, 0, 0, 1), Port: 1},
+						Lifetime{Duration: DefaultLifetime},
+					)
+				case ConnectRequest:
+					res = stun.MustBuild(req, ConnectSuccess, testConnectionID)
+				case ConnectionBindRequest:
+					var id ConnectionID
+					if err := id.GetFrom(req); err != nil || id != testConnectionID {
+						res = stun.MustBuild(req, ConnectionBindError, stun.CodeBadRequest)
+						break
+					}
+					res = stun.MustBuild(req, ConnectionBindSuccess)
+					if _, err := conn.Write(res.Raw); err != nil {
+						return
+					}
+					_, _ = io.Copy(conn, conn)
+					return
+				default:
+					res = stun.MustBuild(req, stun.NewType(req.Type.Method, stun.ClassSuccessResponse),
+						Lifetime{},
+					)
+				}
+				if _, err := conn.Write(res.Raw); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func TestAllocation_TCP(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveTCP(t, l)
+	c, err := stun.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	a, err := Allocate(c, WithTransport(ProtoTCP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	id, err := a.Connect(XORPeerAddress{IP: net.IPv4(192, 0, 2, 2), Port: 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != testConnectionID {
+		t.Errorf("unexpected id %s", id)
+	}
+	t.Run("Bind", func(t *testing.T) {
+		conn, err := net.Dial("tcp4", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if err = a.BindConnection(conn, id); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = conn.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 5)
+		if _, err = io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf, []byte("hello")) {
+			t.Errorf("unexpected data %q", buf)
+		}
+	})
+	t.Run("BadID", func(t *testing.T) {
+		conn, err := net.Dial("tcp4", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		err = a.BindConnection(conn, id+1)
+		if resErr, ok := err.(ResponseErr); !ok || resErr.Code.Code != stun.CodeBadRequest {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
@@ -0,0 +1,15 @@
+// Package turn implements attributes of Traversal Using Relays around
+// NAT (TURN) RFC 5766 on top of stun package.
+//
+// Relayed TCP connection of RFC 6062 TCP allocation is established in
+// two steps. First, CONNECTION-ID is obtained from Allocation.Connect
+// for outgoing connection or from ConnectionAttempt indication for
+// incoming one. Then Allocation.BindConnection binds new data
+// connection to server with that id, and the data connection is relayed
+// to peer.
+package turn
+
+import "encoding/binary"
+
+// bin is shorthand for binary.BigEndian.
+var bin = binary.BigEndian
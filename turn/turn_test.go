@@ -0,0 +1,30 @@
+package turn
+
+import "github.com/pion/stun"
+
+var (
+	_ stun.Setter = new(ChannelNumber)
+	_ stun.Getter = new(ChannelNumber)
+	_ stun.Setter = new(Lifetime)
+	_ stun.Getter = new(Lifetime)
+	_ stun.Setter = new(XORPeerAddress)
+	_ stun.Getter = new(XORPeerAddress)
+	_ stun.Setter = new(XORRelayedAddress)
+	_ stun.Getter = new(XORRelayedAddress)
+	_ stun.Setter = new(Data)
+	_ stun.Getter = new(Data)
+	_ stun.Setter = new(EvenPort)
+	_ stun.Getter = new(EvenPort)
+	_ stun.Setter = new(RequestedTransport)
+	_ stun.Getter = new(RequestedTransport)
+	_ stun.Setter = DontFragment
+	_ stun.Getter = DontFragment
+	_ stun.Setter = new(ReservationToken)
+	_ stun.Getter = new(ReservationToken)
+	_ stun.Setter = new(ConnectionID)
+	_ stun.Getter = new(ConnectionID)
+	_ stun.Setter = new(RequestedAddressFamily)
+	_ stun.Getter = new(RequestedAddressFamily)
+	_ stun.Setter = new(AddressErrorCode)
+	_ stun.Getter = new(AddressErrorCode)
+)
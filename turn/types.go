@@ -0,0 +1,63 @@
+package turn
+
+import "github.com/pion/stun"
+
+// Message types of TURN methods.
+//
+// RFC 5766 Section 13
+var (
+	// Allocate request message type.
+	AllocateRequest = stun.NewType(stun.MethodAllocate, stun.ClassRequest)
+	// Allocate success response message type.
+	AllocateSuccess = stun.NewType(stun.MethodAllocate, stun.ClassSuccessResponse)
+	// Allocate error response message type.
+	AllocateError = stun.NewType(stun.MethodAllocate, stun.ClassErrorResponse)
+
+	// Refresh request message type.
+	RefreshRequest = stun.NewType(stun.MethodRefresh, stun.ClassRequest)
+	// Refresh success response message type.
+	RefreshSuccess = stun.NewType(stun.MethodRefresh, stun.ClassSuccessResponse)
+	// Refresh error response message type.
+	RefreshError = stun.NewType(stun.MethodRefresh, stun.ClassErrorResponse)
+
+	// CreatePermission request message type.
+	CreatePermissionRequest = stun.NewType(stun.MethodCreatePermission, stun.ClassRequest)
+	// CreatePermission success response message type.
+	CreatePermissionSuccess = stun.NewType(stun.MethodCreatePermission, stun.ClassSuccessResponse)
+	// CreatePermission error response message type.
+	CreatePermissionError = stun.NewType(stun.MethodCreatePermission, stun.ClassErrorResponse)
+
+	// ChannelBind request message type.
+	ChannelBindRequest = stun.NewType(stun.MethodChannelBind, stun.ClassRequest)
+	// ChannelBind success response message type.
+	ChannelBindSuccess = stun.NewType(stun.MethodChannelBind, stun.ClassSuccessResponse)
+	// ChannelBind error response message type.
+	ChannelBindError = stun.NewType(stun.MethodChannelBind, stun.ClassErrorResponse)
+
+	// Send indication message type.
+	SendIndication = stun.NewType(stun.MethodSend, stun.ClassIndication)
+	// Data indication message type.
+	DataIndication = stun.NewType(stun.MethodData, stun.ClassIndication)
+)
+
+// Message types of TURN methods for TCP allocations.
+//
+// RFC 6062 Section 6.1
+var (
+	// Connect request message type.
+	ConnectRequest = stun.NewType(stun.MethodConnect, stun.ClassRequest)
+	// Connect success response message type.
+	ConnectSuccess = stun.NewType(stun.MethodConnect, stun.ClassSuccessResponse)
+	// Connect error response message type.
+	ConnectError = stun.NewType(stun.MethodConnect, stun.ClassErrorResponse)
+
+	// ConnectionBind request message type.
+	ConnectionBindRequest = stun.NewType(stun.MethodConnectionBind, stun.ClassRequest)
+	// ConnectionBind success response message type.
+	ConnectionBindSuccess = stun.NewType(stun.MethodConnectionBind, stun.ClassSuccessResponse)
+	// ConnectionBind error response message type.
+	ConnectionBindError = stun.NewType(stun.MethodConnectionBind, stun.ClassErrorResponse)
+
+	// ConnectionAttempt indication message type.
+	ConnectionAttemptIndication = stun.NewType(stun.MethodConnectionAttempt, stun.ClassIndication)
+)
@@ -1,6 +1,9 @@
 package stun
 
-import "errors"
+import (
+	"errors"
+	"sync/atomic"
+)
 
 // UnknownAttributes represents UNKNOWN-ATTRIBUTES attribute.
 //
@@ -23,14 +26,17 @@ func (a UnknownAttributes) String() string {
 }
 
 // type size is 16 bit.
-const attrTypeSize = 4
+const attrTypeSize = 2
 
 // AddTo adds UNKNOWN-ATTRIBUTES attribute to message.
+//
+// Value is encoded as list of 16-bit attribute types, padded by
+// regular attribute padding rules (RFC 5389 Section 15.9).
 func (a UnknownAttributes) AddTo(m *Message) error {
 	v := make([]byte, 0, attrTypeSize*20) // 20 should be enough
 	// If len(a.Types) > 20, there will be allocations.
 	for i, t := range a {
-		v = append(v, 0, 0, 0, 0) // 4 times by 0 (16 bits)
+		v = append(v, 0, 0) // 2 times by 0 (16 bits)
 		first := attrTypeSize * i
 		last := first + attrTypeSize
 		bin.PutUint16(v[first:last], t.Value())
@@ -61,3 +67,111 @@ func (a *UnknownAttributes) GetFrom(m *Message) error {
 	}
 	return nil
 }
+
+// Unknown returns types of attributes of m that are not known, split by
+// comprehension class, in order of appearance. Nil known means types of
+// attributes that are defined in this package.
+func (m *Message) Unknown(known func(t AttrType) bool) (required, optional UnknownAttributes) {
+	if known == nil {
+		known = isKnownAttr
+	}
+	for _, a := range m.Attributes {
+		if known(a.Type) {
+			continue
+		}
+		if a.Type.Required() {
+			required = append(required, a.Type)
+		} else {
+			optional = append(optional, a.Type)
+		}
+	}
+	return required, optional
+}
+
+// UnknownAttrsErr occurs when message contains comprehension-required
+// attributes that are not known. Agent should respond to such request
+// with 420 (Unknown Attribute) error that lists Required types in
+// UNKNOWN-ATTRIBUTES.
+//
+// RFC 5389 Section 7.3.1
+type UnknownAttrsErr struct {
+	Required UnknownAttributes
+	// Optional lists unknown comprehension-optional attributes, which
+	// are just ignored. Useful for logging.
+	Optional UnknownAttributes
+}
+
+func (e *UnknownAttrsErr) Error() string {
+	return "unknown comprehension-required attributes: " + e.Required.String()
+}
+
+// AddTo adds 420 (Unknown Attribute) error code and UNKNOWN-ATTRIBUTES
+// with e.Required to m, so error response to request can be built as
+// Build(req, NewType(req.Type.Method, ClassErrorResponse), e).
+func (e *UnknownAttrsErr) AddTo(m *Message) error {
+	if err := CodeUnknownAttribute.AddTo(m); err != nil {
+		return err
+	}
+	return e.Required.AddTo(m)
+}
+
+// NewUnknownAttributesResponse returns 420 (Unknown Attribute) error
+// response to req that lists required types in UNKNOWN-ATTRIBUTES.
+//
+// RFC 5389 Section 7.3.1
+func NewUnknownAttributesResponse(req *Message, required UnknownAttributes) (*Message, error) {
+	return Build(req, NewType(req.Type.Method, ClassErrorResponse), &UnknownAttrsErr{Required: required})
+}
+
+// AttrRegistry is set of attribute types that are known or handled by
+// application. Use Known as KnownAttributes.Known or Decoder.Known.
+type AttrRegistry map[AttrType]struct{}
+
+// NewAttrRegistry returns registry of types of attributes that are
+// defined in this package and types.
+func NewAttrRegistry(types ...AttrType) AttrRegistry {
+	r := make(AttrRegistry, len(attrNames)+len(types))
+	for t := range attrNames {
+		r[t] = struct{}{}
+	}
+	r.Add(types...)
+	return r
+}
+
+// Add adds types to registry.
+func (r AttrRegistry) Add(types ...AttrType) {
+	for _, t := range types {
+		r[t] = struct{}{}
+	}
+}
+
+// Known returns true if t is in registry.
+func (r AttrRegistry) Known(t AttrType) bool {
+	_, ok := r[t]
+	return ok
+}
+
+// KnownAttributes is Checker that fails with *UnknownAttrsErr if message
+// contains comprehension-required attributes that are not known.
+// Unknown comprehension-optional attributes are ignored.
+type KnownAttributes struct {
+	// Known reports whether attribute type is known to application.
+	// Defaults to types of attributes that are defined in this package.
+	Known func(t AttrType) bool
+
+	// Ignored, if set, is atomically incremented by count of ignored
+	// comprehension-optional attributes.
+	Ignored *uint64
+}
+
+// Check implements Checker.
+func (k KnownAttributes) Check(m *Message) error {
+	required, optional := m.Unknown(k.Known)
+	if k.Ignored != nil && len(optional) > 0 {
+		atomic.AddUint64(k.Ignored, uint64(len(optional)))
+	}
+	if len(required) > 0 {
+		return &UnknownAttrsErr{Required: required, Optional: optional}
+	}
+	return nil
+}
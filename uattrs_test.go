@@ -1,6 +1,7 @@
 package stun
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -40,6 +41,32 @@ func TestUnknownAttributes(t *testing.T) {
 	})
 }
 
+func TestUnknownAttributes_Encoding(t *testing.T) {
+	m := New()
+	a := UnknownAttributes{AttrRealm, AttrNonce, AttrUsername}
+	if err := a.AddTo(m); err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Get(AttrUnknownAttributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x00, 0x14, 0x00, 0x15, 0x00, 0x06}
+	if !bytes.Equal(v, expected) {
+		t.Errorf("bad value %x, expected %x", v, expected)
+	}
+	if m.Length != attributeHeaderSize+8 {
+		t.Error("value should be padded, length:", m.Length)
+	}
+	var got UnknownAttributes
+	if err := got.GetFrom(m); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != a.String() {
+		t.Error(got, "!=", a)
+	}
+}
+
 func BenchmarkUnknownAttributes(b *testing.B) {
 	m := new(Message)
 	a := UnknownAttributes{
@@ -71,3 +98,71 @@ func BenchmarkUnknownAttributes(b *testing.B) {
 		}
 	})
 }
+
+func TestKnownAttributes(t *testing.T) {
+	m := MustBuild(TransactionID, BindingRequest, NewSoftware("software"))
+	m.Add(0x7F01, []byte{1})
+	m.Add(0xFF01, []byte{2})
+	m.Add(0x7F02, []byte{3})
+	required, optional := m.Unknown(nil)
+	if required.String() != "0x7f01, 0x7f02" || optional.String() != "0xff01" {
+		t.Errorf("unexpected unknown attributes %s; %s", required, optional)
+	}
+	var ignored uint64
+	err := m.Check(KnownAttributes{Ignored: &ignored})
+	unknownErr, ok := err.(*UnknownAttrsErr)
+	if !ok {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(unknownErr.Required) != 2 || len(unknownErr.Optional) != 1 {
+		t.Errorf("unexpected error %s", unknownErr)
+	}
+	if unknownErr.Error() != "unknown comprehension-required attributes: 0x7f01, 0x7f02" {
+		t.Errorf("unexpected error string %q", unknownErr)
+	}
+	if ignored != 1 {
+		t.Errorf("ignored should be 1, got %d", ignored)
+	}
+	known := func(t AttrType) bool { return t.Optional() || t == AttrSoftware }
+	if err = m.Check(KnownAttributes{Known: func(t AttrType) bool {
+		return known(t) || t == 0x7F01 || t == 0x7F02
+	}, Ignored: &ignored}); err != nil {
+		t.Error(err)
+	}
+	if ignored != 1 {
+		t.Error("known optional attributes should not be counted")
+	}
+}
+
+func TestNewUnknownAttributesResponse(t *testing.T) {
+	req := MustBuild(TransactionID, BindingRequest)
+	res, err := NewUnknownAttributesResponse(req, UnknownAttributes{0x7F01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Type != BindingError || res.TransactionID != req.TransactionID {
+		t.Errorf("unexpected response %s", res)
+	}
+	var (
+		code    ErrorCodeAttribute
+		unknown UnknownAttributes
+	)
+	if err = res.Parse(&code, &unknown); err != nil {
+		t.Fatal(err)
+	}
+	if code.Code != CodeUnknownAttribute || unknown.String() != "0x7f01" {
+		t.Errorf("unexpected %s; %s", code, unknown)
+	}
+}
+
+func TestAttrRegistry(t *testing.T) {
+	r := NewAttrRegistry(0x7F01)
+	if !r.Known(AttrSoftware) || !r.Known(0x7F01) || r.Known(0x7F02) {
+		t.Error("unexpected registry")
+	}
+	r.Add(0x7F02)
+	m := MustBuild(TransactionID, BindingRequest, RawAttribute{Type: 0x7F02, Value: []byte{1}})
+	if err := m.Check(KnownAttributes{Known: r.Known}); err != nil {
+		t.Error(err)
+	}
+}
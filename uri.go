@@ -0,0 +1,102 @@
+package stun
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// URI schemes for STUN, as defined in RFC 7064.
+const (
+	SchemeSTUN  = "stun"
+	SchemeSTUNS = "stuns"
+)
+
+// Possible errors of ParseURI.
+var (
+	ErrSchemeType = errors.New("unknown scheme type")
+	ErrHost       = errors.New("invalid hostname")
+	ErrPort       = errors.New("invalid port")
+	ErrQuery      = errors.New("queries are not supported in stun uri")
+)
+
+// URI is STUN URI, e.g. "stun:example.org:3478".
+//
+// RFC 7064 Section 3.1
+type URI struct {
+	Scheme string // SchemeSTUN or SchemeSTUNS
+	Host   string // without brackets for IPv6
+	Port   int
+}
+
+// ParseURI parses "stun:" or "stuns:" URI. If port is omitted,
+// DefaultPort or DefaultTLSPort is used depending on scheme.
+func ParseURI(raw string) (URI, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return URI{}, err
+	}
+	var uri URI
+	switch u.Scheme {
+	case SchemeSTUN:
+		uri.Port = DefaultPort
+	case SchemeSTUNS:
+		uri.Port = DefaultTLSPort
+	default:
+		return URI{}, ErrSchemeType
+	}
+	uri.Scheme = u.Scheme
+	if u.RawQuery != "" || u.ForceQuery {
+		return URI{}, ErrQuery
+	}
+	// Authority ("stun://host") is not allowed, so host and port
+	// are in opaque part.
+	hostPort := u.Opaque
+	if hostPort == "" {
+		return URI{}, ErrHost
+	}
+	host, port := hostPort, ""
+	if i := strings.LastIndexByte(hostPort, ':'); i > strings.LastIndexByte(hostPort, ']') {
+		host, port = hostPort[:i], hostPort[i+1:]
+	}
+	if strings.HasPrefix(host, "[") {
+		if !strings.HasSuffix(host, "]") {
+			return URI{}, ErrHost
+		}
+		host = host[1 : len(host)-1]
+		if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+			return URI{}, ErrHost
+		}
+	} else if host == "" || strings.ContainsAny(host, ":[]") {
+		return URI{}, ErrHost
+	}
+	uri.Host = host
+	if port != "" {
+		p, parseErr := strconv.Atoi(port)
+		if parseErr != nil || p < 1 || p > 0xffff {
+			return URI{}, ErrPort
+		}
+		uri.Port = p
+	}
+	return uri, nil
+}
+
+// Network returns network for Dial function: "udp" for SchemeSTUN and
+// "tls" for SchemeSTUNS.
+func (u URI) Network() string {
+	if u.Scheme == SchemeSTUNS {
+		return "tls"
+	}
+	return "udp"
+}
+
+// Address returns "host:port" address for Dial function.
+func (u URI) Address() string {
+	return net.JoinHostPort(u.Host, strconv.Itoa(u.Port))
+}
+
+func (u URI) String() string {
+	return u.Scheme + ":" + u.Address()
+}
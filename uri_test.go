@@ -0,0 +1,62 @@
+package stun
+
+import "testing"
+
+func TestParseURI(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		out     URI
+		network string
+		address string
+	}{
+		{"stun:example.org", URI{SchemeSTUN, "example.org", 3478}, "udp", "example.org:3478"},
+		{"stuns:example.org", URI{SchemeSTUNS, "example.org", 5349}, "tls", "example.org:5349"},
+		{"stun:example.org:19302", URI{SchemeSTUN, "example.org", 19302}, "udp", "example.org:19302"},
+		{"STUN:example.org:", URI{SchemeSTUN, "example.org", 3478}, "udp", "example.org:3478"},
+		{"stun:192.0.2.1:1234", URI{SchemeSTUN, "192.0.2.1", 1234}, "udp", "192.0.2.1:1234"},
+		{"stuns:[2001:db8::1]", URI{SchemeSTUNS, "2001:db8::1", 5349}, "tls", "[2001:db8::1]:5349"},
+		{"stun:[2001:db8::1]:80", URI{SchemeSTUN, "2001:db8::1", 80}, "udp", "[2001:db8::1]:80"},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			u, err := ParseURI(tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u != tc.out {
+				t.Errorf("%+v != %+v", u, tc.out)
+			}
+			if u.Network() != tc.network {
+				t.Errorf("network %s != %s", u.Network(), tc.network)
+			}
+			if u.Address() != tc.address {
+				t.Errorf("address %s != %s", u.Address(), tc.address)
+			}
+			if u.String() != u.Scheme+":"+tc.address {
+				t.Errorf("unexpected string %s", u)
+			}
+		})
+	}
+	for _, tc := range []struct {
+		in  string
+		err error
+	}{
+		{"turn:example.org", ErrSchemeType},
+		{"example.org:3478", ErrSchemeType},
+		{"stun://example.org", ErrHost},
+		{"stun:", ErrHost},
+		{"stun::3478", ErrHost},
+		{"stun:2001:db8::1", ErrHost},
+		{"stun:[2001:db8::1", ErrHost},
+		{"stun:[192.0.2.1]", ErrHost},
+		{"stun:example.org:port", ErrPort},
+		{"stun:example.org:0", ErrPort},
+		{"stun:example.org:65536", ErrPort},
+		{"stun:example.org?transport=udp", ErrQuery},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			if _, err := ParseURI(tc.in); err != tc.err {
+				t.Errorf("unexpected error %v, expected %v", err, tc.err)
+			}
+		})
+	}
+}
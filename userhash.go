@@ -0,0 +1,51 @@
+package stun
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Userhash represents USERHASH attribute, which is sent instead of
+// USERNAME for user anonymity.
+//
+// RFC 8489 Section 14.4
+type Userhash []byte
+
+const userhashSize = sha256.Size
+
+// NewUserhash returns USERHASH for username and realm:
+//
+//	userhash = SHA-256(username ":" realm)
+//
+// Username and realm must be prepared, e.g. by OpaqueString.
+func NewUserhash(username, realm string) Userhash {
+	h := newSHA256()
+	fmt.Fprint(h, username+credentialsSep+realm)
+	return Userhash(h.Sum(nil))
+}
+
+func (u Userhash) String() string {
+	return fmt.Sprintf("0x%x", []byte(u))
+}
+
+// AddTo adds USERHASH attribute to message.
+func (u Userhash) AddTo(m *Message) error {
+	if err := CheckSize(AttrUserhash, len(u), userhashSize); err != nil {
+		return err
+	}
+	m.Add(AttrUserhash, u)
+	return nil
+}
+
+// GetFrom decodes USERHASH attribute from message.
+func (u *Userhash) GetFrom(m *Message) error {
+	v, err := m.Get(AttrUserhash)
+	if err != nil {
+		return err
+	}
+	if err = CheckSize(AttrUserhash, len(v), userhashSize); err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
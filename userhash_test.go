@@ -0,0 +1,39 @@
+package stun
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestUserhash(t *testing.T) {
+	// RFC 8489 Appendix B.1
+	u := NewUserhash("\u30DE\u30C8\u30EA\u30C3\u30AF\u30B9", "example.org")
+	if hex.EncodeToString(u) != "4a3cf38fef6992bda952c6780417da0f24819415569e60b205c46e41407f1704" {
+		t.Fatal("unexpected userhash", u)
+	}
+	m := MustBuild(BindingRequest, u)
+	decoded := new(Message)
+	if _, err := decoded.Write(m.Raw); err != nil {
+		t.Fatal(err)
+	}
+	var got Userhash
+	if err := got.GetFrom(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != u.String() {
+		t.Errorf("%s != %s", got, u)
+	}
+	t.Run("InvalidSize", func(t *testing.T) {
+		if err := Userhash(make([]byte, 10)).AddTo(new(Message)); !IsAttrSizeInvalid(err) {
+			t.Error("unexpected error", err)
+		}
+		invalid := new(Message)
+		invalid.Add(AttrUserhash, make([]byte, 10))
+		if err := got.GetFrom(invalid); !IsAttrSizeInvalid(err) {
+			t.Error("unexpected error", err)
+		}
+		if err := got.GetFrom(new(Message)); err != ErrAttributeNotFound {
+			t.Error("unexpected error", err)
+		}
+	})
+}
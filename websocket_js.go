@@ -0,0 +1,136 @@
+// +build js,wasm
+
+package stun
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"syscall/js"
+)
+
+// webSocketQueueSize is size of queue for received but not yet read
+// messages. Messages are dropped when queue is full, like datagrams.
+const webSocketQueueSize = 64
+
+// ErrWebSocketFailed means that browser failed to establish WebSocket
+// connection.
+var ErrWebSocketFailed = errors.New("websocket connection failed")
+
+// WebSocketConn implements Connection over browser WebSocket API,
+// making it possible to use Client from Go compiled to WebAssembly.
+//
+// Each WebSocket binary message carries exactly one STUN message.
+type WebSocketConn struct {
+	ws       js.Value
+	incoming chan []byte
+	closed   chan struct{}
+	handlers []webSocketHandler
+	once     sync.Once
+}
+
+type webSocketHandler struct {
+	event string
+	fn    js.Func
+}
+
+// DialWebSocket opens WebSocket connection to url, blocking until it is
+// established or failed.
+func DialWebSocket(url string) (*WebSocketConn, error) {
+	c := &WebSocketConn{
+		ws:       js.Global().Get("WebSocket").New(url),
+		incoming: make(chan []byte, webSocketQueueSize),
+		closed:   make(chan struct{}),
+	}
+	c.ws.Set("binaryType", "arraybuffer")
+	opened := make(chan error, 1)
+	// Callbacks are blocking JavaScript event loop, so none of them
+	// should block.
+	c.on("open", func(js.Value) {
+		select {
+		case opened <- nil:
+		default:
+		}
+	})
+	c.on("error", func(js.Value) {
+		select {
+		case opened <- ErrWebSocketFailed:
+		default:
+		}
+	})
+	c.on("close", func(js.Value) {
+		c.shutdown()
+		select {
+		case opened <- ErrWebSocketFailed:
+		default:
+		}
+	})
+	c.on("message", func(e js.Value) {
+		data := js.Global().Get("Uint8Array").New(e.Get("data"))
+		b := make([]byte, data.Get("length").Int())
+		js.CopyBytesToGo(b, data)
+		select {
+		case c.incoming <- b:
+		default:
+			// Dropping message.
+		}
+	})
+	if err := <-opened; err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *WebSocketConn) on(event string, f func(e js.Value)) {
+	fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		f(args[0])
+		return nil
+	})
+	c.handlers = append(c.handlers, webSocketHandler{event: event, fn: fn})
+	c.ws.Call("addEventListener", event, fn)
+}
+
+func (c *WebSocketConn) shutdown() {
+	c.once.Do(func() {
+		close(c.closed)
+	})
+}
+
+// Read reads next message to b, returning io.EOF if connection is closed.
+func (c *WebSocketConn) Read(b []byte) (int, error) {
+	select {
+	case msg := <-c.incoming:
+		return copy(b, msg), nil
+	case <-c.closed:
+		return 0, io.EOF
+	}
+}
+
+// Write sends b as single binary message.
+func (c *WebSocketConn) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+	data := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(data, b)
+	c.ws.Call("send", data)
+	return len(b), nil
+}
+
+// Close closes WebSocket and releases callbacks.
+func (c *WebSocketConn) Close() error {
+	if c.handlers == nil {
+		return io.ErrClosedPipe
+	}
+	c.shutdown()
+	for _, h := range c.handlers {
+		c.ws.Call("removeEventListener", h.event, h.fn)
+		h.fn.Release()
+	}
+	c.handlers = nil
+	c.ws.Call("close")
+	return nil
+}